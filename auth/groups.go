@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"github.com/eogile/agilestack-utils/plugins/group"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/slices"
+)
+
+// AddProfileGroup adds the given group to the subjects of the profile,
+// so that all the members of the group get the profile.
+func (client HydraClient) AddProfileGroup(profileId string, groupId string, tokenInfo *TokenInfo) error {
+	return client.AddProfileUser(profileId, secu.GroupSubject(groupId), tokenInfo)
+}
+
+// DeleteProfileGroup removes the given group from the subjects of the profile.
+func (client HydraClient) DeleteProfileGroup(profileId string, groupId string, tokenInfo *TokenInfo) error {
+	return client.DeleteProfileUser(profileId, secu.GroupSubject(groupId), tokenInfo)
+}
+
+// EffectiveProfiles lists the profiles granted to the user, either directly
+// or through the groups the user is a member of.
+func (client HydraClient) EffectiveProfiles(userId string, groups group.GroupsStorageClient, tokenInfo *TokenInfo) ([]secu.Policy, error) {
+	userGroups, err := groups.ListUserGroups(userId)
+	if err != nil {
+		return nil, err
+	}
+	subjects := make([]string, 0, len(userGroups)+1)
+	subjects = append(subjects, userId)
+	for _, userGroup := range userGroups {
+		subjects = append(subjects, secu.GroupSubject(userGroup.Id))
+	}
+
+	profiles, err := client.ListProfiles(tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+	effectiveProfiles := make([]secu.Policy, 0)
+	for _, profile := range profiles {
+		for _, subject := range profile.Subjects {
+			if slices.StringInSlice(subject, subjects) {
+				effectiveProfiles = append(effectiveProfiles, profile)
+				break
+			}
+		}
+	}
+	return effectiveProfiles, nil
+}
@@ -0,0 +1,81 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/auth"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/ory-am/osin-storage/Godeps/_workspace/src/github.com/stretchr/testify/require"
+)
+
+// An in-memory groups storage client. Only the user groups listing is used.
+type fakeGroupsStorageClient struct {
+	groups []secu.Group
+}
+
+func (c *fakeGroupsStorageClient) StoreGroup(group secu.Group) error         { return nil }
+func (c *fakeGroupsStorageClient) GetGroup(id string) (*secu.Group, error)   { return nil, nil }
+func (c *fakeGroupsStorageClient) ListGroups() ([]secu.Group, error)         { return c.groups, nil }
+func (c *fakeGroupsStorageClient) DeleteGroup(id string) error               { return nil }
+func (c *fakeGroupsStorageClient) AddMember(groupId, userId string) error    { return nil }
+func (c *fakeGroupsStorageClient) RemoveMember(groupId, userId string) error { return nil }
+
+func (c *fakeGroupsStorageClient) ListUserGroups(userId string) ([]secu.Group, error) {
+	userGroups := []secu.Group{}
+	for _, group := range c.groups {
+		for _, member := range group.Members {
+			if member == userId {
+				userGroups = append(userGroups, group)
+			}
+		}
+	}
+	return userGroups, nil
+}
+
+func TestEffectiveProfiles(t *testing.T) {
+	client := auth.NewClient(hydra.URL, hydra.ClientID, hydra.ClientSecret)
+	token, err := client.Login("superadmin@eogile.com", "supersecret")
+	require.Nil(t, err)
+	tokenInfo, err := auth.EncodeTokenInfo(token)
+	require.Nil(t, err)
+
+	groups := &fakeGroupsStorageClient{
+		groups: []secu.Group{
+			secu.Group{Id: "developers", Members: []string{"user-g1", "user-g2"}},
+		},
+	}
+
+	teamProfileId, err := client.CreateProfile(&secu.Policy{
+		Description: "Team profile",
+		Subjects:    []string{},
+		Resource:    "rn:hydra:clients",
+		Permissions: []string{"get"},
+	}, tokenInfo)
+	require.Nil(t, err)
+	require.Nil(t, client.AddProfileGroup(teamProfileId, "developers", tokenInfo))
+
+	userProfileId, err := client.CreateProfile(&secu.Policy{
+		Description: "User profile",
+		Subjects:    []string{"user-g1"},
+		Resource:    "rn:hydra:clients",
+		Permissions: []string{"create"},
+	}, tokenInfo)
+	require.Nil(t, err)
+
+	profiles, err := client.EffectiveProfiles("user-g1", groups, tokenInfo)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(profiles))
+
+	profiles, err = client.EffectiveProfiles("user-g2", groups, tokenInfo)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(profiles))
+	require.Equal(t, teamProfileId, profiles[0].Id)
+
+	require.Nil(t, client.DeleteProfileGroup(teamProfileId, "developers", tokenInfo))
+	profiles, err = client.EffectiveProfiles("user-g2", groups, tokenInfo)
+	require.Nil(t, err)
+	require.Equal(t, 0, len(profiles))
+
+	require.Nil(t, client.DeleteProfile(teamProfileId, tokenInfo))
+	require.Nil(t, client.DeleteProfile(userProfileId, tokenInfo))
+}
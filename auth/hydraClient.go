@@ -1,18 +1,23 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"strings"
 
 	"bytes"
+	"crypto/rand"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/tenant"
 	"github.com/ory-am/hydra/account"
 	"github.com/ory-am/ladon/policy"
 	"golang.org/x/oauth2"
@@ -36,6 +41,9 @@ ODIRe1AuTyHceAbewn8b462yEWKARdpd9AjQW5SIVPfdsz5B6GlYQ5LdYKtznTuy
 -----END PUBLIC KEY-----
 `
 
+// The public key verifying the access tokens.
+var tokenPublicKey = dummyKey
+
 // The policy that authorizes users to access their own data
 var defaultUserPolicy = policy.DefaultPolicy{
 	ID:          "default-policy",
@@ -55,6 +63,9 @@ type HydraClient struct {
 	clientCredentialConfig clientcredentials.Config
 	oauth2Config           oauth2.Config
 	authorizationServer    string
+
+	// The tenant the users and policies handled by the client belong to.
+	tenantId string
 }
 
 func NewClient(authorizationServer, clientID, clientSecret string) *HydraClient {
@@ -85,6 +96,19 @@ func NewClient(authorizationServer, clientID, clientSecret string) *HydraClient
 	}
 }
 
+// ForTenant returns a copy of the client restricted to the users and policies
+// of the given tenant.
+func (client HydraClient) ForTenant(tenantId string) HydraClient {
+	client.tenantId = tenantId
+	return client
+}
+
+// ForContext returns a copy of the client restricted to the users and
+// policies of the tenant held by the context.
+func (client HydraClient) ForContext(ctx context.Context) HydraClient {
+	return client.ForTenant(tenant.FromContext(ctx))
+}
+
 func (client HydraClient) getHttpClient(tokenInfo *TokenInfo) *http.Client {
 	if tokenInfo == nil || tokenInfo.TokenInfo == "" || tokenInfo.TokenInfo == "null" {
 		log.Println(" in getHttpClient, tokenInfo == nil || tokenInfo.TokenInfo==\"\" || tokenInfo.TokenInfo == \"null\"")
@@ -124,7 +148,7 @@ func getUserId(tokenInfo *TokenInfo) (string, error) {
 	log.Printf("token.AccessToken=%v", token.AccessToken)
 
 	accessToken, err := jwt.Parse(token.AccessToken, func(*jwt.Token) (interface{}, error) {
-		return jwt.ParseRSAPublicKeyFromPEM([]byte(dummyKey))
+		return jwt.ParseRSAPublicKeyFromPEM([]byte(tokenPublicKey))
 	})
 	if err != nil {
 		log.Printf("error in getUserId>Unmarshall AccessToken : %v", err)
@@ -150,9 +174,44 @@ func (client HydraClient) GetUser(tokenInfo *TokenInfo) (*secu.User, error, int)
 		return nil, err, respCode
 	}
 	user := secu.NewUser(account)
+	if user.Tenant != client.tenantId {
+		return nil, errors.New("Not Authorized"), http.StatusForbidden
+	}
 	return user, nil, respCode
 }
 
+// TenantOfToken returns the tenant of the user authenticated by the token,
+// whatever the tenant of the client.
+func (client HydraClient) TenantOfToken(tokenInfo *TokenInfo) (string, error) {
+	userId, err := getUserId(tokenInfo)
+	if err != nil {
+		return "", err
+	}
+
+	account := &account.DefaultAccount{}
+	found, err, _ := client.findElement(&account, accountPath+"/"+userId, client.getHttpClient(tokenInfo))
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("Unknown user: \"%s\"", userId)
+	}
+	return secu.NewUser(account).Tenant, nil
+}
+
+// TenantResolver returns a resolver deriving the tenant of a request from the
+// user authenticated by the token of the request, read by tokenInfo (see
+// tenant.NewMiddleware). The requests without token are not authorized.
+func (client HydraClient) TenantResolver(tokenInfo func(r *http.Request) *TokenInfo) tenant.Resolver {
+	return func(r *http.Request) (string, error) {
+		info := tokenInfo(r)
+		if info == nil || info.TokenInfo == "" {
+			return "", errors.New("Not Authorized")
+		}
+		return client.TenantOfToken(info)
+	}
+}
+
 func (client HydraClient) ListUsers(tokenInfo *TokenInfo) ([]secu.User, error, int) {
 	httpClient := client.getHttpClient(tokenInfo)
 
@@ -164,7 +223,9 @@ func (client HydraClient) ListUsers(tokenInfo *TokenInfo) ([]secu.User, error, i
 	}
 	users := make([]secu.User, 0, len(accounts))
 	for _, account := range accounts {
-		users = append(users, *secu.NewUser(&account))
+		if user := secu.NewUser(&account); user.Tenant == client.tenantId {
+			users = append(users, *user)
+		}
 	}
 	return users, nil, respCode
 }
@@ -175,13 +236,19 @@ func (client HydraClient) FindUser(accountId string, tokenInfo *TokenInfo) (*sec
 	if found, err, _ := client.findElement(&account, accountPath+"/"+accountId, httpClient); !found || err != nil {
 		return nil, err
 	}
-	return secu.NewUser(&account), nil
+	user := secu.NewUser(&account)
+	if user.Tenant != client.tenantId {
+		// the users of the other tenants are not visible
+		return nil, nil
+	}
+	return user, nil
 }
 
 func (client HydraClient) CreateUser(user *secu.User, tokenInfo *TokenInfo) (id string, err error) {
 	// by default, an user is user active and not blocked
 	user.SetInactive(false)
 	user.SetBlocked(false)
+	user.Tenant = client.tenantId
 
 	httpClient := client.getHttpClient(tokenInfo)
 	request := user.ToCreateAccountRequest()
@@ -189,87 +256,54 @@ func (client HydraClient) CreateUser(user *secu.User, tokenInfo *TokenInfo) (id
 }
 
 func (client HydraClient) DeleteUser(accountId string, tokenInfo *TokenInfo) error {
+	if err := client.checkUser(accountId, tokenInfo); err != nil {
+		return err
+	}
 
 	httpClient := client.getHttpClient(tokenInfo)
 	return client.deleteElement(accountPath+"/"+accountId, httpClient)
 }
 
-func (client HydraClient) UpdateUserLogin(userId string, r secu.UpdateLoginRequest) error {
+func (client HydraClient) UpdateUserLogin(userId string, r secu.UpdateLoginRequest, tokenInfo *TokenInfo) error {
+	if err := client.checkUser(userId, tokenInfo); err != nil {
+		return err
+	}
 	hydraReq := account.UpdateUsernameRequest{
 		Username: r.Login,
 		Password: r.Password,
 	}
-	json, err := json.Marshal(hydraReq)
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequest("PUT", client.authorizationServer+accountPath+"/"+userId+"/username", bytes.NewReader(json))
-	if err != nil {
-		return err
-	}
 	//FIXME
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while updating the user", resp.Status))
-	}
-
-	return nil
+	_, _, err := client.sendRequest(http.DefaultClient, "PUT", accountPath+"/"+userId+"/username", hydraReq, nil)
+	return err
 }
 
-func (client HydraClient) UpdateUserPassword(userId string, r secu.UpdatePasswordRequest) error {
+func (client HydraClient) UpdateUserPassword(userId string, r secu.UpdatePasswordRequest, tokenInfo *TokenInfo) error {
+	if err := client.checkUser(userId, tokenInfo); err != nil {
+		return err
+	}
 	hydraReq := account.UpdatePasswordRequest{
 		CurrentPassword: r.CurrentPassword,
 		NewPassword:     r.NewPassword,
 	}
-	json, err := json.Marshal(hydraReq)
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequest("PUT", client.authorizationServer+accountPath+"/"+userId+"/password", bytes.NewReader(json))
-	if err != nil {
-		return err
-	}
 	//FIXME
-	httpClient := &http.Client{}
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while updating the user", resp.Status))
-	}
-
-	return nil
+	_, _, err := client.sendRequest(http.DefaultClient, "PUT", accountPath+"/"+userId+"/password", hydraReq, nil)
+	return err
 }
 
 func (client HydraClient) UpdateUserData(userId string, data secu.UserData, tokenInfo *TokenInfo) error {
-	jsonData, err := json.Marshal(data)
-	if err != nil {
+	if err := client.checkUser(userId, tokenInfo); err != nil {
 		return err
 	}
-	hydraReq := account.UpdateDataRequest{Data: string(jsonData)}
-	jsonHydraReq, err := json.Marshal(hydraReq)
-	if err != nil {
-		return err
-	}
-	req, err := http.NewRequest("PUT", client.authorizationServer+accountPath+"/"+userId+"/data", bytes.NewReader(jsonHydraReq))
+	// users cannot be moved to another tenant
+	data.Tenant = client.tenantId
+	jsonData, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
+	hydraReq := account.UpdateDataRequest{Data: string(jsonData)}
 	httpClient := client.getHttpClient(tokenInfo)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while updating the user", resp.Status))
-	}
-
-	return nil
+	_, _, err = client.sendRequest(httpClient, "PUT", accountPath+"/"+userId+"/data", hydraReq, nil)
+	return err
 }
 
 func (client HydraClient) ListProfiles(tokenInfo *TokenInfo) ([]secu.Policy, error) {
@@ -281,7 +315,7 @@ func (client HydraClient) ListProfiles(tokenInfo *TokenInfo) ([]secu.Policy, err
 	profiles := make([]secu.Policy, 0, len(policies))
 	for _, policy := range policies {
 		profile := secu.ConvertPolicy(&policy)
-		if profile != nil {
+		if profile != nil && profile.Tenant == client.tenantId {
 			profiles = append(profiles, *profile)
 		}
 	}
@@ -299,7 +333,7 @@ func (client HydraClient) ListPolicies(tokenInfo *TokenInfo) ([]secu.Policy, err
 	policies := make([]secu.Policy, 0, len(defaultPolicies))
 	for _, policy := range defaultPolicies {
 		profile := secu.ConvertPolicy(&policy)
-		if profile != nil {
+		if profile != nil && profile.Tenant == client.tenantId {
 			policies = append(policies, *profile)
 		}
 	}
@@ -308,6 +342,9 @@ func (client HydraClient) ListPolicies(tokenInfo *TokenInfo) ([]secu.Policy, err
 
 //FindPolicy find a policy by id
 func (client HydraClient) FindPolicy(profileId string, tokenInfo *TokenInfo) (*secu.Policy, error) {
+	if !client.ownsPolicy(profileId) {
+		return nil, nil
+	}
 	var policy policy.DefaultPolicy
 	httpClient := client.getHttpClient(tokenInfo)
 	if found, err, _ := client.findElement(&policy, policyPath+"/"+profileId, httpClient); !found || err != nil {
@@ -318,6 +355,10 @@ func (client HydraClient) FindPolicy(profileId string, tokenInfo *TokenInfo) (*s
 
 // CreatePolicy creates a new policy
 func (client HydraClient) CreatePolicy(policy *secu.Policy, tokenInfo *TokenInfo) (id string, err error) {
+	policy, err = client.tenantPolicy(policy)
+	if err != nil {
+		return "", err
+	}
 	httpClient := client.getHttpClient(tokenInfo)
 
 	hydraPolicy := policy.ToPolicy()
@@ -389,6 +430,9 @@ func isDefaultPolicy(policy policy.DefaultPolicy) bool {
 }
 
 func (client HydraClient) FindProfile(profileId string, tokenInfo *TokenInfo) (*secu.Policy, error) {
+	if !client.ownsPolicy(profileId) {
+		return nil, nil
+	}
 	var policy policy.DefaultPolicy
 	httpClient := client.getHttpClient(tokenInfo)
 	if found, err, _ := client.findElement(&policy, policyPath+"/"+profileId, httpClient); !found || err != nil {
@@ -398,6 +442,10 @@ func (client HydraClient) FindProfile(profileId string, tokenInfo *TokenInfo) (*
 }
 
 func (client HydraClient) CreateProfile(profile *secu.Policy, tokenInfo *TokenInfo) (id string, err error) {
+	profile, err = client.tenantPolicy(profile)
+	if err != nil {
+		return "", err
+	}
 	httpClient := client.getHttpClient(tokenInfo)
 
 	policy := profile.ToPolicy()
@@ -405,37 +453,21 @@ func (client HydraClient) CreateProfile(profile *secu.Policy, tokenInfo *TokenIn
 }
 
 func (client HydraClient) DeleteProfile(profileId string, tokenInfo *TokenInfo) error {
-	req, err := http.NewRequest("DELETE", client.authorizationServer+policyPath+"/"+profileId, nil)
-	if err != nil {
-		return err
+	if !client.ownsPolicy(profileId) {
+		return unknownProfile(profileId)
 	}
 	httpClient := client.getHttpClient(tokenInfo)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while deleting the profile", resp.Status))
-	}
-
-	return nil
+	_, _, err := client.sendRequest(httpClient, "DELETE", policyPath+"/"+profileId, nil, nil)
+	return err
 }
 
 func (client HydraClient) UpdateProfileDescription(profileId string, escapedDescription []byte, tokenInfo *TokenInfo) error {
-	req, err := http.NewRequest("PUT", client.authorizationServer+policyPath+"/"+profileId+"/description", bytes.NewReader(escapedDescription))
-	if err != nil {
-		return err
+	if !client.ownsPolicy(profileId) {
+		return unknownProfile(profileId)
 	}
 	httpClient := client.getHttpClient(tokenInfo)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while deleting the profile", resp.Status))
-	}
-
-	return nil
+	_, _, err := client.sendRequest(httpClient, "PUT", policyPath+"/"+profileId+"/description", escapedDescription, nil)
+	return err
 }
 
 func (client HydraClient) UpdateProfileUsers(profileId string, userIds []string, tokenInfo *TokenInfo) error {
@@ -443,6 +475,9 @@ func (client HydraClient) UpdateProfileUsers(profileId string, userIds []string,
 	if err != nil {
 		return err
 	}
+	if profile == nil {
+		return unknownProfile(profileId)
+	}
 
 	deletedUsers, addedUsers := diff(profile.Subjects, userIds)
 	for _, deletedUser := range deletedUsers {
@@ -460,37 +495,21 @@ func (client HydraClient) UpdateProfileUsers(profileId string, userIds []string,
 }
 
 func (client HydraClient) AddProfileUser(profileId string, userId string, tokenInfo *TokenInfo) error {
-	req, err := http.NewRequest("PUT", client.authorizationServer+policyPath+"/"+profileId+"/subjects/"+userId, nil)
-	if err != nil {
-		return err
+	if !client.ownsPolicy(profileId) {
+		return unknownProfile(profileId)
 	}
 	httpClient := client.getHttpClient(tokenInfo)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while adding user to the profile", resp.Status))
-	}
-
-	return nil
+	_, _, err := client.sendRequest(httpClient, "PUT", policyPath+"/"+profileId+"/subjects/"+userId, nil, nil)
+	return err
 }
 
 func (client HydraClient) DeleteProfileUser(profileId string, userId string, tokenInfo *TokenInfo) error {
-	req, err := http.NewRequest("DELETE", client.authorizationServer+policyPath+"/"+profileId+"/subjects/"+userId, nil)
-	if err != nil {
-		return err
+	if !client.ownsPolicy(profileId) {
+		return unknownProfile(profileId)
 	}
 	httpClient := client.getHttpClient(tokenInfo)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while deleting user from the profile", resp.Status))
-	}
-
-	return nil
+	_, _, err := client.sendRequest(httpClient, "DELETE", policyPath+"/"+profileId+"/subjects/"+userId, nil, nil)
+	return err
 }
 
 func (client HydraClient) UpdateProfileRoles(profileId string, roles []string, tokenInfo *TokenInfo) error {
@@ -516,92 +535,118 @@ func (client HydraClient) UpdateProfileRoles(profileId string, roles []string, t
 }
 
 func (client HydraClient) AddProfileRole(profileId string, role string, tokenInfo *TokenInfo) error {
-	req, err := http.NewRequest("PUT", client.authorizationServer+policyPath+"/"+profileId+"/permissions/"+role, nil)
-	if err != nil {
-		return err
+	if !client.ownsPolicy(profileId) {
+		return unknownProfile(profileId)
 	}
 	httpClient := client.getHttpClient(tokenInfo)
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return err
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while adding role the profile", resp.Status))
-	}
-
-	return nil
+	_, _, err := client.sendRequest(httpClient, "PUT", policyPath+"/"+profileId+"/permissions/"+role, nil, nil)
+	return err
 }
 
 func (client HydraClient) DeleteProfileRole(profileId string, role string, tokenInfo *TokenInfo) error {
-	req, err := http.NewRequest("DELETE", client.authorizationServer+policyPath+"/"+profileId+"/permissions/"+role, nil)
-	if err != nil {
-		return err
+	if !client.ownsPolicy(profileId) {
+		return unknownProfile(profileId)
 	}
 	httpClient := client.getHttpClient(tokenInfo)
-	resp, err := httpClient.Do(req)
+	_, _, err := client.sendRequest(httpClient, "DELETE", policyPath+"/"+profileId+"/permissions/"+role, nil, nil)
+	return err
+}
+
+// Returns an error if the user does not exist in the tenant of the client.
+func (client HydraClient) checkUser(userId string, tokenInfo *TokenInfo) error {
+	user, err := client.FindUser(userId, tokenInfo)
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return errors.New(fmt.Sprintf("Got status '%s' while deleting role from the profile", resp.Status))
+	if user == nil {
+		return fmt.Errorf("Unknown user: \"%s\"", userId)
 	}
-
 	return nil
 }
 
-func (client HydraClient) findElement(element interface{}, path string, httpClient *http.Client) (found bool, err error, respCode int) {
-	fullPath := client.authorizationServer + path
-	resp, err := httpClient.Get(fullPath)
-	if err != nil {
+// Returns true if the given policy id belongs to the tenant of the client.
+func (client HydraClient) ownsPolicy(policyId string) bool {
+	tenantId, _ := secu.SplitTenantPolicyId(policyId)
+	return tenantId == client.tenantId
+}
 
-		if resp == nil {
-			//when resp is nil, it means that the token is empty
-			respCode = http.StatusUnauthorized
-		} else {
-			respCode = resp.StatusCode
-		}
-		log.Printf("Got error when trying to find %v : %v", fullPath, err)
-		return false, errors.New("Error while getting the element: " + path), respCode
-	}
-	if resp.StatusCode == http.StatusNotFound {
-		element = nil
-		return false, nil, resp.StatusCode
+func unknownProfile(profileId string) error {
+	return fmt.Errorf("Unknown profile: \"%s\"", profileId)
+}
+
+// Returns a copy of the policy tagged with the tenant of the client.
+//
+// The tenant is part of the policy id, so the ids of the policies of
+// non-default tenants are generated here instead of by Hydra.
+func (client HydraClient) tenantPolicy(policy *secu.Policy) (*secu.Policy, error) {
+	if policy == nil {
+		return nil, errors.New("Policy must not be nil")
 	}
-	if resp.StatusCode == 401 {
-		//not authentified
-		return false, errors.New("Not authentified"), resp.StatusCode
+	tenantPolicy := *policy
+	tenantPolicy.Tenant = client.tenantId
+
+	tenantId, _ := secu.SplitTenantPolicyId(policy.Id)
+	switch {
+	case tenantId == client.tenantId:
+		return &tenantPolicy, nil
+	case tenantId != tenant.Default:
+		return nil, fmt.Errorf("The policy belongs to another tenant: \"%s\"", policy.Id)
 	}
-	if resp.StatusCode == 403 {
-		return false, errors.New("Not Authorized"), resp.StatusCode
+
+	if tenantPolicy.Id == "" {
+		id, err := newPolicyId()
+		if err != nil {
+			return nil, err
+		}
+		tenantPolicy.Id = id
 	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return false, errors.New(fmt.Sprintf("Got status '%s' while getting the element", resp.Status)), resp.StatusCode
+	tenantPolicy.Id = secu.TenantPolicyId(client.tenantId, tenantPolicy.Id)
+	return &tenantPolicy, nil
+}
+
+// Generates a random (version 4) UUID.
+func newPolicyId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	defer resp.Body.Close()
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
 
-	err = json.NewDecoder(resp.Body).Decode(element)
-	if err != nil {
-		return false, errors.New("Error while decoding the element: " + err.Error()), resp.StatusCode
+func (client HydraClient) findElement(element interface{}, path string, httpClient *http.Client) (found bool, err error, respCode int) {
+	respCode, _, err = client.sendRequest(httpClient, "GET", path, nil, element)
+	if err != nil && respCode == 0 {
+		//when there is no response, it means that the token is empty
+		log.Printf("Got error when trying to find %v : %v", client.authorizationServer+path, err)
+		return false, errors.New("Error while getting the element: " + path), http.StatusUnauthorized
+	}
+	switch {
+	case respCode == http.StatusNotFound:
+		return false, nil, respCode
+	case respCode == http.StatusUnauthorized:
+		//not authentified
+		return false, errors.New("Not authentified"), respCode
+	case respCode == http.StatusForbidden:
+		return false, errors.New("Not Authorized"), respCode
+	case err != nil:
+		return false, err, respCode
 	}
 
-	return true, nil, resp.StatusCode
+	return true, nil, respCode
 }
 
 func (client HydraClient) createElement(element interface{}, path string, httpClient *http.Client) (id string, err error) {
-	jsonElement, err := json.Marshal(element)
-	if err != nil {
-		return
-	}
-
-	resp, err := httpClient.Post(client.authorizationServer+path, "application/json", bytes.NewReader(jsonElement))
+	respCode, header, err := client.sendRequest(httpClient, "POST", path, element, nil)
 	if err != nil {
 		return
 	}
-	if resp.StatusCode != http.StatusCreated {
-		err = errors.New("Got status " + resp.Status)
+	if respCode != http.StatusCreated {
+		err = fmt.Errorf("Got status code %d", respCode)
 		return
 	}
-	location := resp.Header.Get("Location")
+	location := header.Get("Location")
 	if !strings.HasPrefix(location, path+"/") {
 		err = errors.New("Invalid location: " + location)
 		return
@@ -612,24 +657,134 @@ func (client HydraClient) createElement(element interface{}, path string, httpCl
 }
 
 func (client HydraClient) deleteElement(path string, httpClient *http.Client) (err error) {
+	respCode, _, err := client.sendRequest(httpClient, "DELETE", path, nil, nil)
+	if err != nil {
+		return err
+	}
+	if respCode != http.StatusOK {
+		return fmt.Errorf("Got status code %d", respCode)
+	}
+
+	return nil
+}
+
+// RequestError is returned when the authorization server answers a request
+// with a non-2xx status code.
+type RequestError struct {
+	StatusCode int
+	Status     string
+
+	// The error message decoded from the response payload, if any.
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("Got status '%s'", e.Status)
+	}
+	return fmt.Sprintf("Got status '%s': %s", e.Status, e.Message)
+}
+
+// The error payload returned by the authorization server.
+type errorPayload struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+	Message     string `json:"message"`
+}
+
+func (p errorPayload) message() string {
+	switch {
+	case p.Message != "":
+		return p.Message
+	case p.Description != "":
+		return p.Description
+	}
+	return p.Error
+}
+
+// Sends a request to the authorization server and returns the status code
+// and the headers of the response.
+//
+// The body (if not nil) is sent as JSON, unless it is already a byte slice.
+// On success, the response payload is decoded into result (if not nil).
+// On failure, a *RequestError holding the decoded error payload is returned.
+//
+// The response body is always drained and closed, so that the underlying
+// connection can be reused.
+func (client HydraClient) sendRequest(httpClient *http.Client, method, path string, body, result interface{}) (int, http.Header, error) {
+	if httpClient == nil {
+		return 0, nil, errors.New("No HTTP client available for " + path)
+	}
 
-	req, _ := http.NewRequest("DELETE", client.authorizationServer+path, nil)
+	var reader io.Reader
+	if body != nil {
+		bodyBytes, ok := body.([]byte)
+		if !ok {
+			var err error
+			if bodyBytes, err = json.Marshal(body); err != nil {
+				return 0, nil, err
+			}
+		}
+		reader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequest(method, client.authorizationServer+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		err = errors.New("Got status " + resp.Status)
-		return err
+	defer func() {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var payload errorPayload
+		json.NewDecoder(resp.Body).Decode(&payload)
+		return resp.StatusCode, resp.Header, &RequestError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Message:    payload.message(),
+		}
 	}
 
-	return nil
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return resp.StatusCode, resp.Header, errors.New("Error while decoding the element: " + err.Error())
+		}
+	}
+	return resp.StatusCode, resp.Header, nil
 }
 
+// Login issues a token to the user, who must belong to the tenant of the
+// client.
 func (client HydraClient) Login(username string, password string) (token *oauth2.Token, err error) {
+	token, err = client.oauth2Config.PasswordCredentialsToken(oauth2.NoContext, username, password)
+	if err != nil {
+		return nil, err
+	}
 
-	return client.oauth2Config.PasswordCredentialsToken(oauth2.NoContext, username, password)
+	tokenInfo, err := EncodeTokenInfo(token)
+	if err != nil {
+		return nil, err
+	}
+	tenantId, err := client.TenantOfToken(tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+	if tenantId != client.tenantId {
+		// the users of the other tenants are not visible
+		return nil, errors.New("Not Authorized")
+	}
+	return token, nil
 }
 
 func diff(oldIds, newIds []string) (deleted, added []string) {
@@ -0,0 +1,366 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/ory-am/ladon/policy"
+	"golang.org/x/oauth2"
+)
+
+// A transport counting the response bodies that were opened and closed.
+type countingTransport struct {
+	mu     sync.Mutex
+	opened int
+	closed int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.opened++
+	t.mu.Unlock()
+	resp.Body = &countingBody{ReadCloser: resp.Body, transport: t}
+	return resp, nil
+}
+
+type countingBody struct {
+	io.ReadCloser
+	transport *countingTransport
+}
+
+func (b *countingBody) Close() error {
+	b.transport.mu.Lock()
+	b.transport.closed++
+	b.transport.mu.Unlock()
+	return b.ReadCloser.Close()
+}
+
+func newFakeHydraServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts/1", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.Write([]byte(`{"id":"1","username":"user1@eogile.com","data":"{}"}`))
+		case "DELETE":
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+	mux.HandleFunc("/policies", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/policies/p1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"p1"}`))
+	})
+	mux.HandleFunc("/policies/p1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/policies/p1/description", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id":"p1"}`))
+	})
+	mux.HandleFunc("/policies/p1/subjects/u1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"error":"boom"}`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHydraClient_ResponseBodiesAreClosed(t *testing.T) {
+	server := newFakeHydraServer()
+	defer server.Close()
+
+	transport := &countingTransport{}
+	defaultClient := http.DefaultClient
+	http.DefaultClient = &http.Client{Transport: transport}
+	defer func() { http.DefaultClient = defaultClient }()
+
+	client := NewClient(server.URL, "client", "secret")
+
+	user, err := client.FindUser("1", nil)
+	if err != nil || user == nil || user.Login != "user1@eogile.com" {
+		t.Errorf("Unexpected result when finding user 1: %v, %v", user, err)
+	}
+	user, err = client.FindUser("404", nil)
+	if err != nil || user != nil {
+		t.Errorf("Unexpected result when finding an unknown user: %v, %v", user, err)
+	}
+	id, err := client.CreatePolicy(&secu.Policy{Resource: "accounts"}, nil)
+	if err != nil || id != "p1" {
+		t.Errorf("Unexpected result when creating a policy: %v, %v", id, err)
+	}
+	if err := client.UpdateProfileDescription("p1", []byte(`"description"`), nil); err != nil {
+		t.Error("Unexpected error when updating a profile description:", err)
+	}
+	if err := client.DeleteProfile("p1", nil); err != nil {
+		t.Error("Unexpected error when deleting a profile:", err)
+	}
+	if err := client.DeleteUser("1", nil); err != nil {
+		t.Error("Unexpected error when deleting a user:", err)
+	}
+
+	err = client.AddProfileUser("p1", "u1", nil)
+	requestErr, ok := err.(*RequestError)
+	if !ok {
+		t.Fatalf("Expected a *RequestError, got %v", err)
+	}
+	if requestErr.StatusCode != http.StatusInternalServerError || requestErr.Message != "boom" {
+		t.Errorf("Unexpected request error: %v", requestErr)
+	}
+
+	// deleting a user checks it exists in the tenant of the client first
+	if transport.opened != 8 {
+		t.Errorf("Expected 8 responses, got %d", transport.opened)
+	}
+	if transport.opened != transport.closed {
+		t.Errorf("%d response bodies were opened but only %d were closed", transport.opened, transport.closed)
+	}
+}
+
+// A fake Hydra server holding the accounts and policies of two tenants.
+// The created policies are recorded.
+func newFakeTenantsHydraServer(created *[]policy.DefaultPolicy) *httptest.Server {
+	return httptest.NewServer(newFakeTenantsHydraMux(created))
+}
+
+func newFakeTenantsHydraMux(created *[]policy.DefaultPolicy) *http.ServeMux {
+	accounts := map[string]string{
+		"1": `{"id":"1","username":"user1@eogile.com","data":"{}"}`,
+		"2": `{"id":"2","username":"user2@acme.com","data":"{\"tenant\":\"acme\"}"}`,
+	}
+	policies := []policy.DefaultPolicy{
+		{ID: "p1", Effect: "allow", Subjects: []string{"<.*>"}, Resources: []string{"rn:hydra:accounts"}, Permissions: []string{"delete"}},
+		{ID: "tenant:acme:p2", Effect: "allow", Subjects: []string{"<.*>"}, Resources: []string{"rn:hydra:accounts"}, Permissions: []string{"read"}},
+		defaultUserPolicy,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[" + accounts["1"] + "," + accounts["2"] + "]"))
+	})
+	mux.HandleFunc("/accounts/", func(w http.ResponseWriter, r *http.Request) {
+		account, found := accounts[strings.TrimPrefix(r.URL.Path, "/accounts/")]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(account))
+	})
+	mux.HandleFunc("/policies", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			var p policy.DefaultPolicy
+			json.NewDecoder(r.Body).Decode(&p)
+			*created = append(*created, p)
+			w.Header().Set("Location", "/policies/"+p.ID)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		json.NewEncoder(w).Encode(policies)
+	})
+	mux.HandleFunc("/policies/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/policies/")
+		for _, p := range policies {
+			if p.ID == id {
+				json.NewEncoder(w).Encode(p)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	})
+	return mux
+}
+
+func TestHydraClient_TenantIsolation(t *testing.T) {
+	created := []policy.DefaultPolicy{}
+	server := newFakeTenantsHydraServer(&created)
+	defer server.Close()
+
+	defaultClient := *NewClient(server.URL, "client", "secret")
+	acmeClient := defaultClient.ForTenant("acme")
+
+	/*
+	 * Users
+	 */
+	users, err, _ := acmeClient.ListUsers(nil)
+	if err != nil || len(users) != 1 || users[0].Id != "2" {
+		t.Errorf("Unexpected users of the acme tenant: %v, %v", users, err)
+	}
+	users, err, _ = defaultClient.ListUsers(nil)
+	if err != nil || len(users) != 1 || users[0].Id != "1" {
+		t.Errorf("Unexpected users of the default tenant: %v, %v", users, err)
+	}
+	if user, err := acmeClient.FindUser("1", nil); err != nil || user != nil {
+		t.Errorf("The user of the default tenant should not be visible: %v, %v", user, err)
+	}
+	if err := acmeClient.DeleteUser("1", nil); err == nil {
+		t.Error("Expected an error when deleting the user of another tenant")
+	}
+	if err := acmeClient.UpdateUserLogin("1", secu.UpdateLoginRequest{Login: "user1@acme.com"}, nil); err == nil ||
+		err.Error() != `Unknown user: "1"` {
+		t.Error("Expected an error when updating the login of the user of another tenant:", err)
+	}
+	if err := acmeClient.UpdateUserPassword("1", secu.UpdatePasswordRequest{NewPassword: "password"}, nil); err == nil ||
+		err.Error() != `Unknown user: "1"` {
+		t.Error("Expected an error when updating the password of the user of another tenant:", err)
+	}
+
+	/*
+	 * Policies
+	 */
+	policies, err, _ := acmeClient.ListPolicies(nil)
+	if err != nil || len(policies) != 1 || policies[0].Id != "tenant:acme:p2" || policies[0].Tenant != "acme" {
+		t.Errorf("Unexpected policies of the acme tenant: %v, %v", policies, err)
+	}
+	if p, err := defaultClient.FindPolicy("tenant:acme:p2", nil); err != nil || p != nil {
+		t.Errorf("The policy of the acme tenant should not be visible: %v, %v", p, err)
+	}
+	if err := defaultClient.AddProfileUser("tenant:acme:p2", "1", nil); err == nil {
+		t.Error("Expected an error when updating the policy of another tenant")
+	}
+
+	if _, err := acmeClient.CreatePolicy(&secu.Policy{Id: "p3", Resource: "rn:hydra:accounts"}, nil); err != nil {
+		t.Error("Unexpected error when creating a policy:", err)
+	}
+	if _, err := acmeClient.CreatePolicy(&secu.Policy{Resource: "rn:hydra:accounts"}, nil); err != nil {
+		t.Error("Unexpected error when creating a policy without id:", err)
+	}
+	if len(created) != 2 || created[0].ID != "tenant:acme:p3" || !strings.HasPrefix(created[1].ID, "tenant:acme:") {
+		t.Errorf("Unexpected created policies: %v", created)
+	}
+	if _, err := defaultClient.CreatePolicy(&secu.Policy{Id: "tenant:acme:p4"}, nil); err == nil {
+		t.Error("Expected an error when creating a policy in another tenant")
+	}
+}
+
+// Resources storage client holding fixed resources.
+type fakeResources struct {
+	resource.PluginResourcesStorageClient
+	resources []resource.Resource
+}
+
+func (f fakeResources) ListResources() ([]resource.Resource, error) {
+	return f.resources, nil
+}
+
+func TestHydraClient_TenantEffectivePermissions(t *testing.T) {
+	created := []policy.DefaultPolicy{}
+	server := newFakeTenantsHydraServer(&created)
+	defer server.Close()
+
+	resources := fakeResources{resources: []resource.Resource{
+		{Key: "accounts", SecurityKey: "rn:hydra:accounts", Permissions: []string{"read", "delete"}},
+	}}
+	// The warden applies the policies of the default tenant to all the tenants
+	permissions, err := NewClient(server.URL, "client", "secret").ForTenant("acme").EffectivePermissions("2", resources, nil, nil)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if policyIds := grantingPolicyIds(permissions); policyIds != "default-policy,p1,tenant:acme:p2" {
+		t.Errorf("The policies of the acme and default tenants should apply: %s", policyIds)
+	}
+
+	permissions, err = NewClient(server.URL, "client", "secret").EffectivePermissions("1", resources, nil, nil)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if policyIds := grantingPolicyIds(permissions); policyIds != "default-policy,p1" {
+		t.Errorf("Only the policies of the default tenant should apply: %s", policyIds)
+	}
+}
+
+// Returns the sorted ids of the policies granting the permissions, joined by ",".
+func grantingPolicyIds(permissions *secu.EffectivePermissions) string {
+	policyIds := []string{}
+	for _, res := range permissions.Resources {
+		for _, permission := range res.Permissions {
+			policyIds = append(policyIds, permission.PolicyId)
+		}
+	}
+	sort.Strings(policyIds)
+	return strings.Join(policyIds, ",")
+}
+
+func TestHydraClient_UpdateUserWithToken(t *testing.T) {
+	tokenInfo, err := EncodeTokenInfo(&oauth2.Token{AccessToken: "t1", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/accounts/1", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"id":"1","username":"user1@eogile.com","data":"{}"}`))
+	})
+	mux.HandleFunc("/accounts/1/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := NewClient(server.URL, "client", "secret")
+
+	// The user is checked with the token
+	if err := client.UpdateUserLogin("1", secu.UpdateLoginRequest{Login: "user@eogile.com"}, tokenInfo); err != nil {
+		t.Error("Unexpected error when updating the login:", err)
+	}
+	if err := client.UpdateUserPassword("1", secu.UpdatePasswordRequest{NewPassword: "password"}, tokenInfo); err != nil {
+		t.Error("Unexpected error when updating the password:", err)
+	}
+	if err := client.UpdateUserLogin("1", secu.UpdateLoginRequest{Login: "user@eogile.com"}, nil); err == nil {
+		t.Error("Expected an error when updating the login without token")
+	}
+}
+
+func TestHydraClient_LoginTenant(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	tokenPublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	defer func() { tokenPublicKey = dummyKey }()
+
+	created := []policy.DefaultPolicy{}
+	mux := newFakeTenantsHydraMux(&created)
+	mux.HandleFunc("/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		accessToken := jwt.New(jwt.SigningMethodRS256)
+		accessToken.Claims["sub"] = map[string]string{"user1@eogile.com": "1", "user2@acme.com": "2"}[r.FormValue("username")]
+		signed, err := accessToken.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": signed, "token_type": "Bearer", "expires_in": 3600})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	acmeClient := NewClient(server.URL, "client", "secret").ForTenant("acme")
+	if token, err := acmeClient.Login("user2@acme.com", "password"); err != nil || token == nil {
+		t.Errorf("The user of the acme tenant should log in: %v, %v", token, err)
+	}
+	if token, err := acmeClient.Login("user1@eogile.com", "password"); err == nil || token != nil {
+		t.Errorf("The user of the default tenant should not log in through the acme client: %v, %v", token, err)
+	}
+}
@@ -10,7 +10,7 @@ import (
 )
 
 func TestLogin(t *testing.T) {
-	client := auth.NewClient("http://localhost:9090", "superapp2", "supersecret2")
+	client := auth.NewClient(hydra.URL, hydra.ClientID, hydra.ClientSecret)
 	token, err := client.Login("superadmin@eogile.com", "supersecret")
 	require.Nil(t, err)
 	require.NotNil(t, token)
@@ -22,7 +22,7 @@ func TestLogin(t *testing.T) {
 }
 
 func TestCreateUser(t *testing.T) {
-	client := auth.NewClient("http://localhost:9090", "superapp2", "supersecret2")
+	client := auth.NewClient(hydra.URL, hydra.ClientID, hydra.ClientSecret)
 	token, err := client.Login("superadmin@eogile.com", "supersecret")
 	require.Nil(t, err)
 	require.NotNil(t, token)
@@ -57,7 +57,7 @@ func TestCreateUser(t *testing.T) {
 }
 
 func TestUpdateUser(t *testing.T) {
-	client := auth.NewClient("http://localhost:9090", "superapp2", "supersecret2")
+	client := auth.NewClient(hydra.URL, hydra.ClientID, hydra.ClientSecret)
 	token, err := client.Login("superadmin@eogile.com", "supersecret")
 
 	tokenInfo, err := auth.EncodeTokenInfo(token)
@@ -105,7 +105,7 @@ func TestUpdateUser(t *testing.T) {
 // Tests that the default policy allows an user to access its own data
 // but not other users data.
 func TestDefaultPolicy(t *testing.T) {
-	client := auth.NewClient("http://localhost:9090", "superapp2", "supersecret2")
+	client := auth.NewClient(hydra.URL, hydra.ClientID, hydra.ClientSecret)
 	token, err := client.Login("superadmin@eogile.com", "supersecret")
 	require.Nil(t, err)
 	require.NotNil(t, token)
@@ -158,7 +158,7 @@ func TestDefaultPolicy(t *testing.T) {
 }
 
 func TestDefaultPolicy_Recreation(t *testing.T) {
-	client := auth.NewClient("http://localhost:9090", "superapp2", "supersecret2")
+	client := auth.NewClient(hydra.URL, hydra.ClientID, hydra.ClientSecret)
 	token, err := client.Login("superadmin@eogile.com", "supersecret")
 	require.Nil(t, err)
 	require.NotNil(t, token)
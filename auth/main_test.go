@@ -13,6 +13,9 @@ const (
 	network = "network-test"
 )
 
+// The Hydra container used by the tests
+var hydra *test.HydraInfo
+
 func TestMain(m *testing.M) {
 	/*
 		Docker client for test utilities
@@ -24,16 +27,23 @@ func TestMain(m *testing.M) {
 	*/
 	dockerClient.CreateNetWorkIfNeeded(network)
 
+	fixture := test.NewFixture(dockerClient, network)
+	fixture.CleanupOnInterrupt()
+
 	/*
 	 * Bootstrap the PostgreSQL and the Hydra containers.
 	 */
-	if err := test.StartHydraContainer(dockerClient, network); err != nil {
-		log.Fatalln("Error while starting PostgreSQL and Hydra containers", err)
+	var err error
+	hydra, err = fixture.StartHydra()
+	if err != nil {
+		log.Println("Error while starting PostgreSQL and Hydra containers", err)
+		fixture.Cleanup()
+		os.Exit(1)
 	}
 
 	exitCode := m.Run()
 
-	if err := test.StopHydraContainer(dockerClient); err != nil {
+	if err := fixture.Cleanup(); err != nil {
 		log.Fatalln("Error while stopping PostgreSQL and Hydra containers:", err)
 	}
 
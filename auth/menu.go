@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"errors"
+
+	"github.com/eogile/agilestack-utils/plugins/group"
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/secu"
+)
+
+// UserMenu restricts the application menu to the entries the user is allowed
+// to open, according to the policies granted to the user and to the user
+// groups (see EffectivePermissions). Inactive and blocked users get an empty
+// menu.
+func (client HydraClient) UserMenu(user *secu.User, applicationMenu *menu.ApplicationMenu, resources resource.PluginResourcesStorageClient, groups group.GroupsStorageClient, tokenInfo *TokenInfo) (*menu.ApplicationMenu, error) {
+	if user == nil {
+		return nil, errors.New("User must not be nil")
+	}
+	if applicationMenu == nil {
+		return nil, errors.New("Application menu must not be nil")
+	}
+	if user.IsInactive() || user.IsBlocked() {
+		return &menu.ApplicationMenu{Entries: []menu.MenuEntry{}}, nil
+	}
+	permissions, err := client.EffectivePermissions(user.Id, resources, groups, tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+	return menu.FilterMenu(applicationMenu, permissions), nil
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eogile/agilestack-utils/plugins/group"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/tenant"
+	"github.com/ory-am/ladon/policy"
+)
+
+// EffectivePermissions resolves what the given user can actually do.
+//
+// All the policies are walked, including the ones whose subjects are
+// patterns (such as "<.*>" in the default policy) and the ones granted to the
+// user groups (groups may be nil to ignore group memberships). The resources
+// of the matching policies are resolved against the registered resources, and
+// each permission is annotated with the policy granting it.
+//
+// The policies of the tenant of the client and the ones of the default tenant
+// apply: the warden does not know about the tenants and applies the default
+// policies to the users of all the tenants.
+func (client HydraClient) EffectivePermissions(userId string, resources resource.PluginResourcesStorageClient, groups group.GroupsStorageClient, tokenInfo *TokenInfo) (*secu.EffectivePermissions, error) {
+	subjects := []string{userId}
+	if groups != nil {
+		userGroups, err := groups.ListUserGroups(userId)
+		if err != nil {
+			return nil, err
+		}
+		for _, userGroup := range userGroups {
+			subjects = append(subjects, secu.GroupSubject(userGroup.Id))
+		}
+	}
+
+	registeredResources, err := resources.ListResources()
+	if err != nil {
+		return nil, err
+	}
+
+	policies := []policy.DefaultPolicy{}
+	httpClient := client.getHttpClient(tokenInfo)
+	if _, err, _ := client.findElement(&policies, policyPath, httpClient); err != nil {
+		return nil, err
+	}
+
+	byResource := make(map[string]*secu.ResourcePermissions)
+	entry := func(key, securityKey string) *secu.ResourcePermissions {
+		if _, found := byResource[key]; !found {
+			byResource[key] = &secu.ResourcePermissions{
+				Resource:    key,
+				SecurityKey: securityKey,
+				Permissions: []secu.GrantedPermission{},
+			}
+		}
+		return byResource[key]
+	}
+
+	for _, p := range policies {
+		// only the global policies and the ones of the client tenant apply
+		if policyTenant, _ := secu.SplitTenantPolicyId(p.ID); policyTenant != tenant.Default && policyTenant != client.tenantId {
+			continue
+		}
+		if matched, err := matchesAny(p.Subjects, subjects); err != nil {
+			return nil, err
+		} else if !matched {
+			continue
+		}
+
+		for _, policyResource := range p.Resources {
+			resolved := false
+			for _, res := range registeredResources {
+				if matched, err := matchesPattern(policyResource, res.SecurityKey); err != nil {
+					return nil, err
+				} else if !matched {
+					continue
+				}
+				resolved = true
+				resourceEntry := entry(res.Key, res.SecurityKey)
+				for _, permission := range res.Permissions {
+					if matched, err := matchesAny(p.Permissions, []string{permission}); err != nil {
+						return nil, err
+					} else if matched {
+						resourceEntry.Permissions = append(resourceEntry.Permissions, grantedPermission(permission, p))
+					}
+				}
+			}
+
+			// The policy resource does not match any registered resource:
+			// the policy permissions are reported as is.
+			if !resolved {
+				resourceEntry := entry(policyResource, policyResource)
+				for _, permission := range p.Permissions {
+					resourceEntry.Permissions = append(resourceEntry.Permissions, grantedPermission(permission, p))
+				}
+			}
+		}
+	}
+
+	effectivePermissions := &secu.EffectivePermissions{
+		UserId:    userId,
+		Resources: make([]secu.ResourcePermissions, 0, len(byResource)),
+	}
+	for _, resourcePermissions := range byResource {
+		sort.Sort(byPermission(resourcePermissions.Permissions))
+		effectivePermissions.Resources = append(effectivePermissions.Resources, *resourcePermissions)
+	}
+	sort.Sort(byResourceKey(effectivePermissions.Resources))
+	return effectivePermissions, nil
+}
+
+func grantedPermission(permission string, p policy.DefaultPolicy) secu.GrantedPermission {
+	return secu.GrantedPermission{
+		Permission:        permission,
+		Effect:            p.Effect,
+		PolicyId:          p.ID,
+		PolicyDescription: p.Description,
+		Conditional:       len(p.Conditions) > 0,
+	}
+}
+
+// Returns true if one of the patterns matches one of the values.
+func matchesAny(patterns []string, values []string) (bool, error) {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			matched, err := matchesPattern(pattern, value)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// Checks the value against a policy pattern, where the parts enclosed in
+// "<" and ">" are regular expressions (e.g. "<.*>" or "rn:hydra:accounts:<.*>").
+func matchesPattern(pattern, value string) (bool, error) {
+	if !strings.Contains(pattern, "<") {
+		return pattern == value, nil
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("^")
+	remaining := pattern
+	for {
+		start := strings.Index(remaining, "<")
+		if start < 0 {
+			buffer.WriteString(regexp.QuoteMeta(remaining))
+			break
+		}
+		end := strings.Index(remaining[start:], ">")
+		if end < 0 {
+			return false, fmt.Errorf("Unbalanced delimiters in pattern \"%s\"", pattern)
+		}
+		buffer.WriteString(regexp.QuoteMeta(remaining[:start]))
+		buffer.WriteString("(" + remaining[start+1:start+end] + ")")
+		remaining = remaining[start+end+1:]
+	}
+	buffer.WriteString("$")
+
+	re, err := regexp.Compile(buffer.String())
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(value), nil
+}
+
+type byResourceKey []secu.ResourcePermissions
+
+func (r byResourceKey) Len() int           { return len(r) }
+func (r byResourceKey) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r byResourceKey) Less(i, j int) bool { return r[i].Resource < r[j].Resource }
+
+type byPermission []secu.GrantedPermission
+
+func (p byPermission) Len() int      { return len(p) }
+func (p byPermission) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byPermission) Less(i, j int) bool {
+	if p[i].Permission != p[j].Permission {
+		return p[i].Permission < p[j].Permission
+	}
+	return p[i].PolicyId < p[j].PolicyId
+}
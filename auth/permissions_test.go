@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/ory-am/ladon/policy"
+)
+
+// A resources storage client only able to list the given resources.
+type listedResources []resource.Resource
+
+func (r listedResources) StoreResource(resource.Resource) error          { return nil }
+func (r listedResources) GetResource(string) (*resource.Resource, error) { return nil, nil }
+func (r listedResources) ListResources() ([]resource.Resource, error)    { return r, nil }
+func (r listedResources) DeleteResource(string) error                    { return nil }
+
+func TestMatchesPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		value   string
+		matched bool
+	}{
+		{"user-a", "user-a", true},
+		{"user-a", "user-b", false},
+		{"<.*>", "user-a", true},
+		{"<rn:hydra:accounts:.*>", "rn:hydra:accounts:1", true},
+		{"<rn:hydra:accounts:.*>", "rn:hydra:accounts", false},
+		{"rn:hydra:<accounts|clients>", "rn:hydra:clients", true},
+		{"rn:hydra:<accounts|clients>", "rn:hydra:policies", false},
+		{"rn.hydra", "rnXhydra", false},
+	}
+	for _, c := range cases {
+		matched, err := matchesPattern(c.pattern, c.value)
+		if err != nil {
+			t.Errorf("Unexpected error for pattern %s: %v", c.pattern, err)
+		}
+		if matched != c.matched {
+			t.Errorf("Pattern %s, value %s: expected %v, got %v", c.pattern, c.value, c.matched, matched)
+		}
+	}
+
+	if _, err := matchesPattern("rn:<accounts", "rn:accounts"); err == nil {
+		t.Error("Expected an error for an unbalanced pattern")
+	}
+}
+
+func TestEffectivePermissions(t *testing.T) {
+	policies := []policy.DefaultPolicy{
+		defaultUserPolicy,
+		policy.DefaultPolicy{
+			ID:          "p1",
+			Description: "Accounts readers",
+			Subjects:    []string{"user-a"},
+			Effect:      "allow",
+			Resources:   []string{"rn:hydra:accounts"},
+			Permissions: []string{"get"},
+		},
+		policy.DefaultPolicy{
+			ID:          "p2",
+			Description: "Developers",
+			Subjects:    []string{secu.GroupSubject("developers")},
+			Effect:      "allow",
+			Resources:   []string{"<rn:hydra:.*>"},
+			Permissions: []string{"<.*>"},
+		},
+		policy.DefaultPolicy{
+			ID:          "p3",
+			Description: "Somebody else",
+			Subjects:    []string{"user-b"},
+			Effect:      "allow",
+			Resources:   []string{"rn:hydra:accounts"},
+			Permissions: []string{"delete"},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(policies)
+	}))
+	defer server.Close()
+
+	resources := listedResources{
+		resource.Resource{Key: "accounts", SecurityKey: "rn:hydra:accounts", Permissions: []string{"get", "delete"}},
+		resource.Resource{Key: "clients", SecurityKey: "rn:hydra:clients", Permissions: []string{"get"}},
+	}
+	groups := &fakeGroups{[]secu.Group{secu.Group{Id: "developers", Members: []string{"user-a"}}}}
+
+	client := NewClient(server.URL, "client", "secret")
+	result, err := client.EffectivePermissions("user-a", resources, groups, nil)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+
+	expected := &secu.EffectivePermissions{
+		UserId: "user-a",
+		Resources: []secu.ResourcePermissions{
+			secu.ResourcePermissions{
+				Resource:    "<rn:hydra:accounts:.*>",
+				SecurityKey: "<rn:hydra:accounts:.*>",
+				Permissions: []secu.GrantedPermission{
+					granted("get", "default-policy", defaultUserPolicy.Description, true),
+				},
+			},
+			secu.ResourcePermissions{
+				Resource:    "accounts",
+				SecurityKey: "rn:hydra:accounts",
+				Permissions: []secu.GrantedPermission{
+					granted("delete", "p2", "Developers", false),
+					granted("get", "p1", "Accounts readers", false),
+					granted("get", "p2", "Developers", false),
+				},
+			},
+			secu.ResourcePermissions{
+				Resource:    "clients",
+				SecurityKey: "rn:hydra:clients",
+				Permissions: []secu.GrantedPermission{
+					granted("get", "p2", "Developers", false),
+				},
+			},
+		},
+	}
+	expectedJSON, _ := expected.JSON()
+	resultJSON, err := result.JSON()
+	if err != nil {
+		t.Fatal("Unexpected error while rendering the permissions:", err)
+	}
+	if string(expectedJSON) != string(resultJSON) {
+		t.Errorf("Unexpected effective permissions\nexpected: %s\nactual:   %s", expectedJSON, resultJSON)
+	}
+}
+
+func TestUserMenu(t *testing.T) {
+	policies := []policy.DefaultPolicy{
+		policy.DefaultPolicy{
+			ID:          "p1",
+			Subjects:    []string{"user-a"},
+			Effect:      "allow",
+			Resources:   []string{"rn:hydra:accounts"},
+			Permissions: []string{"get"},
+		},
+		policy.DefaultPolicy{
+			ID:          "p2",
+			Subjects:    []string{secu.GroupSubject("auditors")},
+			Effect:      "allow",
+			Resources:   []string{"rn:hydra:clients"},
+			Permissions: []string{"get"},
+		},
+		policy.DefaultPolicy{
+			ID:          "p3",
+			Subjects:    []string{"user-a"},
+			Effect:      "deny",
+			Resources:   []string{"rn:hydra:clients"},
+			Permissions: []string{"get"},
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(policies)
+	}))
+	defer server.Close()
+
+	resources := listedResources{
+		resource.Resource{Key: "accounts", SecurityKey: "rn:hydra:accounts", Permissions: []string{"get"}},
+		resource.Resource{Key: "clients", SecurityKey: "rn:hydra:clients", Permissions: []string{"get"}},
+	}
+	groups := &fakeGroups{[]secu.Group{secu.Group{Id: "auditors", Members: []string{"user-a", "user-b"}}}}
+	applicationMenu := &menu.ApplicationMenu{
+		Entries: []menu.MenuEntry{
+			menu.MenuEntry{Name: "Accounts", Route: "/accounts", Resource: "accounts", Permission: "get", Entries: []menu.MenuEntry{}},
+			menu.MenuEntry{Name: "Clients", Route: "/clients", Resource: "clients", Permission: "get", Entries: []menu.MenuEntry{}},
+		},
+	}
+
+	client := NewClient(server.URL, "client", "secret")
+	names := func(user *secu.User) []string {
+		userMenu, err := client.UserMenu(user, applicationMenu, resources, groups, nil)
+		if err != nil {
+			t.Fatal("Unexpected error:", err)
+		}
+		names := []string{}
+		for _, entry := range userMenu.Entries {
+			names = append(names, entry.Name)
+		}
+		return names
+	}
+
+	// The denial of the clients for user-a wins over the group policy
+	if result := names(&secu.User{Id: "user-a"}); len(result) != 1 || result[0] != "Accounts" {
+		t.Errorf("Unexpected menu of user-a: %v", result)
+	}
+	if result := names(&secu.User{Id: "user-b"}); len(result) != 1 || result[0] != "Clients" {
+		t.Errorf("Unexpected menu of user-b: %v", result)
+	}
+	blocked := &secu.User{Id: "user-a"}
+	blocked.SetBlocked(true)
+	if result := names(blocked); len(result) != 0 {
+		t.Errorf("Unexpected menu of a blocked user: %v", result)
+	}
+}
+
+func granted(permission, policyId, policyDescription string, conditional bool) secu.GrantedPermission {
+	return secu.GrantedPermission{
+		Permission:        permission,
+		Effect:            "allow",
+		PolicyId:          policyId,
+		PolicyDescription: policyDescription,
+		Conditional:       conditional,
+	}
+}
+
+// A groups storage client only able to list the user groups.
+type fakeGroups struct {
+	groups []secu.Group
+}
+
+func (g *fakeGroups) StoreGroup(secu.Group) error          { return nil }
+func (g *fakeGroups) GetGroup(string) (*secu.Group, error) { return nil, nil }
+func (g *fakeGroups) ListGroups() ([]secu.Group, error)    { return g.groups, nil }
+func (g *fakeGroups) DeleteGroup(string) error             { return nil }
+func (g *fakeGroups) AddMember(string, string) error       { return nil }
+func (g *fakeGroups) RemoveMember(string, string) error    { return nil }
+
+func (g *fakeGroups) ListUserGroups(userId string) ([]secu.Group, error) {
+	userGroups := []secu.Group{}
+	for _, group := range g.groups {
+		for _, member := range group.Members {
+			if member == userId {
+				userGroups = append(userGroups, group)
+			}
+		}
+	}
+	return userGroups, nil
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/slices"
+)
+
+// AssignRole grants the given role to the user.
+//
+// The policies materializing the role are reconciled with the role definition
+// first, then the user is added to the subjects of each of them.
+func (client HydraClient) AssignRole(userId string, role *secu.Role, resources resource.PluginResourcesStorageClient, tokenInfo *TokenInfo) error {
+	expected, err := rolePolicies(client.tenantId, role, resources)
+	if err != nil {
+		return err
+	}
+	current, err := client.reconcileRolePolicies(role.Id, expected, tokenInfo)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range expected {
+		existing, found := current[policy.Id]
+		if !found {
+			policy.Subjects = []string{userId}
+			if _, err := client.CreatePolicy(&policy, tokenInfo); err != nil {
+				return err
+			}
+			continue
+		}
+		if !slices.StringInSlice(userId, existing.Subjects) {
+			if err := client.AddProfileUser(policy.Id, userId, tokenInfo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RevokeRole removes the user from the subjects of all the policies
+// materializing the given role.
+func (client HydraClient) RevokeRole(userId string, roleId string, tokenInfo *TokenInfo) error {
+	current, err := client.listRolePolicies(roleId, tokenInfo)
+	if err != nil {
+		return err
+	}
+	for _, policy := range current {
+		if slices.StringInSlice(userId, policy.Subjects) {
+			if err := client.DeleteProfileUser(policy.Id, userId, tokenInfo); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReconcileRole updates the policies materializing the given role after its
+// definition changed. The users holding the role keep it.
+func (client HydraClient) ReconcileRole(role *secu.Role, resources resource.PluginResourcesStorageClient, tokenInfo *TokenInfo) error {
+	expected, err := rolePolicies(client.tenantId, role, resources)
+	if err != nil {
+		return err
+	}
+	_, err = client.reconcileRolePolicies(role.Id, expected, tokenInfo)
+	return err
+}
+
+// Makes the existing policies of the role match the expected ones:
+// - policies that no longer match a grant are deleted,
+// - policies whose resource or permissions changed are re-created,
+// - missing policies are created for the users already holding the role.
+//
+// Returns the policies of the role after the reconciliation, by id.
+func (client HydraClient) reconcileRolePolicies(roleId string, expected []secu.Policy, tokenInfo *TokenInfo) (map[string]secu.Policy, error) {
+	current, err := client.listRolePolicies(roleId, tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	holders := []string{}
+	for _, policy := range current {
+		_, added := diff(holders, policy.Subjects)
+		holders = append(holders, added...)
+	}
+
+	reconciled := make(map[string]secu.Policy, len(expected))
+	for _, policy := range expected {
+		existing, found := current[policy.Id]
+		if found {
+			deleted, added := diff(existing.Permissions, policy.Permissions)
+			if existing.Resource == policy.Resource && len(deleted) == 0 && len(added) == 0 {
+				reconciled[policy.Id] = existing
+				delete(current, policy.Id)
+				continue
+			}
+			if err := client.DeleteProfile(policy.Id, tokenInfo); err != nil {
+				return nil, err
+			}
+			delete(current, policy.Id)
+		}
+		if len(holders) == 0 {
+			continue
+		}
+		policy.Subjects = holders
+		if _, err := client.CreatePolicy(&policy, tokenInfo); err != nil {
+			return nil, err
+		}
+		reconciled[policy.Id] = policy
+	}
+
+	// The remaining policies match grants removed from the role.
+	for id := range current {
+		if err := client.DeleteProfile(id, tokenInfo); err != nil {
+			return nil, err
+		}
+	}
+	return reconciled, nil
+}
+
+// Lists the policies materializing the grants of the given role, by id.
+func (client HydraClient) listRolePolicies(roleId string, tokenInfo *TokenInfo) (map[string]secu.Policy, error) {
+	policies, err, _ := client.ListPolicies(tokenInfo)
+	if err != nil {
+		return nil, err
+	}
+	rolePolicies := make(map[string]secu.Policy)
+	for _, policy := range policies {
+		if secu.IsRolePolicyId(policy.Id, roleId) {
+			rolePolicies[policy.Id] = policy
+		}
+	}
+	return rolePolicies, nil
+}
+
+// Builds the policies (without subjects) materializing the grants of the role
+// in the given tenant. The policies resources are the security keys of the
+// granted resources.
+func rolePolicies(tenantId string, role *secu.Role, resources resource.PluginResourcesStorageClient) ([]secu.Policy, error) {
+	if role == nil {
+		return nil, errors.New("Role must not be nil")
+	}
+	policies := make([]secu.Policy, 0, len(role.Grants))
+	for _, grant := range role.Grants {
+		if err := secu.ValidateRolePolicyId(role.Id, grant.Resource); err != nil {
+			return nil, err
+		}
+		res, err := resources.GetResource(grant.Resource)
+		if err != nil {
+			return nil, err
+		}
+		if res == nil {
+			return nil, fmt.Errorf("Unknown resource: \"%s\"", grant.Resource)
+		}
+		policies = append(policies, secu.Policy{
+			Id:          secu.TenantPolicyId(tenantId, secu.RolePolicyId(role.Id, grant.Resource)),
+			Description: role.Description,
+			Subjects:    []string{},
+			Permissions: grant.Permissions,
+			Resource:    res.SecurityKey,
+			Tenant:      tenantId,
+		})
+	}
+	return policies, nil
+}
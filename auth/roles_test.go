@@ -0,0 +1,128 @@
+package auth_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/auth"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/ory-am/osin-storage/Godeps/_workspace/src/github.com/stretchr/testify/require"
+)
+
+// An in-memory resources storage client, by resource key.
+type fakeResourcesStorageClient map[string]resource.Resource
+
+func (c fakeResourcesStorageClient) StoreResource(res resource.Resource) error {
+	c[res.Key] = res
+	return nil
+}
+
+func (c fakeResourcesStorageClient) GetResource(name string) (*resource.Resource, error) {
+	res, found := c[name]
+	if !found {
+		return nil, nil
+	}
+	return &res, nil
+}
+
+func (c fakeResourcesStorageClient) ListResources() ([]resource.Resource, error) {
+	list := make([]resource.Resource, 0, len(c))
+	for _, res := range c {
+		list = append(list, res)
+	}
+	return list, nil
+}
+
+func (c fakeResourcesStorageClient) DeleteResource(name string) error {
+	delete(c, name)
+	return nil
+}
+
+var roleResources = fakeResourcesStorageClient{
+	"accounts": resource.Resource{
+		Key:         "accounts",
+		SecurityKey: "rn:hydra:accounts",
+		Permissions: []string{"create", "get", "delete"},
+	},
+	"policies": resource.Resource{
+		Key:         "policies",
+		SecurityKey: "rn:hydra:policies",
+		Permissions: []string{"create", "get"},
+	},
+}
+
+func TestAssignAndRevokeRole(t *testing.T) {
+	client := auth.NewClient(hydra.URL, hydra.ClientID, hydra.ClientSecret)
+	token, err := client.Login("superadmin@eogile.com", "supersecret")
+	require.Nil(t, err)
+	tokenInfo, err := auth.EncodeTokenInfo(token)
+	require.Nil(t, err)
+
+	role := &secu.Role{
+		Id:          "reader",
+		Description: "Read only access",
+		Grants: []secu.Grant{
+			secu.Grant{Resource: "accounts", Permissions: []string{"get"}},
+			secu.Grant{Resource: "policies", Permissions: []string{"get"}},
+		},
+	}
+	require.Nil(t, client.AssignRole("user-a", role, roleResources, tokenInfo))
+	require.Nil(t, client.AssignRole("user-b", role, roleResources, tokenInfo))
+
+	policy, err := client.FindPolicy(secu.RolePolicyId("reader", "accounts"), tokenInfo)
+	require.Nil(t, err)
+	require.NotNil(t, policy)
+	require.Equal(t, "rn:hydra:accounts", policy.Resource)
+	require.Equal(t, []string{"get"}, policy.Permissions)
+	require.Equal(t, []string{"user-a", "user-b"}, policy.Subjects)
+
+	/*
+	 * Changing the role definition: the users keep the role.
+	 */
+	role.Grants = []secu.Grant{
+		secu.Grant{Resource: "accounts", Permissions: []string{"get", "create"}},
+	}
+	require.Nil(t, client.ReconcileRole(role, roleResources, tokenInfo))
+
+	policy, err = client.FindPolicy(secu.RolePolicyId("reader", "accounts"), tokenInfo)
+	require.Nil(t, err)
+	require.NotNil(t, policy)
+	require.Equal(t, []string{"get", "create"}, policy.Permissions)
+	require.Equal(t, []string{"user-a", "user-b"}, policy.Subjects)
+
+	policy, err = client.FindPolicy(secu.RolePolicyId("reader", "policies"), tokenInfo)
+	require.Nil(t, err)
+	require.Nil(t, policy)
+
+	/*
+	 * Revoking the role of user-a
+	 */
+	require.Nil(t, client.RevokeRole("user-a", "reader", tokenInfo))
+
+	policy, err = client.FindPolicy(secu.RolePolicyId("reader", "accounts"), tokenInfo)
+	require.Nil(t, err)
+	require.NotNil(t, policy)
+	require.Equal(t, []string{"user-b"}, policy.Subjects)
+}
+
+func TestAssignRole_InvalidResourceKey(t *testing.T) {
+	// No request is sent to Hydra
+	client := auth.NewClient("http://localhost:1", "client", "secret")
+	resources := fakeResourcesStorageClient{
+		"accounts/1?x": resource.Resource{Key: "accounts/1?x", SecurityKey: "rn:hydra:accounts", Permissions: []string{"get"}},
+	}
+
+	err := client.AssignRole("user-a", &secu.Role{
+		Id:     "reader",
+		Grants: []secu.Grant{secu.Grant{Resource: "accounts/1?x", Permissions: []string{"get"}}},
+	}, resources, nil)
+	require.NotNil(t, err)
+	require.Equal(t, "The resource key does not match the pattern \"^[a-zA-Z0-9\\-_.]+$\": \"accounts/1?x\"", err.Error())
+
+	err = client.ReconcileRole(&secu.Role{
+		Id:     "tenant:reader",
+		Grants: []secu.Grant{secu.Grant{Resource: "accounts", Permissions: []string{"get"}}},
+	}, roleResources, nil)
+	require.NotNil(t, err)
+	require.Equal(t, "The role id does not match the pattern \"^[a-zA-Z0-9\\-_.]+$\": \"tenant:reader\"", err.Error())
+}
@@ -1,86 +1,199 @@
 package components
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
-	"github.com/hashicorp/consul/api"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
 )
 
 const consulPath = "agilestack/components"
 
-var (
-	consulAddress = "consul.agilestacknet:8500"
-)
+// Change the address of Consul used by the package-level functions.
+//
+// Deprecated: use NewStore and SetDefaultStore instead.
+func SetConsulAddress(consulAddress string) {
+	config, err := store.NewConsulConfig(store.WithAddress(consulAddress))
+	if err != nil {
+		log.Println("Invalid Consul configuration:", err)
+		return
+	}
+	s, err := NewStore(config)
+	if err != nil {
+		log.Println("Unable to create the Consul client:", err)
+		return
+	}
+	SetDefaultStore(s)
+}
 
-// Change the address of Consul.
-// Default is "consul.agilestacknet:8500"
-func SetConsulAddress(consultAddress string) {
-	consulAddress = consultAddress
+// ComponentsStore stores the components of the application in a key/value store.
+type ComponentsStore struct {
+	kv store.KVStore
 }
 
-func StoreComponents(components *Components) error {
+// Returns a components store backed by the given key/value store.
+func NewComponentsStore(kv store.KVStore) *ComponentsStore {
+	return &ComponentsStore{kv: kv}
+}
+
+// Stores the components for the tenant of the context.
+func (s *ComponentsStore) StoreComponents(ctx context.Context, components *Components) error {
 	if err := Validate(components); err != nil {
 		return err
 	}
 
-	client, err := consulClient()
-	if err != nil {
+	if err := store.PutJSON(s.kv, tenant.Key(ctx, consulPath), components); err != nil {
+		log.Println("Error while storing the components:", err)
 		return err
 	}
+	return nil
+}
 
-	kv := client.KV()
+// Returns the operation storing the components for the tenant of the context,
+// to apply with other operations (see store.Apply).
+func StoreOperation(ctx context.Context, components *Components) (store.Operation, error) {
+	if err := Validate(components); err != nil {
+		return store.Operation{}, err
+	}
+	return store.SetJSON(tenant.Key(ctx, consulPath), components)
+}
 
-	bytes, err := json.Marshal(components)
+// Loads the components of the tenant of the context.
+func (s *ComponentsStore) GetComponents(ctx context.Context) (*Components, error) {
+	var components Components
+	found, err := store.GetJSON(s.kv, tenant.Key(ctx, consulPath), &components)
 	if err != nil {
-		log.Println("Error while marshalling the components:", err)
-		return err
+		log.Println("Error while loading components:", err)
+		return nil, err
 	}
+	if !found {
+		return nil, nil
+	}
+	return &components, nil
+}
+
+// Deletes the components of the tenant of the context.
+func (s *ComponentsStore) DeleteComponents(ctx context.Context) error {
+	return s.kv.Delete(tenant.Key(ctx, consulPath))
+}
+
+// A change of the components of the application.
+type ComponentsEvent struct {
+	Type store.EventType
+
+	// The components after the change, or the last known components when they
+	// are removed.
+	Components Components
+}
+
+// Watches the components of the tenant of the context until the context is
+// done, then closes the returned channel. The components existing when the
+// watch starts are notified as added.
+func (s *ComponentsStore) Watch(ctx context.Context) <-chan ComponentsEvent {
+	key := tenant.Key(ctx, consulPath)
+	pairs := store.Watch(ctx, s.kv, key)
+	events := make(chan ComponentsEvent)
+	go func() {
+		defer close(events)
+		for event := range pairs {
+			// The prefix also matches the keys starting with the path
+			if event.Pair.Key != key {
+				continue
+			}
+			components := Components{}
+			if err := json.Unmarshal(event.Pair.Value, &components); err != nil {
+				log.Printf("Error while unmarshalling the components '%s': %v", event.Pair.Key, err)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case events <- ComponentsEvent{Type: event.Type, Components: components}:
+			}
+		}
+	}()
+	return events
+}
+
+func StoreComponents(components *Components) error {
+	return StoreComponentsContext(context.Background(), components)
+}
 
-	pair := &api.KVPair{
-		Key:   consulPath,
-		Value: bytes,
+// Stores the components for the tenant of the context.
+func StoreComponentsContext(ctx context.Context, components *Components) error {
+	s, err := DefaultStore()
+	if err != nil {
+		return err
 	}
-	_, err = kv.Put(pair, &api.WriteOptions{})
-	return err
+	return s.StoreComponents(ctx, components)
 }
 
 func GetComponents() (*Components, error) {
-	client, err := consulClient()
+	return GetComponentsContext(context.Background())
+}
+
+// Loads the components of the tenant of the context.
+func GetComponentsContext(ctx context.Context) (*Components, error) {
+	s, err := DefaultStore()
 	if err != nil {
 		return nil, err
 	}
+	return s.GetComponents(ctx)
+}
 
-	pair, _, err := client.KV().Get(consulPath, nil)
+func DeleteComponents() error {
+	return DeleteComponentsContext(context.Background())
+}
+
+// Deletes the components of the tenant of the context.
+func DeleteComponentsContext(ctx context.Context) error {
+	s, err := DefaultStore()
 	if err != nil {
-		log.Println("Error while loading components from Consul:", err)
-		return nil, err
+		return err
 	}
+	return s.DeleteComponents(ctx)
+}
 
-	if pair == nil {
-		return nil, nil
+// Watches the components of the tenant of the context until the context is
+// done.
+func Watch(ctx context.Context) (<-chan ComponentsEvent, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
 	}
+	return s.Watch(ctx), nil
+}
 
-	var components Components
-	err = json.Unmarshal(pair.Value, &components)
+// Returns a components store backed by the Consul matching the configuration.
+func NewStore(config store.ConsulConfig) (*ComponentsStore, error) {
+	kv, err := store.NewConsulStoreFromConfig(config)
 	if err != nil {
 		return nil, err
 	}
-	return &components, nil
+	return NewComponentsStore(kv), nil
 }
 
-func DeleteComponents() error {
-	client, err := consulClient()
+// The key/value store of the package-level functions.
+var defaultKV store.Override
+
+// Returns the store used by the package-level functions, backed by the default
+// key/value store unless another one is set (see store.Override).
+func DefaultStore() (*ComponentsStore, error) {
+	kv, err := defaultKV.Store()
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	_, err = client.KV().Delete(consulPath, nil)
-	return err
+	return NewComponentsStore(kv), nil
 }
 
-func consulClient() (*api.Client, error) {
-	config := api.DefaultConfig()
-	config.Address = consulAddress
-	return api.NewClient(config)
+// Changes the store used by the package-level functions. A nil store restores
+// the default one.
+func SetDefaultStore(s *ComponentsStore) {
+	if s == nil {
+		defaultKV.Set(nil)
+		return
+	}
+	defaultKV.Set(s.kv)
 }
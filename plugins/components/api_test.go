@@ -1,10 +1,13 @@
 package components_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
 	"github.com/eogile/agilestack-utils/plugins/components"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
 	"github.com/stretchr/testify/require"
 )
 
@@ -82,4 +85,59 @@ func TestDeleteComponents(t *testing.T) {
 	result, err := components.GetComponents()
 	require.Nil(t, result)
 	require.Nil(t, err)
-}
\ No newline at end of file
+}
+func TestGetComponents_Tenants(t *testing.T) {
+	deleteAll(t)
+	acme := tenant.NewContext(context.Background(), "acme")
+	require.Nil(t, components.StoreComponentsContext(acme, &components1))
+
+	result, err := components.GetComponents()
+	require.Nil(t, err)
+	require.Nil(t, result)
+
+	result, err = components.GetComponentsContext(acme)
+	require.Nil(t, err)
+	require.NotNil(t, result)
+	validateComponents(t, components1, *result)
+
+	pair, _, err := consulClient(t).KV().Get("agilestack/tenants/acme/components", nil)
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+}
+
+func TestComponentsStore_MemoryStore(t *testing.T) {
+	componentsStore := components.NewComponentsStore(store.NewMemoryStore())
+	ctx := context.Background()
+
+	result, err := componentsStore.GetComponents(ctx)
+	require.Nil(t, err)
+	require.Nil(t, result)
+
+	require.Nil(t, componentsStore.StoreComponents(ctx, &components1))
+	result, err = componentsStore.GetComponents(ctx)
+	require.Nil(t, err)
+	require.NotNil(t, result)
+	validateComponents(t, components1, *result)
+
+	require.Nil(t, componentsStore.DeleteComponents(ctx))
+	result, err = componentsStore.GetComponents(ctx)
+	require.Nil(t, err)
+	require.Nil(t, result)
+}
+
+func TestComponentsStore_Watch(t *testing.T) {
+	componentsStore := components.NewComponentsStore(store.NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := componentsStore.Watch(ctx)
+	require.Nil(t, componentsStore.StoreComponents(ctx, &components1))
+	event := <-events
+	require.Equal(t, store.Added, event.Type)
+	validateComponents(t, components1, event.Components)
+
+	require.Nil(t, componentsStore.DeleteComponents(ctx))
+	event = <-events
+	require.Equal(t, store.Removed, event.Type)
+	validateComponents(t, components1, event.Components)
+}
@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"github.com/eogile/agilestack-utils/plugins/components"
+	"github.com/eogile/agilestack-utils/plugins/store"
 	"github.com/eogile/agilestack-utils/plugins/test"
+	TestUtils "github.com/eogile/agilestack-utils/test"
 	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/require"
 )
@@ -16,15 +18,24 @@ var components1 = components.Components{
 	MainComponent: "Main",
 }
 
+// The address of the Consul container
+var consulAddress string
+
 func TestMain(m *testing.M) {
 	log.Println("Launching tests agilestack/utils/plugins/menu")
-	components.SetConsulAddress("127.0.0.1:8501")
-	test.DoTestMain(m)
+	test.DoTestMain(m, func(consul *TestUtils.ConsulInfo) {
+		consulAddress = consul.Address
+		s, err := components.NewStore(store.ConsulConfig{Address: consul.Address})
+		if err != nil {
+			log.Fatalln("Unable to create the store:", err)
+		}
+		components.SetDefaultStore(s)
+	})
 }
 
 func consulClient(t *testing.T) *api.Client {
 	config := api.DefaultConfig()
-	config.Address = "localhost:8501"
+	config.Address = consulAddress
 	client, err := api.NewClient(config)
 	require.Nil(t, err)
 	return client
@@ -0,0 +1,228 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/slices"
+	"github.com/eogile/agilestack-utils/tenant"
+)
+
+const (
+	groupsPrefix = "agilestack/security/groups/"
+
+	// Number of attempts to update a group modified concurrently
+	maxUpdateAttempts = 5
+)
+
+// GroupsStorageClient is an interface for the operations on the user groups
+type GroupsStorageClient interface {
+	// StoreGroup insert or modify a group
+	StoreGroup(group secu.Group) error
+
+	// GetGroup retrieve a group by its id
+	GetGroup(id string) (*secu.Group, error)
+
+	// ListGroups retrieve all the groups
+	ListGroups() ([]secu.Group, error)
+
+	// DeleteGroup delete a group given its id
+	DeleteGroup(id string) error
+
+	// AddMember adds a user to a group
+	AddMember(groupId, userId string) error
+
+	// RemoveMember removes a user from a group
+	RemoveMember(groupId, userId string) error
+
+	// ListUserGroups retrieve the groups the given user is a member of
+	ListUserGroups(userId string) ([]secu.Group, error)
+}
+
+// GroupsStore stores the user groups of a tenant in a key/value store.
+type GroupsStore struct {
+	kv       store.KVStore
+	tenantId string
+}
+
+// NewGroupsStore returns a groups store backed by the given key/value store, scoped to the tenant of the context.
+// The membership changes are only safe against concurrent modifications when the store is a store.CASStore.
+func NewGroupsStore(ctx context.Context, kv store.KVStore) *GroupsStore {
+	return &GroupsStore{kv, tenant.FromContext(ctx)}
+}
+
+// NewGroupsStorageClient returns a fresh GroupsStore backed by the default store (see DefaultStore)
+func NewGroupsStorageClient() GroupsStorageClient {
+	return NewTenantGroupsStorageClient(context.Background())
+}
+
+// NewTenantGroupsStorageClient returns a fresh GroupsStore backed by the default store, scoped to the tenant of the context.
+func NewTenantGroupsStorageClient(ctx context.Context) GroupsStorageClient {
+	kv, err := DefaultStore()
+	if err != nil {
+		log.Println("Unable to create the store", err)
+		return nil
+	}
+	return NewGroupsStore(ctx, kv)
+}
+
+// NewGroupsStorageClientWithConfig returns a fresh GroupsStore using the given Consul configuration,
+// scoped to the tenant of the context
+func NewGroupsStorageClientWithConfig(ctx context.Context, config store.ConsulConfig) (GroupsStorageClient, error) {
+	kv, err := store.NewConsulStoreFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewGroupsStore(ctx, kv), nil
+}
+
+// StoreGroup store a group in the store
+func (s *GroupsStore) StoreGroup(group secu.Group) error {
+	if err := Validate(&group); err != nil {
+		return err
+	}
+	return store.PutJSON(s.kv, s.key(group.Id), group)
+}
+
+// GetGroup retrieve a group in the store given its id
+func (s *GroupsStore) GetGroup(id string) (*secu.Group, error) {
+	group, _, err := s.getGroup(id)
+	return group, err
+}
+
+// ListGroups list all the groups
+func (s *GroupsStore) ListGroups() ([]secu.Group, error) {
+	pairs, err := s.kv.List(s.key(""))
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]secu.Group, len(pairs))
+	for i, pair := range pairs {
+		if err := json.Unmarshal(pair.Value, &groups[i]); err != nil {
+			log.Printf("Error while unmarshalling group '%s': %v", pair.Key, err)
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// DeleteGroup delete a group in the store given its id
+func (s *GroupsStore) DeleteGroup(id string) error {
+	return s.kv.Delete(s.key(id))
+}
+
+// AddMember adds a user to a group. Nothing is done if the user is
+// already a member of the group.
+func (s *GroupsStore) AddMember(groupId, userId string) error {
+	return s.updateGroup(groupId, func(group *secu.Group) bool {
+		if slices.StringInSlice(userId, group.Members) {
+			return false
+		}
+		group.Members = append(group.Members, userId)
+		return true
+	})
+}
+
+// RemoveMember removes a user from a group. Nothing is done if the user is
+// not a member of the group.
+func (s *GroupsStore) RemoveMember(groupId, userId string) error {
+	return s.updateGroup(groupId, func(group *secu.Group) bool {
+		members := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			if member != userId {
+				members = append(members, member)
+			}
+		}
+		if len(members) == len(group.Members) {
+			return false
+		}
+		group.Members = members
+		return true
+	})
+}
+
+// ListUserGroups list the groups the given user is a member of
+func (s *GroupsStore) ListUserGroups(userId string) ([]secu.Group, error) {
+	groups, err := s.ListGroups()
+	if err != nil {
+		return nil, err
+	}
+	userGroups := make([]secu.Group, 0)
+	for _, group := range groups {
+		if slices.StringInSlice(userId, group.Members) {
+			userGroups = append(userGroups, group)
+		}
+	}
+	return userGroups, nil
+}
+
+// Loads the group and its modify index.
+func (s *GroupsStore) getGroup(id string) (*secu.Group, uint64, error) {
+	pair, err := s.kv.Get(s.key(id))
+	if err != nil {
+		return nil, 0, err
+	}
+	if pair == nil {
+		return nil, 0, nil
+	}
+	group := &secu.Group{}
+	err = json.Unmarshal(pair.Value, group)
+	return group, pair.ModifyIndex, err
+}
+
+// Applies the given update to the group and stores it with a check-and-set
+// operation, so that concurrent membership changes are not lost.
+// The update function returns false if the group does not need to be stored.
+func (s *GroupsStore) updateGroup(id string, update func(*secu.Group) bool) error {
+	for attempt := 0; attempt < maxUpdateAttempts; attempt++ {
+		group, modifyIndex, err := s.getGroup(id)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			return fmt.Errorf("Unknown group: \"%s\"", id)
+		}
+		if !update(group) {
+			return nil
+		}
+
+		groupBytes, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		stored, err := store.CheckAndSet(s.kv, s.key(id), groupBytes, modifyIndex)
+		if err != nil {
+			return err
+		}
+		if stored {
+			return nil
+		}
+		log.Printf("Group '%s' was modified concurrently, retrying", id)
+	}
+	return fmt.Errorf("Unable to update the group \"%s\": too many concurrent modifications", id)
+}
+
+// Returns the key of the given group, scoped to the tenant of the store.
+func (s *GroupsStore) key(id string) string {
+	return tenant.TenantKey(s.tenantId, groupsPrefix+id)
+}
+
+// The key/value store of the clients created by NewGroupsStorageClient and
+// NewTenantGroupsStorageClient.
+var defaultKV store.Override
+
+// Returns the key/value store of the clients created by NewGroupsStorageClient and
+// NewTenantGroupsStorageClient (see store.Override).
+func DefaultStore() (store.KVStore, error) {
+	return defaultKV.Store()
+}
+
+// Changes the key/value store of the clients created by NewGroupsStorageClient and
+// NewTenantGroupsStorageClient. A nil store restores the default one.
+func SetDefaultStore(kv store.KVStore) {
+	defaultKV.Set(kv)
+}
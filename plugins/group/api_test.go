@@ -0,0 +1,168 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/tenant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreGroup(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreGroup(group1))
+
+	pair, _, err := consulTestClient.KV().Get("agilestack/security/groups/developers", nil)
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+
+	var result secu.Group
+	require.Nil(t, json.Unmarshal(pair.Value, &result))
+	require.Equal(t, group1, result)
+}
+
+func TestStoreGroup_Invalid(t *testing.T) {
+	deleteAll(t)
+	err := storageClient().StoreGroup(secu.Group{Id: "team"})
+	require.NotNil(t, err)
+	require.Equal(t, "The members slice must not be nil", err.Error())
+
+	pair, _, err := consulTestClient.KV().Get("agilestack/security/groups/team", nil)
+	require.Nil(t, err)
+	require.Nil(t, pair)
+}
+
+func TestGetGroup_NotFound(t *testing.T) {
+	deleteAll(t)
+	result, err := storageClient().GetGroup("developers")
+	require.Nil(t, err)
+	require.Nil(t, result)
+}
+
+func TestListGroups(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreGroup(group1))
+	require.Nil(t, storageClient().StoreGroup(group2))
+
+	groups, err := storageClient().ListGroups()
+	require.Nil(t, err)
+	require.Equal(t, []secu.Group{group1, group2}, groups)
+}
+
+func TestDeleteGroup(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreGroup(group1))
+	require.Nil(t, storageClient().StoreGroup(group2))
+	require.Nil(t, storageClient().DeleteGroup(group1.Id))
+
+	groups, err := storageClient().ListGroups()
+	require.Nil(t, err)
+	require.Equal(t, []secu.Group{group2}, groups)
+}
+
+func TestAddMember(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreGroup(group2))
+	require.Nil(t, storageClient().AddMember(group2.Id, "user-c"))
+	// Adding a member twice has no effect
+	require.Nil(t, storageClient().AddMember(group2.Id, "user-c"))
+
+	result, err := storageClient().GetGroup(group2.Id)
+	require.Nil(t, err)
+	require.Equal(t, []string{"user-b", "user-c"}, result.Members)
+}
+
+func TestAddMember_Concurrent(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreGroup(secu.Group{Id: "team", Members: []string{}}))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for _, userId := range []string{"user-a", "user-b", "user-c"} {
+		wg.Add(1)
+		go func(userId string) {
+			defer wg.Done()
+			errs <- storageClient().AddMember("team", userId)
+		}(userId)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Nil(t, err)
+	}
+
+	result, err := storageClient().GetGroup("team")
+	require.Nil(t, err)
+	require.Equal(t, 3, len(result.Members))
+}
+
+func TestAddMember_UnknownGroup(t *testing.T) {
+	deleteAll(t)
+	err := storageClient().AddMember("team", "user-a")
+	require.NotNil(t, err)
+	require.Equal(t, "Unknown group: \"team\"", err.Error())
+}
+
+func TestRemoveMember(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreGroup(group1))
+	require.Nil(t, storageClient().RemoveMember(group1.Id, "user-a"))
+	require.Nil(t, storageClient().RemoveMember(group1.Id, "user-z"))
+
+	result, err := storageClient().GetGroup(group1.Id)
+	require.Nil(t, err)
+	require.Equal(t, []string{"user-b"}, result.Members)
+}
+
+func TestListUserGroups(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreGroup(group1))
+	require.Nil(t, storageClient().StoreGroup(group2))
+
+	groups, err := storageClient().ListUserGroups("user-a")
+	require.Nil(t, err)
+	require.Equal(t, []secu.Group{group1}, groups)
+
+	groups, err = storageClient().ListUserGroups("user-b")
+	require.Nil(t, err)
+	require.Equal(t, []secu.Group{group1, group2}, groups)
+
+	groups, err = storageClient().ListUserGroups("user-z")
+	require.Nil(t, err)
+	require.Equal(t, 0, len(groups))
+}
+
+func TestGroupsStore_MemoryStore(t *testing.T) {
+	kv := store.NewMemoryStore()
+	SetDefaultStore(kv)
+	defer SetDefaultStore(nil)
+
+	client := NewTenantGroupsStorageClient(tenant.NewContext(context.Background(), "acme"))
+	require.Nil(t, client.StoreGroup(secu.Group{Id: "team", Members: []string{}}))
+
+	var wg sync.WaitGroup
+	for _, userId := range []string{"user-a", "user-b", "user-c"} {
+		wg.Add(1)
+		go func(userId string) {
+			defer wg.Done()
+			require.Nil(t, client.AddMember("team", userId))
+		}(userId)
+	}
+	wg.Wait()
+
+	pair, err := kv.Get("agilestack/tenants/acme/security/groups/team")
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+
+	groups, err := NewGroupsStorageClient().ListGroups()
+	require.Nil(t, err)
+	require.Equal(t, 0, len(groups))
+
+	result, err := client.GetGroup("team")
+	require.Nil(t, err)
+	require.Equal(t, 3, len(result.Members))
+}
@@ -0,0 +1,46 @@
+package group
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/test"
+	"github.com/eogile/agilestack-utils/secu"
+	TestUtils "github.com/eogile/agilestack-utils/test"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+var consulTestClient *api.Client
+
+var (
+	group1 = secu.Group{
+		Id:          "developers",
+		Description: "The development team",
+		Members:     []string{"user-a", "user-b"},
+	}
+
+	group2 = secu.Group{
+		Id:          "support",
+		Description: "The support team",
+		Members:     []string{"user-b"},
+	}
+)
+
+func TestMain(m *testing.M) {
+	log.Println("Launching tests agilestack/utils/plugins/group")
+	test.DoTestMain(m, func(consul *TestUtils.ConsulInfo) {
+		consulTestClient, _ = consul.NewClient()
+	})
+}
+
+func storageClient() *GroupsStore {
+	return NewGroupsStore(context.Background(), store.NewConsulStore(consulTestClient))
+}
+
+func deleteAll(t *testing.T) {
+	_, err := consulTestClient.KV().DeleteTree("agilestack/", &api.WriteOptions{})
+	require.Nil(t, err)
+}
@@ -0,0 +1,32 @@
+package group
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/eogile/agilestack-utils/secu"
+)
+
+const groupIdPattern = "^[a-zA-Z0-9\\-_]+$"
+
+func Validate(group *secu.Group) error {
+	if group == nil {
+		return errors.New("Group must not be nil")
+	}
+
+	if matched, err := regexp.MatchString(groupIdPattern, group.Id); !matched || err != nil {
+		return fmt.Errorf("The group id does not match the pattern \"%s\": \"%s\"", groupIdPattern, group.Id)
+	}
+
+	if group.Members == nil {
+		return errors.New("The members slice must not be nil")
+	}
+
+	for _, member := range group.Members {
+		if matched, err := regexp.MatchString("^\\s*$", member); matched || err != nil {
+			return errors.New("Member id must not be blank")
+		}
+	}
+	return nil
+}
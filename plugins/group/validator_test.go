@@ -0,0 +1,39 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_Nil(t *testing.T) {
+	err := Validate(nil)
+	require.NotNil(t, err)
+	require.Equal(t, "Group must not be nil", err.Error())
+}
+
+func TestValidate_InvalidId(t *testing.T) {
+	for _, id := range []string{"", "	", "équipe", "my team", "group:1"} {
+		err := Validate(&secu.Group{Id: id, Members: []string{}})
+		require.NotNil(t, err)
+		require.Equal(t, "The group id does not match the pattern \"^[a-zA-Z0-9\\-_]+$\": \""+id+"\"", err.Error())
+	}
+}
+
+func TestValidate_MembersNil(t *testing.T) {
+	err := Validate(&secu.Group{Id: "team"})
+	require.NotNil(t, err)
+	require.Equal(t, "The members slice must not be nil", err.Error())
+}
+
+func TestValidate_BlankMember(t *testing.T) {
+	err := Validate(&secu.Group{Id: "team", Members: []string{"user-a", " "}})
+	require.NotNil(t, err)
+	require.Equal(t, "Member id must not be blank", err.Error())
+}
+
+func TestValidate(t *testing.T) {
+	require.Nil(t, Validate(&group1))
+	require.Nil(t, Validate(&group2))
+}
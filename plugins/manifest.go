@@ -0,0 +1,235 @@
+package plugins
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/eogile/agilestack-utils/plugins/components"
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/translations"
+)
+
+// The usual file name of the manifest of a plugin.
+const ManifestFileName = "agilestack-plugin.json"
+
+/*
+The manifest of a plugin, describing its registration in JSON (see
+LoadManifest):
+
+	{
+	  "name": "accounts",
+	  "version": "1.2.0",
+	  "platformVersion": "1.4.0",
+	  "sources": "dist",
+	  "dependencies": [{"pluginName": "users", "version": "2.0.0"}],
+	  "reducers": ["accounts"],
+	  "routes": [{"href": "accounts", "componentName": "AccountsList", "type": "content-route", "routes": []}],
+	  "menu": [{"name": "Accounts", "route": "/accounts", "entries": []}],
+	  "components": {"app": "App", "main": "Main"},
+	  "resources": [{"key": "accounts", "security_key": "rn:hydra:accounts", "permissions": ["create"]}],
+	  "translations": {"fr": {"app": {"title": "Comptes"}}}
+	}
+*/
+type Manifest struct {
+	// The name of the plugin.
+	Name string `json:"name"`
+
+	// The version of the plugin and the minimal version of the platform it
+	// requires.
+	Version         string `json:"version"`
+	PlatformVersion string `json:"platformVersion"`
+
+	// The path of the sources, relative to the manifest file.
+	Sources string `json:"sources"`
+
+	Dependencies []registration.Dependency            `json:"dependencies"`
+	Reducers     []string                             `json:"reducers"`
+	Routes       []registration.Route                 `json:"routes"`
+	Menu         []menu.MenuEntry                     `json:"menu"`
+	Components   *ManifestComponents                  `json:"components"`
+	Resources    []resource.Resource                  `json:"resources"`
+	Translations map[string]translations.Translations `json:"translations"`
+
+	// The path of the manifest file.
+	path string
+}
+
+// The main components of the application, when the plugin provides them.
+type ManifestComponents struct {
+	App  string `json:"app"`
+	Main string `json:"main"`
+}
+
+// A problem of a manifest file, at a line.
+type ManifestProblem struct {
+	Line    int
+	Message string
+}
+
+// The error of an invalid manifest file, listing its problems by line.
+type ManifestError struct {
+	Path     string
+	Problems []ManifestProblem
+}
+
+func (e *ManifestError) Error() string {
+	problems := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		problems[i] = fmt.Sprintf("%s:%d: %s", e.Path, problem.Line, problem.Message)
+	}
+	return strings.Join(problems, ", ")
+}
+
+/*
+Loads the manifest file of a plugin. The manifest is checked against its schema
+(the known fields, their types and the required ones), then its parts are
+validated like by Register. When the manifest is invalid, the error is a
+*ManifestError giving the line of each problem.
+*/
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println("Error while reading the plugin manifest:", err)
+		return nil, err
+	}
+
+	var document interface{}
+	if err := json.Unmarshal(data, &document); err != nil {
+		line := 1
+		if syntaxError, ok := err.(*json.SyntaxError); ok {
+			line = lineAt(data, syntaxError.Offset)
+		}
+		return nil, &ManifestError{Path: path, Problems: []ManifestProblem{{line, err.Error()}}}
+	}
+
+	root := newJSONScanner(data).value()
+	problems := checkSchema(root, manifestSchema)
+	var m *Manifest
+	if len(problems) == 0 {
+		m = &Manifest{path: path}
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, err
+		}
+		problems = m.validate(root)
+	}
+	if len(problems) > 0 {
+		sort.Stable(byLine(problems))
+		return nil, &ManifestError{Path: path, Problems: problems}
+	}
+	return m, nil
+}
+
+// Validates the parts of the manifest, reporting the problems at the line of
+// the part.
+func (m *Manifest) validate(root *jsonNode) []ManifestProblem {
+	problems := []ManifestProblem{}
+	report := func(err error, path ...string) {
+		if err != nil {
+			problems = append(problems, ManifestProblem{root.line(path...), err.Error()})
+		}
+	}
+
+	config := m.Registration()
+	if strings.TrimSpace(m.Name) == "" || strings.Contains(m.Name, "/") {
+		report(fmt.Errorf("Invalid plugin name: \"%s\"", m.Name), "name")
+	}
+	report(registration.Validate(config.Config), "routes")
+	report(menu.ValidateMenu(config.Menu), "menu")
+	if config.Components != nil {
+		report(components.Validate(config.Components), "components")
+	}
+	for i, res := range m.Resources {
+		if res.Key == "" {
+			report(errors.New("The resource key must not be empty"), "resources", fmt.Sprint(i))
+		}
+	}
+	for _, lang := range sortedLanguages(m.Translations) {
+		report(translations.Validate(m.Name, lang, m.Translations[lang]), "translations", lang)
+	}
+	return problems
+}
+
+// Returns the registration described by the manifest.
+func (m *Manifest) Registration() FullRegistration {
+	sources := m.Sources
+	if !filepath.IsAbs(sources) {
+		sources = filepath.Join(filepath.Dir(m.path), sources)
+	}
+	config := FullRegistration{
+		PluginName:  m.Name,
+		SourcesPath: sources,
+		Menu:        &menu.Menu{PluginName: m.Name, Entries: orEmptyEntries(m.Menu)},
+		Config: &registration.PluginConfiguration{
+			PluginName:      m.Name,
+			Reducers:        orEmptyStrings(m.Reducers),
+			Routes:          orEmptyRoutes(m.Routes),
+			Version:         m.Version,
+			PlatformVersion: m.PlatformVersion,
+			Dependencies:    m.Dependencies,
+		},
+		Resources:    m.Resources,
+		Translations: m.Translations,
+	}
+	if m.Components != nil {
+		config.Components = &components.Components{
+			PluginName:    m.Name,
+			AppComponent:  m.Components.App,
+			MainComponent: m.Components.Main,
+		}
+	}
+	return config
+}
+
+func orEmptyEntries(entries []menu.MenuEntry) []menu.MenuEntry {
+	if entries == nil {
+		return []menu.MenuEntry{}
+	}
+	return entries
+}
+
+func orEmptyStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func orEmptyRoutes(routes []registration.Route) []registration.Route {
+	if routes == nil {
+		return []registration.Route{}
+	}
+	return routes
+}
+
+// Loads the manifest file of a plugin and registers the plugin (see
+// LoadManifest and Register).
+func RegisterFromManifest(path string) error {
+	m, err := LoadManifest(path)
+	if err != nil {
+		return err
+	}
+	return Register(m.Registration())
+}
+
+type byLine []ManifestProblem
+
+func (p byLine) Len() int           { return len(p) }
+func (p byLine) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byLine) Less(i, j int) bool { return p[i].Line < p[j].Line }
+
+// Returns the line of the offset in the data, starting at 1.
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
@@ -0,0 +1,280 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// A value of a JSON document, with the line it starts at.
+type jsonNode struct {
+	// "object", "array", "string", "number", "boolean" or "null"
+	kind      string
+	startLine int
+
+	// The fields of an object, in the order of the document
+	fields []jsonField
+
+	// The items of an array
+	items []*jsonNode
+}
+
+type jsonField struct {
+	name  string
+	line  int
+	value *jsonNode
+}
+
+/*
+Returns the line of the value at the path, made of field names and array
+indexes, such as ("menu", "0", "route"). When the path is not found, returns the
+line of the deepest value found.
+*/
+func (n *jsonNode) line(path ...string) int {
+	line := n.startLine
+	node := n
+	for _, step := range path {
+		var next *jsonNode
+		switch node.kind {
+		case "object":
+			for _, field := range node.fields {
+				if field.name == step {
+					line = field.line
+					next = field.value
+				}
+			}
+		case "array":
+			if i, err := strconv.Atoi(step); err == nil && i >= 0 && i < len(node.items) {
+				next = node.items[i]
+				line = next.startLine
+			}
+		}
+		if next == nil {
+			break
+		}
+		node = next
+	}
+	return line
+}
+
+// Reads the values of a valid JSON document, keeping track of the lines.
+type jsonScanner struct {
+	data []byte
+	pos  int
+	line int
+}
+
+func newJSONScanner(data []byte) *jsonScanner {
+	return &jsonScanner{data: data, line: 1}
+}
+
+func (s *jsonScanner) skipSpaces() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\n':
+			s.line++
+		case ' ', '\t', '\r':
+		default:
+			return
+		}
+		s.pos++
+	}
+}
+
+// Reads the next value. The document must be valid.
+func (s *jsonScanner) value() *jsonNode {
+	s.skipSpaces()
+	node := &jsonNode{startLine: s.line}
+	switch s.data[s.pos] {
+	case '{':
+		node.kind = "object"
+		s.pos++
+		for s.skipSpaces(); s.data[s.pos] != '}'; s.skipSpaces() {
+			line := s.line
+			name := s.string()
+			s.skipSpaces()
+			s.pos++ // ':'
+			node.fields = append(node.fields, jsonField{name: name, line: line, value: s.value()})
+			s.skipSpaces()
+			if s.data[s.pos] == ',' {
+				s.pos++
+			}
+		}
+		s.pos++
+	case '[':
+		node.kind = "array"
+		s.pos++
+		for s.skipSpaces(); s.data[s.pos] != ']'; s.skipSpaces() {
+			node.items = append(node.items, s.value())
+			s.skipSpaces()
+			if s.data[s.pos] == ',' {
+				s.pos++
+			}
+		}
+		s.pos++
+	case '"':
+		node.kind = "string"
+		s.string()
+	case 't', 'f':
+		node.kind = "boolean"
+		s.literal()
+	case 'n':
+		node.kind = "null"
+		s.literal()
+	default:
+		node.kind = "number"
+		s.literal()
+	}
+	return node
+}
+
+func (s *jsonScanner) string() string {
+	start := s.pos
+	for s.pos++; s.data[s.pos] != '"'; s.pos++ {
+		if s.data[s.pos] == '\\' {
+			s.pos++
+		}
+	}
+	s.pos++
+	var value string
+	json.Unmarshal(s.data[start:s.pos], &value)
+	return value
+}
+
+// Reads a number, true, false or null.
+func (s *jsonScanner) literal() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ',', '}', ']', ' ', '\t', '\r', '\n':
+			return
+		}
+		s.pos++
+	}
+}
+
+// The expected shape of a JSON value.
+type schema struct {
+	kind string
+
+	// The known fields of an object, and the required ones
+	fields   map[string]*schema
+	required []string
+
+	// The values of an object whose fields are free, such as a map
+	values *schema
+
+	// The items of an array
+	items *schema
+}
+
+// An object without constraints on its fields.
+var anyObject = &schema{kind: "object"}
+
+func stringsSchema() *schema {
+	return &schema{kind: "array", items: &schema{kind: "string"}}
+}
+
+// The schema of the manifest of a plugin.
+var manifestSchema = func() *schema {
+	subRoute := &schema{kind: "object", fields: map[string]*schema{
+		"href":          {kind: "string"},
+		"componentName": {kind: "string"},
+	}}
+	subRoute.fields["routes"] = &schema{kind: "array", items: subRoute}
+
+	menuEntry := &schema{kind: "object", fields: map[string]*schema{
+		"name":           {kind: "string"},
+		"route":          {kind: "string"},
+		"weight":         {kind: "number"},
+		"translationKey": {kind: "string"},
+		"resource":       {kind: "string"},
+		"permission":     {kind: "string"},
+		"parentRoute":    {kind: "string"},
+	}}
+	menuEntry.fields["entries"] = &schema{kind: "array", items: menuEntry}
+
+	return &schema{kind: "object", required: []string{"name", "sources"}, fields: map[string]*schema{
+		"name":            {kind: "string"},
+		"version":         {kind: "string"},
+		"platformVersion": {kind: "string"},
+		"sources":         {kind: "string"},
+		"dependencies": {kind: "array", items: &schema{kind: "object", required: []string{"pluginName"}, fields: map[string]*schema{
+			"pluginName": {kind: "string"},
+			"version":    {kind: "string"},
+		}}},
+		"reducers": stringsSchema(),
+		"routes": {kind: "array", items: &schema{kind: "object", fields: map[string]*schema{
+			"href":          {kind: "string"},
+			"componentName": {kind: "string"},
+			"routes":        {kind: "array", items: subRoute},
+			"type":          {kind: "string"},
+			"isIndex":       {kind: "boolean"},
+		}}},
+		"menu": {kind: "array", items: menuEntry},
+		"components": {kind: "object", required: []string{"app", "main"}, fields: map[string]*schema{
+			"app":  {kind: "string"},
+			"main": {kind: "string"},
+		}},
+		"resources": {kind: "array", items: &schema{kind: "object", required: []string{"key"}, fields: map[string]*schema{
+			"key":          {kind: "string"},
+			"security_key": {kind: "string"},
+			"permissions":  stringsSchema(),
+		}}},
+		"translations": {kind: "object", values: anyObject},
+	}}
+}()
+
+// Returns the problems of the document against the schema.
+func checkSchema(root *jsonNode, expected *schema) []ManifestProblem {
+	problems := []ManifestProblem{}
+	if root.kind != "object" {
+		return append(problems, ManifestProblem{root.startLine, fmt.Sprintf("The manifest must be of type object, not %s", root.kind)})
+	}
+	checkValue(root, expected, "", &problems)
+	return problems
+}
+
+// Checks the value at the path against the schema. The null values are
+// accepted, except for the required fields.
+func checkValue(node *jsonNode, expected *schema, path string, problems *[]ManifestProblem) {
+	if node.kind == "null" {
+		return
+	}
+	if node.kind != expected.kind {
+		*problems = append(*problems, ManifestProblem{node.startLine,
+			fmt.Sprintf("The field \"%s\" must be of type %s, not %s", path, expected.kind, node.kind)})
+		return
+	}
+
+	switch node.kind {
+	case "array":
+		for i, item := range node.items {
+			checkValue(item, expected.items, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "object":
+		present := map[string]bool{}
+		for _, field := range node.fields {
+			fieldPath := field.name
+			if path != "" {
+				fieldPath = path + "." + field.name
+			}
+			present[field.name] = field.value.kind != "null"
+			if expected.values != nil {
+				checkValue(field.value, expected.values, fieldPath, problems)
+			} else if fieldSchema, found := expected.fields[field.name]; found {
+				checkValue(field.value, fieldSchema, fieldPath, problems)
+			} else if expected.fields != nil {
+				*problems = append(*problems, ManifestProblem{field.line, fmt.Sprintf("Unknown field \"%s\"", fieldPath)})
+			}
+		}
+		for _, name := range expected.required {
+			if !present[name] {
+				fieldPath := name
+				if path != "" {
+					fieldPath = path + "." + name
+				}
+				*problems = append(*problems, ManifestProblem{node.startLine, fmt.Sprintf("Missing required field \"%s\"", fieldPath)})
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	"github.com/stretchr/testify/require"
+)
+
+const validManifest = `{
+  "name": "accounts",
+  "sources": "sources",
+  "reducers": ["accounts"],
+  "routes": [
+    {"href": "accounts", "componentName": "AccountsList", "type": "content-route", "routes": []}
+  ],
+  "menu": [
+    {"name": "Accounts", "route": "/accounts", "weight": 10, "entries": []}
+  ],
+  "components": {"app": "App", "main": "Main"},
+  "resources": [
+    {"key": "accounts", "security_key": "rn:hydra:accounts", "permissions": ["create"]}
+  ],
+  "translations": {
+    "fr": {"app": {"title": "Comptes"}}
+  }
+}
+`
+
+// Writes the manifest next to the sources, and returns its path.
+func writeManifest(t *testing.T, sources, content string) string {
+	path := filepath.Join(filepath.Dir(sources), ManifestFileName)
+	require.Nil(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadManifest(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+
+	manifest, err := LoadManifest(writeManifest(t, sources, validManifest))
+	require.Nil(t, err)
+	config := manifest.Registration()
+	require.Equal(t, "accounts", config.PluginName)
+	require.Equal(t, sources, config.SourcesPath)
+	require.Equal(t, "accounts", config.Menu.PluginName)
+	require.Equal(t, 10, config.Menu.Entries[0].Weight)
+	require.Equal(t, "Main", config.Components.MainComponent)
+	require.Equal(t, []string{"accounts"}, config.Config.Reducers)
+	require.Equal(t, "AccountsList", config.Config.Routes[0].ComponentName)
+	require.Equal(t, []resource.Resource{
+		{Key: "accounts", SecurityKey: "rn:hydra:accounts", Permissions: []string{"create"}},
+	}, config.Resources)
+	require.Equal(t, translations.Translations{"app": map[string]interface{}{"title": "Comptes"}}, config.Translations["fr"])
+}
+
+func TestLoadManifest_Minimal(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+
+	manifest, err := LoadManifest(writeManifest(t, sources, `{"name": "accounts", "sources": "/plugins/accounts"}`))
+	require.Nil(t, err)
+	config := manifest.Registration()
+	require.Equal(t, "/plugins/accounts", config.SourcesPath)
+	require.Empty(t, config.Menu.Entries)
+	require.Equal(t, &registration.PluginConfiguration{
+		PluginName: "accounts",
+		Reducers:   []string{},
+		Routes:     []registration.Route{},
+	}, config.Config)
+	require.Nil(t, config.Components)
+}
+
+func TestLoadManifest_SchemaErrors(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+
+	path := writeManifest(t, sources, `{
+  "name": "accounts",
+  "reducers": ["accounts", 3],
+  "menu": [
+    {"name": "Accounts", "label": "Accounts", "entries": []}
+  ],
+  "components": {
+    "app": "App"
+  },
+  "translations": {"fr": "Comptes"}
+}`)
+	_, err := LoadManifest(path)
+	require.NotNil(t, err)
+	manifestError, ok := err.(*ManifestError)
+	require.True(t, ok)
+	require.Equal(t, []ManifestProblem{
+		{1, "Missing required field \"sources\""},
+		{3, "The field \"reducers[1]\" must be of type string, not number"},
+		{5, "Unknown field \"menu[0].label\""},
+		{7, "Missing required field \"components.main\""},
+		{10, "The field \"translations.fr\" must be of type object, not string"},
+	}, manifestError.Problems)
+	require.True(t, strings.HasPrefix(err.Error(), path+":1: Missing required field \"sources\", "+path+":3: The field"))
+}
+
+func TestLoadManifest_SyntaxError(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+
+	path := writeManifest(t, sources, "{\n  \"name\": \"accounts\",\n  \"sources\": \"sources\"\n  \"menu\": []\n}")
+	_, err := LoadManifest(path)
+	require.NotNil(t, err)
+	require.Equal(t, path+":4: invalid character '\"' after object key:value pair", err.Error())
+
+	_, err = LoadManifest(writeManifest(t, sources, "[]"))
+	require.NotNil(t, err)
+	require.Equal(t, []ManifestProblem{{1, "The manifest must be of type object, not array"}}, err.(*ManifestError).Problems)
+}
+
+func TestLoadManifest_InvalidParts(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+
+	_, err := LoadManifest(writeManifest(t, sources, `{
+  "name": "accounts",
+  "sources": "sources",
+  "components": {"app": "App", "main": "main-component"},
+  "resources": [
+    {"key": ""}
+  ],
+  "translations": {
+    "french": {}
+  }
+}`))
+	require.NotNil(t, err)
+	require.Equal(t, []ManifestProblem{
+		{4, "The Main component name does not match the pattern \"^[a-zA-Z0-9]+$\": \"main-component\""},
+		{6, "The resource key must not be empty"},
+		{9, "The language does not match the pattern \"^[a-z]{2,3}([_\\-][a-zA-Z0-9]+)*$\": \"french\""},
+	}, err.(*ManifestError).Problems)
+
+	_, err = LoadManifest(filepath.Join(filepath.Dir(sources), "missing.json"))
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestRegisterFromManifest(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	require.Nil(t, RegisterFromManifest(writeManifest(t, sources, validManifest)))
+
+	_, err := os.Stat(webModulesDir + "accounts/index.js")
+	require.Nil(t, err)
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configurations))
+	require.Equal(t, []string{"accounts"}, configurations[0].Resources)
+	catalog, err := translations.GetTranslations("fr")
+	require.Nil(t, err)
+	require.Equal(t, translations.Translations{"app": map[string]interface{}{"title": "Comptes"}}, catalog)
+
+	require.Nil(t, Unregister("accounts"))
+}
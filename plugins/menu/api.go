@@ -1,58 +1,71 @@
 package menu
 
 import (
-	"github.com/hashicorp/consul/api"
-	"log"
+	"context"
 	"encoding/json"
+	"log"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
 )
 
-var consulAddress = "consul.agilestacknet:8500"
 const menuPrefix = "agilestack/menu/"
 
-func SetConsulAddress(consultAddress string) {
-	consulAddress = consultAddress
-}
-
-// Stores the menu and returns the result error.
-func StoreMenu(menu *Menu) error {
-	client, err := client()
+// Change the address of Consul used by the package-level functions.
+//
+// Deprecated: use NewStore and SetDefaultStore instead.
+func SetConsulAddress(consulAddress string) {
+	config, err := store.NewConsulConfig(store.WithAddress(consulAddress))
 	if err != nil {
-		return err
+		log.Println("Invalid Consul configuration:", err)
+		return
 	}
-
-	if err:= ValidateMenu(menu); err != nil {
-		log.Println("Menu is invalid:", err)
-		return err
+	s, err := NewStore(config)
+	if err != nil {
+		log.Println("Unable to create the Consul client:", err)
+		return
 	}
+	SetDefaultStore(s)
+}
 
-	kv := client.KV()
+// MenuStore stores the menus of the plugins in a key/value store.
+type MenuStore struct {
+	kv store.KVStore
+}
 
-	bytes, err := json.Marshal(menu)
-	if err != nil {
-		log.Println("Error while marshalling the menu:", err)
+// Returns a menu store backed by the given key/value store.
+func NewMenuStore(kv store.KVStore) *MenuStore {
+	return &MenuStore{kv: kv}
+}
+
+// Stores the menu for the tenant of the context and returns the result error.
+func (s *MenuStore) StoreMenu(ctx context.Context, menu *Menu) error {
+	if err := ValidateMenu(menu); err != nil {
+		log.Println("Menu is invalid:", err)
 		return err
 	}
 
-	pair := &api.KVPair{
-		Key:   menuPrefix + menu.PluginName,
-		Value: bytes,
+	if err := store.PutJSON(s.kv, tenant.Key(ctx, menuPrefix+menu.PluginName), menu); err != nil {
+		log.Println("Error while storing the menu:", err)
+		return err
 	}
-	_, err = kv.Put(pair, &api.WriteOptions{})
-	return err
+	return nil
 }
 
-// Lists all the existing menus.
-func  ListMenus() ([]Menu, error) {
-	client, err := client()
-	if err != nil {
-		return nil, err
+// Returns the operation storing the menu for the tenant of the context, to
+// apply with other operations (see store.Apply).
+func StoreOperation(ctx context.Context, menu *Menu) (store.Operation, error) {
+	if err := ValidateMenu(menu); err != nil {
+		return store.Operation{}, err
 	}
+	return store.SetJSON(tenant.Key(ctx, menuPrefix+menu.PluginName), menu)
+}
 
-	kv := client.KV()
-
-	pairs, _, err := kv.List(menuPrefix, nil)
+// Lists all the existing menus of the tenant of the context.
+func (s *MenuStore) ListMenus(ctx context.Context) ([]Menu, error) {
+	pairs, err := s.kv.List(tenant.Key(ctx, menuPrefix))
 	if err != nil {
-		log.Println("Error while listing menu entries in Consul:", err)
+		log.Println("Error while listing menu entries:", err)
 		return nil, err
 	}
 
@@ -69,20 +82,122 @@ func  ListMenus() ([]Menu, error) {
 	return menus, nil
 }
 
+// Deletes the menu matching the given plugin name, for the tenant of the context.
+func (s *MenuStore) DeleteMenu(ctx context.Context, pluginName string) error {
+	return s.kv.Delete(tenant.Key(ctx, menuPrefix+pluginName))
+}
+
+// A change of the menu of a plugin.
+type MenuEvent struct {
+	Type store.EventType
+
+	// The menu after the change, or the last known menu when it is removed.
+	Menu Menu
+}
+
+// Watches the menus of the tenant of the context until the context is done,
+// then closes the returned channel. The menus existing when the watch starts
+// are notified as added.
+func (s *MenuStore) Watch(ctx context.Context) <-chan MenuEvent {
+	pairs := store.Watch(ctx, s.kv, tenant.Key(ctx, menuPrefix))
+	events := make(chan MenuEvent)
+	go func() {
+		defer close(events)
+		for event := range pairs {
+			menu := Menu{}
+			if err := json.Unmarshal(event.Pair.Value, &menu); err != nil {
+				log.Printf("Error while unmarshalling menu '%s': %v", event.Pair.Key, err)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case events <- MenuEvent{Type: event.Type, Menu: menu}:
+			}
+		}
+	}()
+	return events
+}
+
+// Stores the menu and returns the result error.
+func StoreMenu(menu *Menu) error {
+	return StoreMenuContext(context.Background(), menu)
+}
+
+// Stores the menu for the tenant of the context and returns the result error.
+func StoreMenuContext(ctx context.Context, menu *Menu) error {
+	s, err := DefaultStore()
+	if err != nil {
+		return err
+	}
+	return s.StoreMenu(ctx, menu)
+}
+
+// Lists all the existing menus.
+func ListMenus() ([]Menu, error) {
+	return ListMenusContext(context.Background())
+}
+
+// Lists all the existing menus of the tenant of the context.
+func ListMenusContext(ctx context.Context) ([]Menu, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.ListMenus(ctx)
+}
+
 // Deletes the menu matching the given plugin name.
 func DeleteMenu(pluginName string) error {
-	client, err := client()
+	return DeleteMenuContext(context.Background(), pluginName)
+}
+
+// Deletes the menu matching the given plugin name, for the tenant of the context.
+func DeleteMenuContext(ctx context.Context, pluginName string) error {
+	s, err := DefaultStore()
 	if err != nil {
 		return err
 	}
+	return s.DeleteMenu(ctx, pluginName)
+}
 
-	kv := client.KV()
-	_, err = kv.Delete(menuPrefix+pluginName, nil)
-	return err
+// Watches the menus of the tenant of the context until the context is done.
+func Watch(ctx context.Context) (<-chan MenuEvent, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.Watch(ctx), nil
+}
+
+// Returns a menu store backed by the Consul matching the configuration.
+func NewStore(config store.ConsulConfig) (*MenuStore, error) {
+	kv, err := store.NewConsulStoreFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewMenuStore(kv), nil
 }
 
-func client() (*api.Client, error) {
-	config := api.DefaultConfig()
-	config.Address = consulAddress
-	return api.NewClient(config)
-}
\ No newline at end of file
+// The key/value store of the package-level functions.
+var defaultKV store.Override
+
+// Returns the store used by the package-level functions, backed by the default
+// key/value store unless another one is set (see store.Override).
+func DefaultStore() (*MenuStore, error) {
+	kv, err := defaultKV.Store()
+	if err != nil {
+		return nil, err
+	}
+	return NewMenuStore(kv), nil
+}
+
+// Changes the store used by the package-level functions. A nil store restores
+// the default one.
+func SetDefaultStore(s *MenuStore) {
+	if s == nil {
+		defaultKV.Set(nil)
+		return
+	}
+	defaultKV.Set(s.kv)
+}
@@ -1,10 +1,14 @@
 package menu_test
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
 	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
+	TestUtils "github.com/eogile/agilestack-utils/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -192,3 +196,122 @@ func TestDeleteMenu_api(t *testing.T) {
 	require.Equal(t, 1, len(menus))
 	require.Equal(t, menu2.PluginName, menus[0].PluginName)
 }
+
+func TestListMenus_Tenants_api(t *testing.T) {
+	deleteAllMenus(t)
+	acme := tenant.NewContext(context.Background(), "acme")
+	require.Nil(t, menu.StoreMenu(&menu1))
+	require.Nil(t, menu.StoreMenuContext(acme, &menu2))
+
+	menus, err := menu.ListMenus()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(menus))
+	validateMenu(t, menu1, menus[0])
+
+	menus, err = menu.ListMenusContext(acme)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(menus))
+	validateMenu(t, menu2, menus[0])
+
+	pair, _, err := consulClient(t).KV().Get("agilestack/tenants/acme/menu/plugin_2", nil)
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+
+	// Deleting the menu of another tenant has no effect
+	require.Nil(t, menu.DeleteMenuContext(acme, menu1.PluginName))
+	menus, err = menu.ListMenus()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(menus))
+}
+
+func TestMenuStore_MemoryStore(t *testing.T) {
+	kv := store.NewMemoryStore()
+	menuStore := menu.NewMenuStore(kv)
+	ctx := context.Background()
+
+	require.Nil(t, menuStore.StoreMenu(ctx, &menu1))
+	require.Nil(t, menuStore.StoreMenu(ctx, &menu2))
+
+	menus, err := menuStore.ListMenus(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(menus))
+	validateMenu(t, menu1, menus[0])
+	validateMenu(t, menu2, menus[1])
+
+	pair, err := kv.Get("agilestack/menu/plugin_2")
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+
+	require.Nil(t, menuStore.DeleteMenu(ctx, menu1.PluginName))
+	menus, err = menuStore.ListMenus(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(menus))
+	validateMenu(t, menu2, menus[0])
+}
+
+func TestNewStore_TwoConsuls(t *testing.T) {
+	consul1 := TestUtils.NewFakeConsul()
+	defer consul1.Close()
+	consul2 := TestUtils.NewFakeConsul()
+	defer consul2.Close()
+
+	store1, err := menu.NewStore(store.ConsulConfig{Address: consul1.Info().Address})
+	require.Nil(t, err)
+	store2, err := menu.NewStore(store.ConsulConfig{Address: consul2.Info().Address})
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	require.Nil(t, store1.StoreMenu(ctx, &menu1))
+	require.Nil(t, store2.StoreMenu(ctx, &menu2))
+
+	menus, err := store1.ListMenus(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(menus))
+	validateMenu(t, menu1, menus[0])
+
+	menus, err = store2.ListMenus(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(menus))
+	validateMenu(t, menu2, menus[0])
+}
+
+func TestMenuStore_Watch(t *testing.T) {
+	menuStore := menu.NewMenuStore(store.NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.Nil(t, menuStore.StoreMenu(ctx, &menu1))
+	events := menuStore.Watch(ctx)
+	event := <-events
+	require.Equal(t, store.Added, event.Type)
+	validateMenu(t, menu1, event.Menu)
+
+	require.Nil(t, menuStore.StoreMenu(ctx, &menu2))
+	event = <-events
+	require.Equal(t, store.Added, event.Type)
+	validateMenu(t, menu2, event.Menu)
+
+	require.Nil(t, menuStore.DeleteMenu(ctx, menu1.PluginName))
+	event = <-events
+	require.Equal(t, store.Removed, event.Type)
+	validateMenu(t, menu1, event.Menu)
+}
+
+func TestStoreOperation(t *testing.T) {
+	kv := store.NewMemoryStore()
+	menuStore := menu.NewMenuStore(kv)
+	ctx := context.Background()
+
+	operation, err := menu.StoreOperation(ctx, &menu1)
+	require.Nil(t, err)
+	require.Nil(t, store.Apply(kv, []store.Operation{operation}))
+
+	menus, err := menuStore.ListMenus(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(menus))
+	validateMenu(t, menu1, menus[0])
+
+	_, err = menu.StoreOperation(ctx, &menu.Menu{PluginName: "plugin"})
+	require.NotNil(t, err)
+	require.Equal(t, "The menu entries slice must not be nil", err.Error())
+}
@@ -0,0 +1,122 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A menu entry while the application menu is built.
+type menuNode struct {
+	entry      MenuEntry
+	pluginName string
+	children   []*menuNode
+}
+
+/*
+Builds the application menu from the menus of all the plugins.
+
+The top-level entries of the plugins are the top-level entries of the
+application menu, except the entries having a parent route: they are added
+under the entry of that route, which may be provided by another plugin.
+The entries are sorted by weight then name at every level.
+
+Returns an error when several plugins provide the same route, when a parent
+route is unknown, or when parent routes form a cycle.
+*/
+func BuildApplicationMenu(menus []Menu) (*ApplicationMenu, error) {
+	sorted := make([]Menu, len(menus))
+	copy(sorted, menus)
+	sort.Sort(byPluginName(sorted))
+
+	problems := []string{}
+	routes := map[string]*menuNode{}
+	var index func(pluginName string, entries []MenuEntry) []*menuNode
+	index = func(pluginName string, entries []MenuEntry) []*menuNode {
+		nodes := make([]*menuNode, 0, len(entries))
+		for _, entry := range entries {
+			node := &menuNode{entry: entry, pluginName: pluginName}
+			node.children = index(pluginName, entry.Entries)
+			if existing, found := routes[entry.Route]; !found {
+				routes[entry.Route] = node
+			} else if existing.pluginName != pluginName {
+				problems = append(problems, fmt.Sprintf("The route \"%s\" is provided by the plugins \"%s\" and \"%s\"",
+					entry.Route, existing.pluginName, pluginName))
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes
+	}
+
+	roots := []*menuNode{}
+	contributions := []*menuNode{}
+	for _, menu := range sorted {
+		for _, node := range index(menu.PluginName, menu.Entries) {
+			if node.entry.ParentRoute == "" {
+				roots = append(roots, node)
+			} else {
+				contributions = append(contributions, node)
+			}
+		}
+	}
+
+	for _, node := range contributions {
+		parent, found := routes[node.entry.ParentRoute]
+		if !found {
+			problems = append(problems, fmt.Sprintf("Unknown parent route \"%s\" of the menu entry \"%s\" of the plugin \"%s\"",
+				node.entry.ParentRoute, node.entry.Name, node.pluginName))
+			continue
+		}
+		parent.children = append(parent.children, node)
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, ", "))
+	}
+
+	// The contributions which cannot be reached from the top-level entries
+	// are parents of each other.
+	reached := map[*menuNode]bool{}
+	entries := buildEntries(roots, reached)
+	for _, node := range contributions {
+		if !reached[node] {
+			problems = append(problems, fmt.Sprintf("The parent route \"%s\" of the menu entry \"%s\" of the plugin \"%s\" forms a cycle",
+				node.entry.ParentRoute, node.entry.Name, node.pluginName))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, ", "))
+	}
+	return &ApplicationMenu{Entries: entries}, nil
+}
+
+// Returns the sorted entries of the nodes, and marks the nodes as reached.
+func buildEntries(nodes []*menuNode, reached map[*menuNode]bool) []MenuEntry {
+	entries := make([]MenuEntry, 0, len(nodes))
+	for _, node := range nodes {
+		reached[node] = true
+		entry := node.entry
+		entry.ParentRoute = ""
+		entry.Entries = buildEntries(node.children, reached)
+		entries = append(entries, entry)
+	}
+	sort.Stable(byWeightAndName(entries))
+	return entries
+}
+
+type byPluginName []Menu
+
+func (m byPluginName) Len() int           { return len(m) }
+func (m byPluginName) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byPluginName) Less(i, j int) bool { return m[i].PluginName < m[j].PluginName }
+
+type byWeightAndName []MenuEntry
+
+func (e byWeightAndName) Len() int      { return len(e) }
+func (e byWeightAndName) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byWeightAndName) Less(i, j int) bool {
+	if e[i].Weight != e[j].Weight {
+		return e[i].Weight < e[j].Weight
+	}
+	return e[i].Name < e[j].Name
+}
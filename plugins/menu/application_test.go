@@ -0,0 +1,116 @@
+package menu_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/stretchr/testify/require"
+)
+
+func entry(name, route string, weight int, entries ...menu.MenuEntry) menu.MenuEntry {
+	return menu.MenuEntry{Name: name, Route: route, Weight: weight, Entries: append([]menu.MenuEntry{}, entries...)}
+}
+
+// Returns the names of the entries, the sub entries being between brackets.
+func entryNames(entries []menu.MenuEntry) []string {
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name)
+		if len(e.Entries) > 0 {
+			names = append(names, "[")
+			names = append(names, entryNames(e.Entries)...)
+			names = append(names, "]")
+		}
+	}
+	return names
+}
+
+func TestBuildApplicationMenu(t *testing.T) {
+	menus := []menu.Menu{
+		{
+			PluginName: "users",
+			Entries: []menu.MenuEntry{
+				entry("Users", "/users", 20,
+					entry("Groups", "/users/groups", 2),
+					entry("Accounts", "/users/accounts", 2),
+					entry("Roles", "/users/roles", 1),
+				),
+			},
+		},
+		{
+			PluginName: "dashboard",
+			Entries: []menu.MenuEntry{
+				entry("Dashboard", "/dashboard", 0),
+				entry("Administration", "/admin", 20),
+			},
+		},
+	}
+
+	applicationMenu, err := menu.BuildApplicationMenu(menus)
+	require.Nil(t, err)
+	require.Equal(t,
+		[]string{"Dashboard", "Administration", "Users", "[", "Roles", "Accounts", "Groups", "]"},
+		entryNames(applicationMenu.Entries))
+
+	// The menus are left unchanged
+	require.Equal(t, "Groups", menus[0].Entries[0].Entries[0].Name)
+}
+
+func TestBuildApplicationMenu_Empty(t *testing.T) {
+	applicationMenu, err := menu.BuildApplicationMenu(nil)
+	require.Nil(t, err)
+	require.NotNil(t, applicationMenu.Entries)
+	require.Equal(t, 0, len(applicationMenu.Entries))
+}
+
+func TestBuildApplicationMenu_ParentRoute(t *testing.T) {
+	settings := entry("Settings", "/admin/settings", 5, entry("Mail", "/admin/settings/mail", 1))
+	settings.ParentRoute = "/admin"
+	smtp := entry("SMTP", "/admin/settings/smtp", 0)
+	smtp.ParentRoute = "/admin/settings/mail"
+
+	applicationMenu, err := menu.BuildApplicationMenu([]menu.Menu{
+		{PluginName: "settings", Entries: []menu.MenuEntry{settings}},
+		{PluginName: "mail", Entries: []menu.MenuEntry{smtp}},
+		{PluginName: "admin", Entries: []menu.MenuEntry{entry("Administration", "/admin", 1, entry("Audit", "/admin/audit", 1))}},
+	})
+	require.Nil(t, err)
+	require.Equal(t,
+		[]string{"Administration", "[", "Audit", "Settings", "[", "Mail", "[", "SMTP", "]", "]", "]"},
+		entryNames(applicationMenu.Entries))
+	require.Equal(t, "", applicationMenu.Entries[0].Entries[1].ParentRoute)
+}
+
+func TestBuildApplicationMenu_DuplicateRoute(t *testing.T) {
+	_, err := menu.BuildApplicationMenu([]menu.Menu{
+		{PluginName: "plugin-b", Entries: []menu.MenuEntry{entry("Users", "/admin", 1, entry("Users", "/users", 1))}},
+		{PluginName: "plugin-a", Entries: []menu.MenuEntry{entry("Users", "/users", 1)}},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "The route \"/users\" is provided by the plugins \"plugin-a\" and \"plugin-b\"", err.Error())
+}
+
+func TestBuildApplicationMenu_UnknownParentRoute(t *testing.T) {
+	settings := entry("Settings", "/admin/settings", 5)
+	settings.ParentRoute = "/admin"
+
+	_, err := menu.BuildApplicationMenu([]menu.Menu{
+		{PluginName: "settings", Entries: []menu.MenuEntry{settings}},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Unknown parent route \"/admin\" of the menu entry \"Settings\" of the plugin \"settings\"", err.Error())
+}
+
+func TestBuildApplicationMenu_Cycle(t *testing.T) {
+	a := entry("A", "/a", 1)
+	a.ParentRoute = "/b"
+	b := entry("B", "/b", 1)
+	b.ParentRoute = "/a"
+
+	_, err := menu.BuildApplicationMenu([]menu.Menu{
+		{PluginName: "plugin-a", Entries: []menu.MenuEntry{a}},
+		{PluginName: "plugin-b", Entries: []menu.MenuEntry{b}},
+	})
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "forms a cycle")
+}
@@ -0,0 +1,35 @@
+package menu
+
+import (
+	"github.com/eogile/agilestack-utils/secu"
+)
+
+/*
+Returns the application menu restricted to the entries allowed by the given
+permissions (see MenuEntry.Resource and MenuEntry.Permission).
+
+The sub entries of a hidden entry are hidden too. The entries whose sub entries
+are all hidden are removed, unless they require a permission themselves. The
+permissions granted under conditions do not show an entry (see
+secu.EffectivePermissions.IsAllowed).
+*/
+func FilterMenu(applicationMenu *ApplicationMenu, permissions *secu.EffectivePermissions) *ApplicationMenu {
+	return &ApplicationMenu{Entries: filterEntries(applicationMenu.Entries, permissions)}
+}
+
+func filterEntries(entries []MenuEntry, permissions *secu.EffectivePermissions) []MenuEntry {
+	filtered := make([]MenuEntry, 0, len(entries))
+	for _, entry := range entries {
+		restricted := entry.Resource != ""
+		if restricted && !permissions.IsAllowed(entry.Resource, entry.Permission) {
+			continue
+		}
+		children := filterEntries(entry.Entries, permissions)
+		if len(entry.Entries) > 0 && len(children) == 0 && !restricted {
+			continue
+		}
+		entry.Entries = children
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
@@ -0,0 +1,90 @@
+package menu_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/stretchr/testify/require"
+)
+
+func restricted(name, route, resource, permission string, entries ...menu.MenuEntry) menu.MenuEntry {
+	e := entry(name, route, 0, entries...)
+	e.Resource = resource
+	e.Permission = permission
+	return e
+}
+
+func TestFilterMenu_Conditional(t *testing.T) {
+	applicationMenu := &menu.ApplicationMenu{
+		Entries: []menu.MenuEntry{
+			restricted("Accounts", "/accounts", "accounts", "get"),
+			restricted("Profile", "/profile", "profile", "get"),
+			restricted("Clients", "/clients", "clients", "get"),
+		},
+	}
+	// Only the owner of an account can get it, the clients are denied to
+	// their owner
+	permissions := &secu.EffectivePermissions{
+		UserId: "user-a",
+		Resources: []secu.ResourcePermissions{
+			{Resource: "accounts", Permissions: []secu.GrantedPermission{{Permission: "get", Effect: "allow", Conditional: true}}},
+			{Resource: "profile", Permissions: []secu.GrantedPermission{
+				{Permission: "get", Effect: "allow", Conditional: true},
+				{Permission: "get", Effect: "allow"},
+			}},
+			{Resource: "clients", Permissions: []secu.GrantedPermission{
+				{Permission: "get", Effect: "allow"},
+				{Permission: "get", Effect: "deny", Conditional: true},
+			}},
+		},
+	}
+
+	// The entries needing a condition are hidden
+	filtered := menu.FilterMenu(applicationMenu, permissions)
+	require.Equal(t, []string{"Profile"}, entryNames(filtered.Entries))
+}
+
+func TestFilterMenu(t *testing.T) {
+	applicationMenu := &menu.ApplicationMenu{
+		Entries: []menu.MenuEntry{
+			entry("Dashboard", "/dashboard", 0),
+			entry("Administration", "/admin", 1,
+				restricted("Accounts", "/admin/accounts", "accounts", "get"),
+				restricted("Clients", "/admin/clients", "clients", "get"),
+			),
+			entry("Settings", "/settings", 2,
+				restricted("Mail", "/settings/mail", "settings", "update"),
+			),
+			restricted("Reports", "/reports", "reports", "get",
+				restricted("Exports", "/reports/exports", "exports", "create"),
+			),
+			restricted("Billing", "/billing", "billing", "get",
+				entry("Invoices", "/billing/invoices", 0),
+			),
+		},
+	}
+	permissions := &secu.EffectivePermissions{
+		UserId: "user-a",
+		Resources: []secu.ResourcePermissions{
+			{Resource: "accounts", Permissions: []secu.GrantedPermission{{Permission: "get", Effect: "allow"}}},
+			{Resource: "clients", Permissions: []secu.GrantedPermission{
+				{Permission: "get", Effect: "allow"},
+				{Permission: "get", Effect: "deny"},
+			}},
+			{Resource: "settings", Permissions: []secu.GrantedPermission{{Permission: "get", Effect: "allow"}}},
+			{Resource: "reports", Permissions: []secu.GrantedPermission{{Permission: "get", Effect: "allow"}}},
+		},
+	}
+
+	filtered := menu.FilterMenu(applicationMenu, permissions)
+	// The settings have no visible entry left, the reports remain reachable
+	// even without their exports.
+	require.Equal(t,
+		[]string{"Dashboard", "Administration", "[", "Accounts", "]", "Reports"},
+		entryNames(filtered.Entries))
+	require.NotNil(t, filtered.Entries[2].Entries)
+
+	// The application menu is left unchanged
+	require.Equal(t, 2, len(applicationMenu.Entries[1].Entries))
+}
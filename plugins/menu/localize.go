@@ -0,0 +1,75 @@
+package menu
+
+// A Catalog gives the translations of the dotted keys (such as
+// "app.menu.users") in each language.
+type Catalog interface {
+	// Translate returns the translation of the key in the language, and false
+	// if there is none.
+	Translate(lang, key string) (string, bool)
+}
+
+// A translation key of the menu missing in a language.
+type MissingTranslation struct {
+	Key      string `json:"key"`
+	Language string `json:"language"`
+}
+
+/*
+Returns a copy of the application menu whose entry names are translated in the
+given language, along with the translations missing in the catalog. See
+translations.LocalizeMenu to use the stored catalogs.
+
+The names of the entries with a translation key are looked up in the language,
+then in the fallback language, which may be empty when the catalog falls back
+on other languages itself (such as a translations.Localizer). The entries keep
+their name when there is no translation at all. The entries without a
+translation key keep their name.
+*/
+func LocalizeMenu(applicationMenu *ApplicationMenu, lang string, catalog Catalog, fallbackLang string) (*ApplicationMenu, []MissingTranslation) {
+	languages := []string{lang}
+	if fallbackLang != "" && fallbackLang != lang {
+		languages = append(languages, fallbackLang)
+	}
+	localizer := &menuLocalizer{
+		catalog:   catalog,
+		languages: languages,
+		missing:   []MissingTranslation{},
+		reported:  map[MissingTranslation]bool{},
+	}
+	return &ApplicationMenu{Entries: localizer.localize(applicationMenu.Entries)}, localizer.missing
+}
+
+type menuLocalizer struct {
+	catalog   Catalog
+	languages []string
+	missing   []MissingTranslation
+	reported  map[MissingTranslation]bool
+}
+
+func (l *menuLocalizer) localize(entries []MenuEntry) []MenuEntry {
+	localized := make([]MenuEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.TranslationKey != "" {
+			entry.Name = l.translate(entry.TranslationKey, entry.Name)
+		}
+		entry.Entries = l.localize(entry.Entries)
+		localized = append(localized, entry)
+	}
+	return localized
+}
+
+// Returns the translation of the key in the first language having one, or
+// the default value. The languages without translation are reported once.
+func (l *menuLocalizer) translate(key, defaultValue string) string {
+	for _, lang := range l.languages {
+		if translation, found := l.catalog.Translate(lang, key); found {
+			return translation
+		}
+		missing := MissingTranslation{Key: key, Language: lang}
+		if !l.reported[missing] {
+			l.reported[missing] = true
+			l.missing = append(l.missing, missing)
+		}
+	}
+	return defaultValue
+}
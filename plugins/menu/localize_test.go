@@ -0,0 +1,72 @@
+package menu_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/stretchr/testify/require"
+)
+
+func translated(name, route, key string, entries ...menu.MenuEntry) menu.MenuEntry {
+	e := entry(name, route, 0, entries...)
+	e.TranslationKey = key
+	return e
+}
+
+// A catalog of the translations of the dotted keys, by language.
+type flatCatalog map[string]map[string]string
+
+func (c flatCatalog) Translate(lang, key string) (string, bool) {
+	translation, found := c[lang][key]
+	return translation, found
+}
+
+var catalog = flatCatalog{
+	"en": {
+		"app.menu.admin":    "Administration",
+		"app.menu.accounts": "Accounts",
+		"app.menu.clients":  "Clients",
+		"app.menu.settings": "Settings",
+	},
+	"fr": {
+		"app.menu.admin":    "Administration",
+		"app.menu.accounts": "Comptes",
+	},
+}
+
+func TestLocalizeMenu(t *testing.T) {
+	applicationMenu := &menu.ApplicationMenu{
+		Entries: []menu.MenuEntry{
+			translated("Admin", "/admin", "app.menu.admin",
+				translated("Accounts", "/admin/accounts", "app.menu.accounts"),
+				translated("Clients", "/admin/clients", "app.menu.clients"),
+				translated("Audit", "/admin/audit", "app.menu.audit"),
+				translated("Audit", "/admin/audit/old", "app.menu.audit"),
+			),
+			entry("Dashboard", "/dashboard", 0),
+		},
+	}
+
+	localized, missing := menu.LocalizeMenu(applicationMenu, "fr", catalog, "en")
+	require.Equal(t,
+		[]string{"Administration", "[", "Comptes", "Clients", "Audit", "Audit", "]", "Dashboard"},
+		entryNames(localized.Entries))
+	require.Equal(t, []menu.MissingTranslation{
+		{Key: "app.menu.clients", Language: "fr"},
+		{Key: "app.menu.audit", Language: "fr"},
+		{Key: "app.menu.audit", Language: "en"},
+	}, missing)
+
+	// The application menu is left unchanged
+	require.Equal(t, "Admin", applicationMenu.Entries[0].Name)
+}
+
+func TestLocalizeMenu_NoFallback(t *testing.T) {
+	applicationMenu := &menu.ApplicationMenu{
+		Entries: []menu.MenuEntry{translated("Clients", "/clients", "app.menu.clients")},
+	}
+
+	localized, missing := menu.LocalizeMenu(applicationMenu, "fr", catalog, "")
+	require.Equal(t, []string{"Clients"}, entryNames(localized.Entries))
+	require.Equal(t, []menu.MissingTranslation{{Key: "app.menu.clients", Language: "fr"}}, missing)
+}
@@ -5,7 +5,9 @@ import (
 	"testing"
 
 	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/store"
 	"github.com/eogile/agilestack-utils/plugins/test"
+	TestUtils "github.com/eogile/agilestack-utils/test"
 	"github.com/hashicorp/consul/api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -50,15 +52,24 @@ var (
 	}
 )
 
+// The address of the Consul container
+var consulAddress string
+
 func TestMain(m *testing.M) {
 	log.Println("Launching tests agilestack/utils/plugins/menu")
-	menu.SetConsulAddress("127.0.0.1:8501")
-	test.DoTestMain(m)
+	test.DoTestMain(m, func(consul *TestUtils.ConsulInfo) {
+		consulAddress = consul.Address
+		s, err := menu.NewStore(store.ConsulConfig{Address: consul.Address})
+		if err != nil {
+			log.Fatalln("Unable to create the store:", err)
+		}
+		menu.SetDefaultStore(s)
+	})
 }
 
 func consulClient(t *testing.T) *api.Client {
 	config := api.DefaultConfig()
-	config.Address = "localhost:8501"
+	config.Address = consulAddress
 	client, err := api.NewClient(config)
 	require.Nil(t, err)
 	return client
@@ -27,5 +27,30 @@ type (
 
 		// The sub menu entries
 		Entries    []MenuEntry `json:"entries"`
+
+		// The key of the translation of the name (such as "app.menu.users"),
+		// see LocalizeMenu. The name is used when it is empty.
+		TranslationKey string `json:"translationKey,omitempty"`
+
+		// The resource (the key of a registered resource) and the permission
+		// a user must hold to see the entry. Both are empty when the entry is
+		// visible to all the users.
+		Resource   string `json:"resource,omitempty"`
+		Permission string `json:"permission,omitempty"`
+
+		// The route of the entry, possibly provided by another plugin, this
+		// entry is added under in the application menu. Only allowed on the
+		// top-level entries, which are top-level entries of the application
+		// menu when it is empty.
+		ParentRoute string `json:"parentRoute,omitempty"`
+	}
+
+	/*
+	The application menu, made of the entries of all the deployed plugins
+	(see BuildApplicationMenu).
+	 */
+	ApplicationMenu struct {
+		// The top-level menu entries
+		Entries []MenuEntry `json:"entries"`
 	}
 )
\ No newline at end of file
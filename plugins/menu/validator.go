@@ -23,6 +23,13 @@ func ValidateMenu(menu *Menu) error {
 		if err := validateMenuEntry(menuEntry); err != nil {
 			return err
 		}
+		if menuEntry.ParentRoute == "" {
+			continue
+		}
+		if matched, err := regexp.MatchString("^[a-z0-9\\-_/]+$", menuEntry.ParentRoute); !matched || err != nil {
+			return fmt.Errorf("Menu entry parent route does not match the pattern \"^[a-z0-9\\-_/]+$\": \"%s\"",
+				menuEntry.ParentRoute)
+		}
 	}
 	return nil
 }
@@ -35,6 +42,14 @@ func validateMenuEntry(menuEntry MenuEntry) error {
 		return fmt.Errorf("Menu entry route does not match the pattern \"^[a-z0-9\\-_/]+$\": \"%s\"",
 			menuEntry.Route)
 	}
+	if menuEntry.TranslationKey != "" {
+		if matched, err := regexp.MatchString("^[a-zA-Z0-9_\\-]+(\\.[a-zA-Z0-9_\\-]+)*$", menuEntry.TranslationKey); !matched || err != nil {
+			return fmt.Errorf("Menu entry translation key is not a dotted term: \"%s\"", menuEntry.TranslationKey)
+		}
+	}
+	if (menuEntry.Resource == "") != (menuEntry.Permission == "") {
+		return fmt.Errorf("Menu entry resource and permission must be set together: \"%s\"", menuEntry.Name)
+	}
 	if menuEntry.Entries == nil {
 		return errors.New("The menu entries slice must not be nil")
 	}
@@ -43,6 +58,9 @@ func validateMenuEntry(menuEntry MenuEntry) error {
 		if err := validateMenuEntry(menuEntry); err != nil {
 			return err
 		}
+		if menuEntry.ParentRoute != "" {
+			return fmt.Errorf("Only the top-level menu entries may have a parent route: \"%s\"", menuEntry.Name)
+		}
 	}
 	return nil
 }
\ No newline at end of file
@@ -180,4 +180,56 @@ func TestValidate(t *testing.T) {
 	}
 	err := menu.ValidateMenu(inputMenu)
 	require.Nil(t, err)
-}
\ No newline at end of file
+}
+func TestValidate_ParentRoute(t *testing.T) {
+	inputMenu := &menu.Menu{
+		PluginName: "settings",
+		Entries: []menu.MenuEntry{
+			menu.MenuEntry{Name: "Settings", Route: "/admin/settings", ParentRoute: "/admin", Entries: []menu.MenuEntry{}},
+		},
+	}
+	require.Nil(t, menu.ValidateMenu(inputMenu))
+
+	inputMenu.Entries[0].ParentRoute = "/Admin"
+	err := menu.ValidateMenu(inputMenu)
+	require.NotNil(t, err)
+	require.Equal(t, "Menu entry parent route does not match the pattern \"^[a-z0-9\\-_/]+$\": \"/Admin\"", err.Error())
+
+	inputMenu.Entries[0].ParentRoute = ""
+	inputMenu.Entries[0].Entries = []menu.MenuEntry{
+		menu.MenuEntry{Name: "Mail", Route: "/admin/settings/mail", ParentRoute: "/admin", Entries: []menu.MenuEntry{}},
+	}
+	err = menu.ValidateMenu(inputMenu)
+	require.NotNil(t, err)
+	require.Equal(t, "Only the top-level menu entries may have a parent route: \"Mail\"", err.Error())
+}
+
+func TestValidate_ResourceWithoutPermission(t *testing.T) {
+	inputMenu := &menu.Menu{
+		PluginName: "accounts",
+		Entries: []menu.MenuEntry{
+			menu.MenuEntry{Name: "Accounts", Route: "/accounts", Resource: "accounts", Entries: []menu.MenuEntry{}},
+		},
+	}
+	err := menu.ValidateMenu(inputMenu)
+	require.NotNil(t, err)
+	require.Equal(t, "Menu entry resource and permission must be set together: \"Accounts\"", err.Error())
+
+	inputMenu.Entries[0].Permission = "get"
+	require.Nil(t, menu.ValidateMenu(inputMenu))
+}
+
+func TestValidate_TranslationKey(t *testing.T) {
+	inputMenu := &menu.Menu{
+		PluginName: "accounts",
+		Entries: []menu.MenuEntry{
+			menu.MenuEntry{Name: "Accounts", Route: "/accounts", TranslationKey: "app.menu.accounts", Entries: []menu.MenuEntry{}},
+		},
+	}
+	require.Nil(t, menu.ValidateMenu(inputMenu))
+
+	inputMenu.Entries[0].TranslationKey = "app..accounts"
+	err := menu.ValidateMenu(inputMenu)
+	require.NotNil(t, err)
+	require.Equal(t, "Menu entry translation key is not a dotted term: \"app..accounts\"", err.Error())
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/eogile/agilestack-utils/plugins/components"
 	"github.com/eogile/agilestack-utils/plugins/menu"
 	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/translations"
 )
 
 type FullRegistration struct {
@@ -12,4 +14,13 @@ type FullRegistration struct {
 	Menu        *menu.Menu
 	Components  *components.Components
 	Config      *registration.PluginConfiguration
+
+	// The security resources of the plugin.
+	Resources []resource.Resource
+
+	// The translations contributed by the plugin, by language.
+	Translations map[string]translations.Translations
+
+	// Allows to replace a greater version of the plugin.
+	Force bool
 }
@@ -2,19 +2,27 @@
 package plugins
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
-	"bytes"
+	"github.com/eogile/agilestack-utils/files"
+	"github.com/eogile/agilestack-utils/plugins/components"
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/translations"
 	"github.com/nats-io/nats"
 	"github.com/nats-io/nats/encoders/protobuf"
-	"io/ioutil"
-	"os"
-	"github.com/eogile/agilestack-utils/plugins/registration"
-	"github.com/eogile/agilestack-utils/plugins/menu"
-	"github.com/eogile/agilestack-utils/plugins/components"
-	"github.com/eogile/agilestack-utils/files"
 )
 
 /*
@@ -59,25 +67,379 @@ func ChangeBaseUrl(rootDir string) {
 
 }
 
+// The directory of the plugins sources in the application builder.
+var webModulesDir = "/shared/root-app-builder/web_modules/"
+
+/*
+Registers the plugin: copies its sources into the application builder, stores
+its routes and reducers, its menu, its components, its security resources and
+its translations, then launches the application build.
+
+All the parts are validated before anything is written. The keys, including
+the translations catalogs, are written in a single transaction of the store (see
+DefaultStore and store.Apply), and the previous sources are restored when the
+transaction fails. The transaction fails when another plugin is registered or a
+translations catalog is updated meanwhile (see registration.Installed): the
+registration is then checked and the transaction computed again. Consul accepts
+at most store.MaxConsulTxnOperations operations in a transaction: a
+registration needing more fails before anything is written. The registration is
+kept when the build fails.
+
+The registration fails when the platform is older than the version the plugin
+requires, when its dependencies are not satisfied (see
+registration.CheckDependencies), when its routes, reducers or components
+conflict with the ones of the other plugins (see registration.CheckConflicts),
+and, unless it is forced, with a *registration.DowngradeError when a greater
+version of the plugin is installed.
+*/
 func Register(config FullRegistration) error {
-	destination := "/shared/root-app-builder/web_modules/" + config.PluginName
-	if err:= files.CopyDir(config.SourcesPath, destination); err!=nil {
+	return RegisterContext(context.Background(), config)
+}
+
+// Registers the plugin for the tenant of the context (see Register).
+func RegisterContext(ctx context.Context, config FullRegistration) error {
+	operations, err := registrationOperations(ctx, config)
+	if err != nil {
+		log.Println("Invalid plugin registration:", err)
 		return err
 	}
-	if err := registration.StoreRoutesAndReducers(config.Config); err!= nil {
+	kv, err := DefaultStore()
+	if err != nil {
+		return err
+	}
+	configurationStore := registration.NewConfigurationStore(kv)
+	installed, err := checkRegistration(ctx, configurationStore, config)
+	if err != nil {
 		return err
 	}
 
-	if err := menu.StoreMenu(config.Menu); err != nil {
+	sources, err := copySources(config.SourcesPath, webModulesDir+config.PluginName)
+	if err != nil {
+		return err
+	}
+	if err := applyRegistration(ctx, configurationStore, installed, config, operations); err != nil {
+		log.Println("Error while storing the plugin registration:", err)
+		sources.rollback()
 		return err
 	}
+	sources.commit()
+	return registration.LaunchApplicationBuild()
+}
 
-	if config.Components != nil {
-		if err:= components.StoreComponents(config.Components); err != nil {
+// Reads the installed plugins of the tenant of the context and checks the
+// configuration of the plugin against them.
+func checkRegistration(ctx context.Context, configurationStore *registration.ConfigurationStore, config FullRegistration) (*registration.Installed, error) {
+	installed, err := configurationStore.Installed(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := installed.Check(pluginConfiguration(config), config.Force); err != nil {
+		log.Println("Incompatible plugin configuration:", err)
+		return nil, err
+	}
+	return installed, nil
+}
+
+// The number of times the registration is checked and applied when the
+// installed plugins or the translations catalogs are updated concurrently.
+const maxRegisterAttempts = 10
+
+/*
+Applies the operations of the registration along with the ones storing its
+configuration, checked against the installed plugins, and its translations.
+They are checked and applied again while the installed plugins or the
+translations catalogs are updated concurrently.
+*/
+func applyRegistration(ctx context.Context, configurationStore *registration.ConfigurationStore, installed *registration.Installed,
+	config FullRegistration, operations []store.Operation) error {
+	kv := configurationStore.KV()
+	translationsStore := translations.NewTranslationsStore(kv)
+	var err error
+	for attempt := 0; attempt < maxRegisterAttempts; attempt++ {
+		if attempt > 0 {
+			if installed, err = checkRegistration(ctx, configurationStore, config); err != nil {
+				return err
+			}
+		}
+		var configOperations, translationOperations []store.Operation
+		if configOperations, err = installed.StoreOperations(ctx, pluginConfiguration(config)); err != nil {
+			return err
+		}
+		translationOperations, err = translationsStore.StoreOperations(ctx, config.PluginName, config.Translations)
+		if err != nil {
+			return err
+		}
+		all := append(append(configOperations, operations...), translationOperations...)
+		err = store.Apply(kv, all)
+		if _, conflict := err.(*store.ConflictError); !conflict {
 			return err
 		}
 	}
+	return err
+}
 
-	return registration.LaunchApplicationBuild()
+// Returns the configuration of the plugin, listing the keys of its security
+// resources.
+func pluginConfiguration(config FullRegistration) *registration.PluginConfiguration {
+	pluginConfig := config.Config
+	if pluginConfig != nil && len(config.Resources) > 0 {
+		withResources := *pluginConfig
+		withResources.Resources = resourceKeys(pluginConfig.Resources, config.Resources)
+		pluginConfig = &withResources
+	}
+	return pluginConfig
 }
 
+/*
+Returns the operations storing the parts of the registration, but its
+configuration and its translations, which depend on the stored ones. Fails when
+a part is invalid, listing the problems of all the parts.
+*/
+func registrationOperations(ctx context.Context, config FullRegistration) ([]store.Operation, error) {
+	problems := []string{}
+	operations := []store.Operation{}
+	add := func(operation store.Operation, err error) {
+		if err != nil {
+			problems = append(problems, err.Error())
+			return
+		}
+		operations = append(operations, operation)
+	}
+
+	if strings.TrimSpace(config.PluginName) == "" || strings.Contains(config.PluginName, "/") {
+		problems = append(problems, fmt.Sprintf("Invalid plugin name: \"%s\"", config.PluginName))
+	}
+	if err := registration.Validate(pluginConfiguration(config)); err != nil {
+		problems = append(problems, err.Error())
+	}
+	add(menu.StoreOperation(ctx, config.Menu))
+	if config.Components != nil {
+		add(components.StoreOperation(ctx, config.Components))
+	}
+	for _, res := range config.Resources {
+		add(resource.StoreOperation(ctx, res))
+	}
+	for _, lang := range sortedLanguages(config.Translations) {
+		if err := translations.Validate(config.PluginName, lang, config.Translations[lang]); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, ", "))
+	}
+	return operations, nil
+}
+
+func sortedLanguages(catalogs map[string]translations.Translations) []string {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// The sources of a plugin copied into the application builder. The previous
+// sources are kept aside until the registration is done.
+type sourcesCopy struct {
+	destination string
+	backup      string
+}
+
+func copySources(source, destination string) (*sourcesCopy, error) {
+	sources := &sourcesCopy{destination: destination}
+	if _, err := os.Stat(destination); err == nil {
+		sources.backup = filepath.Join(filepath.Dir(destination), "."+filepath.Base(destination)+".backup")
+		if err := os.RemoveAll(sources.backup); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(destination, sources.backup); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := files.CopyDir(source, destination); err != nil {
+		sources.rollback()
+		return nil, err
+	}
+	return sources, nil
+}
+
+// Removes the copied sources and restores the previous ones. The errors are
+// logged.
+func (c *sourcesCopy) rollback() {
+	if err := os.RemoveAll(c.destination); err != nil {
+		log.Printf("Error while removing the sources '%s': %v", c.destination, err)
+	}
+	if c.backup == "" {
+		return
+	}
+	if err := os.Rename(c.backup, c.destination); err != nil {
+		log.Printf("Error while restoring the sources '%s': %v", c.destination, err)
+	}
+}
+
+// Removes the previous sources.
+func (c *sourcesCopy) commit() {
+	if c.backup == "" {
+		return
+	}
+	if err := os.RemoveAll(c.backup); err != nil {
+		log.Printf("Error while removing the sources '%s': %v", c.backup, err)
+	}
+}
+
+// Returns the keys, followed by the keys of the resources missing from them.
+func resourceKeys(keys []string, resources []resource.Resource) []string {
+	merged := append([]string{}, keys...)
+	for _, res := range resources {
+		found := false
+		for _, key := range merged {
+			found = found || key == res.Key
+		}
+		if !found {
+			merged = append(merged, res.Key)
+		}
+	}
+	return merged
+}
+
+// A step of an unregistration which failed.
+type UnregisterFailure struct {
+	// What the step does, such as "deleting the menu".
+	Step string
+	Err  error
+}
+
+// The error of an unregistration, listing the steps which failed. The other
+// steps are done.
+type UnregisterError struct {
+	PluginName string
+	Failures   []UnregisterFailure
+}
+
+func (e *UnregisterError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		failures[i] = failure.Step + ": " + failure.Err.Error()
+	}
+	return fmt.Sprintf("Unable to unregister the plugin \"%s\": %s", e.PluginName, strings.Join(failures, ", "))
+}
+
+/*
+Unregisters the plugin, undoing Register: removes its sources from the
+application builder, deletes its routes and reducers, its menu, its components,
+its security resources and its translations, then relaunches the application
+build. The keys are deleted from the store Register writes to (see
+DefaultStore).
+
+The plugin is not unregistered when other plugins depend on it. Otherwise, all
+the steps are attempted even when some of them fail: the returned error is then
+an *UnregisterError listing the failed steps.
+*/
+func Unregister(pluginName string) error {
+	return UnregisterContext(context.Background(), pluginName)
+}
+
+// Unregisters the plugin of the tenant of the context (see Unregister).
+func UnregisterContext(ctx context.Context, pluginName string) error {
+	if strings.TrimSpace(pluginName) == "" || strings.Contains(pluginName, "/") {
+		return fmt.Errorf("Invalid plugin name: \"%s\"", pluginName)
+	}
+	kv, err := DefaultStore()
+	if err != nil {
+		return err
+	}
+	configurationStore := registration.NewConfigurationStore(kv)
+	configurations, err := configurationStore.ListRoutesAndReducers(ctx)
+	if err != nil {
+		return err
+	}
+	if dependents := registration.Dependents(pluginName, configurations); len(dependents) > 0 {
+		return fmt.Errorf("Unable to unregister the plugin \"%s\": the plugins \"%s\" depend on it",
+			pluginName, strings.Join(dependents, "\", \""))
+	}
+
+	unregisterError := &UnregisterError{PluginName: pluginName}
+	step := func(name string, err error) {
+		if err != nil {
+			log.Printf("Error while unregistering the plugin %s, %s: %v", pluginName, name, err)
+			unregisterError.Failures = append(unregisterError.Failures, UnregisterFailure{Step: name, Err: err})
+		}
+	}
+
+	step("removing the sources", os.RemoveAll(webModulesDir+pluginName))
+
+	// The resources keys are read before the configuration is deleted
+	resourceKeys := pluginResourceKeys(pluginName, configurations)
+	step("deleting the routes and reducers", configurationStore.DeleteRoutesAndReducers(ctx, pluginName))
+	step("deleting the menu", menu.NewMenuStore(kv).DeleteMenu(ctx, pluginName))
+	step("deleting the components", deletePluginComponents(ctx, components.NewComponentsStore(kv), pluginName))
+	if len(resourceKeys) > 0 {
+		step("deleting the security resources", deleteResources(ctx, kv, resourceKeys))
+	}
+	step("deleting the translations", translations.NewTranslationsStore(kv).DeleteTranslations(ctx, pluginName))
+	step("launching the application build", registration.LaunchApplicationBuild())
+
+	if len(unregisterError.Failures) > 0 {
+		return unregisterError
+	}
+	return nil
+}
+
+func pluginResourceKeys(pluginName string, configurations []registration.PluginConfiguration) []string {
+	for _, config := range configurations {
+		if config.PluginName == pluginName {
+			return config.Resources
+		}
+	}
+	return nil
+}
+
+// Deletes the components if they are provided by the plugin.
+func deletePluginComponents(ctx context.Context, componentsStore *components.ComponentsStore, pluginName string) error {
+	current, err := componentsStore.GetComponents(ctx)
+	if err != nil || current == nil || current.PluginName != pluginName {
+		return err
+	}
+	return componentsStore.DeleteComponents(ctx)
+}
+
+func deleteResources(ctx context.Context, kv store.KVStore, keys []string) error {
+	operations := make([]store.Operation, len(keys))
+	for i, key := range keys {
+		operations[i] = resource.DeleteOperation(ctx, key)
+	}
+	return store.Apply(kv, operations)
+}
+
+// Returns the key/value store Register and Unregister use: the store of the
+// registration package (see registration.DefaultStore).
+func DefaultStore() (store.KVStore, error) {
+	configurationStore, err := registration.DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return configurationStore.KV(), nil
+}
+
+// Changes the key/value store Register and Unregister use, which is the store
+// of the registration, menu, components and translations packages, so that
+// their package-level functions read what Register writes. A nil store
+// restores the default one.
+func SetDefaultStore(kv store.KVStore) {
+	if kv == nil {
+		registration.SetDefaultStore(nil)
+		menu.SetDefaultStore(nil)
+		components.SetDefaultStore(nil)
+		translations.SetDefaultStore(nil)
+		return
+	}
+	registration.SetDefaultStore(registration.NewConfigurationStore(kv))
+	menu.SetDefaultStore(menu.NewMenuStore(kv))
+	components.SetDefaultStore(components.NewComponentsStore(kv))
+	translations.SetDefaultStore(translations.NewTranslationsStore(kv))
+}
@@ -1,2 +1,530 @@
 package plugins
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/components"
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/test"
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	TestUtils "github.com/eogile/agilestack-utils/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	log.Println("Launching tests agilestack/utils/plugins")
+	test.DoTestMain(m, func(consul *TestUtils.ConsulInfo) {
+		// The stores and the resources client use the environment
+		os.Setenv(store.EnvConsulAddress, consul.Address)
+	})
+}
+
+// A fake application builder counting the builds.
+type fakeAppBuilder struct {
+	server *httptest.Server
+	builds int32
+	status int32
+}
+
+func newFakeAppBuilder(status int32) *fakeAppBuilder {
+	builder := &fakeAppBuilder{status: status}
+	builder.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&builder.builds, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&builder.status)))
+	}))
+	registration.SetDefaultAppBuilder(registration.NewAppBuilder(builder.server.URL, nil))
+	return builder
+}
+
+// Creates the sources of a plugin and the web modules directory, and returns
+// the path of the sources and a cleanup function.
+func setUpDirectories(t *testing.T) (string, func()) {
+	root, err := ioutil.TempDir("", "plugins")
+	require.Nil(t, err)
+	sources := filepath.Join(root, "sources")
+	require.Nil(t, os.MkdirAll(sources, 0755))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(sources, "index.js"), []byte("export default {}"), 0644))
+
+	webModulesDir = filepath.Join(root, "web_modules") + "/"
+	require.Nil(t, os.MkdirAll(webModulesDir, 0755))
+	return sources, func() { os.RemoveAll(root) }
+}
+
+func fullRegistration(sources string) FullRegistration {
+	return FullRegistration{
+		PluginName:  "accounts",
+		SourcesPath: sources,
+		Menu: &menu.Menu{PluginName: "accounts", Entries: []menu.MenuEntry{
+			{Name: "Accounts", Route: "/accounts", Entries: []menu.MenuEntry{}},
+		}},
+		Components: &components.Components{PluginName: "accounts", AppComponent: "App", MainComponent: "Main"},
+		Config: &registration.PluginConfiguration{
+			PluginName: "accounts",
+			Reducers:   []string{"accounts"},
+			Routes: []registration.Route{
+				{Href: "accounts", ComponentName: "AccountsList", Type: "content-route", Routes: []registration.SubRoute{}},
+			},
+		},
+		Resources: []resource.Resource{
+			{Key: "accounts", SecurityKey: "rn:hydra:accounts", Permissions: []string{"create"}},
+		},
+	}
+}
+
+func newResourcesClientForTest(t *testing.T) resource.PluginResourcesStorageClient {
+	config, err := store.NewConsulConfig()
+	require.Nil(t, err)
+	client, err := resource.NewResourcesStorageClientWithConfig(context.Background(), config)
+	require.Nil(t, err)
+	return client
+}
+
+func TestRegister_Resources(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	config := fullRegistration(sources)
+	require.Nil(t, Register(config))
+	require.Nil(t, config.Config.Resources)
+
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configurations))
+	require.Equal(t, []string{"accounts"}, configurations[0].Resources)
+
+	res, err := newResourcesClientForTest(t).GetResource("accounts")
+	require.Nil(t, err)
+	require.Equal(t, "rn:hydra:accounts", res.SecurityKey)
+
+	require.Nil(t, Unregister("accounts"))
+}
+
+func TestUnregister(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	require.Nil(t, Register(fullRegistration(sources)))
+	require.Nil(t, translations.StoreTranslations("accounts", "fr", translations.Translations{"app": map[string]interface{}{"title": "Comptes"}}))
+	_, err := os.Stat(webModulesDir + "accounts/index.js")
+	require.Nil(t, err)
+
+	require.Nil(t, Unregister("accounts"))
+	require.Equal(t, int32(2), atomic.LoadInt32(&builder.builds))
+
+	_, err = os.Stat(webModulesDir + "accounts")
+	require.True(t, os.IsNotExist(err))
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Empty(t, configurations)
+	menus, err := menu.ListMenus()
+	require.Nil(t, err)
+	require.Empty(t, menus)
+	current, err := components.GetComponents()
+	require.Nil(t, err)
+	require.Nil(t, current)
+	res, err := newResourcesClientForTest(t).GetResource("accounts")
+	require.Nil(t, err)
+	require.Nil(t, res)
+	translationsStore, err := translations.DefaultStore()
+	require.Nil(t, err)
+	languages, err := translationsStore.ListLanguages(context.Background())
+	require.Nil(t, err)
+	require.Empty(t, languages)
+}
+
+func TestUnregister_OtherPluginComponents(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	require.Nil(t, Register(fullRegistration(sources)))
+	require.Nil(t, components.StoreComponents(&components.Components{PluginName: "layout", AppComponent: "App", MainComponent: "Main"}))
+
+	require.Nil(t, Unregister("accounts"))
+	current, err := components.GetComponents()
+	require.Nil(t, err)
+	require.NotNil(t, current)
+	require.Equal(t, "layout", current.PluginName)
+	require.Nil(t, components.DeleteComponents())
+}
+
+func TestUnregister_PartialFailure(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	require.Nil(t, Register(fullRegistration(sources)))
+
+	atomic.StoreInt32(&builder.status, http.StatusInternalServerError)
+	err := Unregister("accounts")
+	require.NotNil(t, err)
+	require.Equal(t, "Unable to unregister the plugin \"accounts\": launching the application build: Invalid status code: 500", err.Error())
+	unregisterError, ok := err.(*UnregisterError)
+	require.True(t, ok)
+	require.Equal(t, 1, len(unregisterError.Failures))
+
+	// The other steps are done
+	menus, err := menu.ListMenus()
+	require.Nil(t, err)
+	require.Empty(t, menus)
+}
+
+func TestUnregister_InvalidName(t *testing.T) {
+	err := Unregister("../accounts")
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid plugin name: \"../accounts\"", err.Error())
+}
+
+func TestRegister_InvalidParts(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	config := fullRegistration(sources)
+	config.Menu.Entries = nil
+	config.Components.MainComponent = "main-component"
+	err := Register(config)
+	require.NotNil(t, err)
+	require.Equal(t, "The menu entries slice must not be nil, "+
+		"The Main component name does not match the pattern \"^[a-zA-Z0-9]+$\": \"main-component\"", err.Error())
+
+	// Nothing is written
+	_, err = os.Stat(webModulesDir + "accounts")
+	require.True(t, os.IsNotExist(err))
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Empty(t, configurations)
+	require.Equal(t, int32(0), atomic.LoadInt32(&builder.builds))
+}
+
+// A store whose transactions fail.
+type failingTxnStore struct {
+	*store.MemoryStore
+}
+
+func (s failingTxnStore) Txn(operations []store.Operation) error {
+	return errors.New("Transaction rolled back: Permission denied")
+}
+
+func TestRegister_Rollback(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	require.Nil(t, Register(fullRegistration(sources)))
+
+	// The new version of the plugin cannot be stored
+	kv := failingTxnStore{store.NewMemoryStore()}
+	SetDefaultStore(kv)
+	defer SetDefaultStore(nil)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(sources, "index.js"), []byte("export default {v2}"), 0644))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(sources, "new.js"), []byte("export default {}"), 0644))
+
+	err := Register(fullRegistration(sources))
+	require.NotNil(t, err)
+	require.Equal(t, "Transaction rolled back: Permission denied", err.Error())
+	require.Equal(t, int32(1), atomic.LoadInt32(&builder.builds))
+
+	// The previous sources are restored
+	content, err := ioutil.ReadFile(webModulesDir + "accounts/index.js")
+	require.Nil(t, err)
+	require.Equal(t, "export default {}", string(content))
+	_, err = os.Stat(webModulesDir + "accounts/new.js")
+	require.True(t, os.IsNotExist(err))
+	entries, err := ioutil.ReadDir(webModulesDir)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(entries))
+
+	SetDefaultStore(nil)
+	require.Nil(t, Unregister("accounts"))
+}
+
+func TestRegister_ReplacesSources(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	require.Nil(t, Register(fullRegistration(sources)))
+
+	require.Nil(t, os.Remove(filepath.Join(sources, "index.js")))
+	require.Nil(t, ioutil.WriteFile(filepath.Join(sources, "main.js"), []byte("export default {}"), 0644))
+	require.Nil(t, Register(fullRegistration(sources)))
+
+	entries, err := ioutil.ReadDir(webModulesDir + "accounts")
+	require.Nil(t, err)
+	require.Equal(t, 1, len(entries))
+	require.Equal(t, "main.js", entries[0].Name())
+	entries, err = ioutil.ReadDir(webModulesDir)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(entries))
+
+	require.Nil(t, Unregister("accounts"))
+}
+
+func TestRegister_Downgrade(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	config := fullRegistration(sources)
+	config.Config.Version = "1.2.0"
+	require.Nil(t, Register(config))
+
+	config = fullRegistration(sources)
+	config.Config.Version = "1.1.0"
+	err := Register(config)
+	require.NotNil(t, err)
+	require.Equal(t, "Unable to downgrade the plugin \"accounts\" from 1.2.0 to 1.1.0", err.Error())
+	require.Equal(t, int32(1), atomic.LoadInt32(&builder.builds))
+
+	config.Force = true
+	require.Nil(t, Register(config))
+	versions, err := registration.ListPluginVersions()
+	require.Nil(t, err)
+	require.Equal(t, []registration.PluginVersion{{PluginName: "accounts", Version: "1.1.0"}}, versions)
+
+	require.Nil(t, Unregister("accounts"))
+}
+
+func TestRegister_Dependencies(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	reports := FullRegistration{
+		PluginName:  "reports",
+		SourcesPath: sources,
+		Menu:        &menu.Menu{PluginName: "reports", Entries: []menu.MenuEntry{}},
+		Config: &registration.PluginConfiguration{
+			PluginName:   "reports",
+			Reducers:     []string{},
+			Routes:       []registration.Route{},
+			Dependencies: []registration.Dependency{{PluginName: "accounts"}},
+		},
+	}
+	err := Register(reports)
+	require.NotNil(t, err)
+	require.Equal(t, "The plugin \"reports\" depends on the plugin \"accounts\", which is not registered", err.Error())
+	_, err = os.Stat(webModulesDir + "reports")
+	require.True(t, os.IsNotExist(err))
+
+	require.Nil(t, Register(fullRegistration(sources)))
+	require.Nil(t, Register(reports))
+
+	err = Unregister("accounts")
+	require.NotNil(t, err)
+	require.Equal(t, "Unable to unregister the plugin \"accounts\": the plugins \"reports\" depend on it", err.Error())
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Equal(t, 2, len(configurations))
+
+	require.Nil(t, Unregister("reports"))
+	require.Nil(t, Unregister("accounts"))
+}
+
+func TestRegister_Conflicts(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	require.Nil(t, Register(fullRegistration(sources)))
+
+	config := fullRegistration(sources)
+	config.PluginName = "users"
+	config.Menu.PluginName = "users"
+	config.Components = nil
+	config.Resources = nil
+	config.Config.PluginName = "users"
+	err := Register(config)
+	require.NotNil(t, err)
+	require.Equal(t, "The route \"/accounts\" of the plugin \"users\" is already provided by the plugin \"accounts\", "+
+		"The reducer \"accounts\" of the plugin \"users\" is already provided by the plugin \"accounts\", "+
+		"The component \"AccountsList\" of the plugin \"users\" is already provided by the plugin \"accounts\"", err.Error())
+	_, err = os.Stat(webModulesDir + "users")
+	require.True(t, os.IsNotExist(err))
+
+	require.Nil(t, Unregister("accounts"))
+}
+
+func TestRegister_DefaultStore(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	kv := store.NewMemoryStore()
+	SetDefaultStore(kv)
+	defer SetDefaultStore(nil)
+
+	config := fullRegistration(sources)
+	config.Translations = map[string]translations.Translations{"fr": {"app": map[string]interface{}{"title": "Comptes"}}}
+	require.Nil(t, Register(config))
+
+	// The package-level functions read the store
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configurations))
+	catalog, err := translations.GetTranslations("fr")
+	require.Nil(t, err)
+	require.Equal(t, translations.Translations{"app": map[string]interface{}{"title": "Comptes"}}, catalog)
+
+	// Only the revision of the configurations is left
+	require.Nil(t, Unregister("accounts"))
+	pairs, err := kv.List("")
+	require.Nil(t, err)
+	require.Equal(t, 1, len(pairs))
+	require.Equal(t, "agilestack/registration-revision", pairs[0].Key)
+}
+
+// A store whose first transaction is preceded by the translations of another
+// plugin.
+type concurrentTranslationsStore struct {
+	*store.MemoryStore
+	txns int32
+}
+
+func (s *concurrentTranslationsStore) Txn(operations []store.Operation) error {
+	if atomic.AddInt32(&s.txns, 1) == 1 {
+		err := translations.NewTranslationsStore(s.MemoryStore).StoreTranslations(context.Background(),
+			"users", "fr", translations.Translations{"users": map[string]interface{}{"title": "Utilisateurs"}})
+		if err != nil {
+			return err
+		}
+	}
+	return s.MemoryStore.Txn(operations)
+}
+
+func TestRegister_ConcurrentTranslations(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	kv := &concurrentTranslationsStore{MemoryStore: store.NewMemoryStore()}
+	SetDefaultStore(kv)
+	defer SetDefaultStore(nil)
+
+	config := fullRegistration(sources)
+	config.Translations = map[string]translations.Translations{"fr": {"app": map[string]interface{}{"title": "Comptes"}}}
+	require.Nil(t, Register(config))
+	require.Equal(t, int32(2), atomic.LoadInt32(&kv.txns))
+
+	// The catalog merges both contributions
+	catalog, err := translations.GetTranslations("fr")
+	require.Nil(t, err)
+	require.Equal(t, translations.Translations{
+		"app":   map[string]interface{}{"title": "Comptes"},
+		"users": map[string]interface{}{"title": "Utilisateurs"},
+	}, catalog)
+
+	require.Nil(t, Unregister("accounts"))
+}
+
+// A store whose first transaction is preceded by the registration of another
+// plugin providing the same route.
+type concurrentRegistrationStore struct {
+	*store.MemoryStore
+	txns int32
+}
+
+func (s *concurrentRegistrationStore) Txn(operations []store.Operation) error {
+	if atomic.AddInt32(&s.txns, 1) == 1 {
+		err := registration.NewConfigurationStore(s.MemoryStore).StoreRoutesAndReducers(context.Background(),
+			&registration.PluginConfiguration{
+				PluginName: "users",
+				Reducers:   []string{"users"},
+				Routes: []registration.Route{
+					{Href: "accounts", ComponentName: "UsersAccounts", Type: "content-route", Routes: []registration.SubRoute{}},
+				},
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return s.MemoryStore.Txn(operations)
+}
+
+func TestRegister_ConcurrentConflict(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	kv := &concurrentRegistrationStore{MemoryStore: store.NewMemoryStore()}
+	SetDefaultStore(kv)
+	defer SetDefaultStore(nil)
+
+	// The registration is checked again against the plugin registered meanwhile
+	err := Register(fullRegistration(sources))
+	require.NotNil(t, err)
+	require.Equal(t, "The route \"/accounts\" of the plugin \"accounts\" is already provided by the plugin \"users\"", err.Error())
+	require.Equal(t, int32(1), atomic.LoadInt32(&kv.txns))
+
+	_, err = os.Stat(webModulesDir + "accounts")
+	require.True(t, os.IsNotExist(err))
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configurations))
+	require.Equal(t, "users", configurations[0].PluginName)
+	require.Equal(t, int32(0), atomic.LoadInt32(&builder.builds))
+}
+
+func TestRegister_TranslationsRollback(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+	kv := failingTxnStore{store.NewMemoryStore()}
+	SetDefaultStore(kv)
+	defer SetDefaultStore(nil)
+
+	config := fullRegistration(sources)
+	config.Translations = map[string]translations.Translations{"fr": {"app": map[string]interface{}{"title": "Comptes"}}}
+	require.NotNil(t, Register(config))
+
+	// The translations are not stored either
+	pairs, err := kv.List("")
+	require.Nil(t, err)
+	require.Empty(t, pairs)
+}
+
+func TestRegister_TooManyOperations(t *testing.T) {
+	sources, cleanup := setUpDirectories(t)
+	defer cleanup()
+	builder := newFakeAppBuilder(http.StatusOK)
+	defer builder.server.Close()
+
+	config := fullRegistration(sources)
+	for i := len(config.Resources); i <= store.MaxConsulTxnOperations-4; i++ {
+		config.Resources = append(config.Resources, resource.Resource{Key: fmt.Sprint("resource", i)})
+	}
+	err := Register(config)
+	require.NotNil(t, err)
+	require.Equal(t, "Unable to apply 65 operations in a transaction: Consul accepts 64 operations at most", err.Error())
+
+	// Nothing is written
+	_, err = os.Stat(webModulesDir + "accounts")
+	require.True(t, os.IsNotExist(err))
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Empty(t, configurations)
+	require.Equal(t, int32(0), atomic.LoadInt32(&builder.builds))
+}
@@ -1,73 +1,151 @@
 package registration
 
 import (
+	"context"
 	"encoding/json"
 	"log"
+	"sort"
 
-	"github.com/hashicorp/consul/api"
-	"net/http"
-	"errors"
-	"strconv"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
 )
 
 const consulPrefix = "agilestack/registration/"
 
-var (
-	consulAddress = "consul.agilestacknet:8500"
-	appBuilderAddress = "http://agilestack-root-app-builder:8080"
-)
+// Change the address of Consul used by the package-level functions.
+//
+// Deprecated: use NewStore and SetDefaultStore instead.
+func SetConsulAddress(consulAddress string) {
+	config, err := store.NewConsulConfig(store.WithAddress(consulAddress))
+	if err != nil {
+		log.Println("Invalid Consul configuration:", err)
+		return
+	}
+	s, err := NewStore(config)
+	if err != nil {
+		log.Println("Unable to create the Consul client:", err)
+		return
+	}
+	SetDefaultStore(s)
+}
 
-// Change the address of Consul.
-// Default is "consul.agilestacknet:8500"
-func SetConsulAddress(consultAddress string) {
-	consulAddress = consultAddress
+// ConfigurationStore stores the routes and the reducers of the plugins in a
+// key/value store.
+type ConfigurationStore struct {
+	kv store.KVStore
 }
 
-// Change the address of the application builder.
-// Default is "http://agilestack-root-app-builder:8080".
-func SetAppBuilderAddress(address string) {
-	appBuilderAddress = address
+// Returns a configuration store backed by the given key/value store.
+func NewConfigurationStore(kv store.KVStore) *ConfigurationStore {
+	return &ConfigurationStore{kv: kv}
 }
 
-// Stores the routes and the reducers into Consul store.
-func StoreRoutesAndReducers(config *PluginConfiguration) error {
-	client, err := store()
-	if err != nil {
+// Returns the key/value store backing the configuration store.
+func (s *ConfigurationStore) KV() store.KVStore {
+	return s.kv
+}
+
+/*
+Stores the routes and the reducers, for the tenant of the context.
+
+Fails when the platform version is lower than the one required by the plugin
+(see PlatformVersion), with a *DowngradeError when the installed configuration
+of the plugin has a greater version, when the dependencies of the plugin are
+not satisfied (see CheckDependencies), and with a *ConflictError when the
+routes, the reducers or the components conflict with the ones of the other
+plugins (see CheckConflicts).
+*/
+func (s *ConfigurationStore) StoreRoutesAndReducers(ctx context.Context, config *PluginConfiguration) error {
+	return s.storeRoutesAndReducers(ctx, config, false)
+}
+
+// Stores the routes and the reducers for the tenant of the context, even when
+// it downgrades the plugin (see StoreRoutesAndReducers).
+func (s *ConfigurationStore) ForceStoreRoutesAndReducers(ctx context.Context, config *PluginConfiguration) error {
+	return s.storeRoutesAndReducers(ctx, config, true)
+}
+
+// The configuration is checked again when the configurations are changed
+// concurrently.
+func (s *ConfigurationStore) storeRoutesAndReducers(ctx context.Context, config *PluginConfiguration, force bool) error {
+	if err := Validate(config); err != nil {
 		return err
 	}
 
-	err = Validate(config)
+	var err error
+	for attempt := 0; attempt < maxStoreAttempts; attempt++ {
+		var installed *Installed
+		if installed, err = s.Installed(ctx); err != nil {
+			return err
+		}
+		if err = installed.Check(config, force); err != nil {
+			return err
+		}
+		var operations []store.Operation
+		if operations, err = installed.StoreOperations(ctx, config); err != nil {
+			return err
+		}
+		err = store.Apply(s.kv, operations)
+		if _, conflict := err.(*store.ConflictError); !conflict {
+			break
+		}
+	}
 	if err != nil {
-		return err
+		log.Println("Error while storing the plugin configuration:", err)
 	}
+	return err
+}
 
-	kv := client.KV()
-
-	bytes, err := json.Marshal(config)
+// Checks the platform version required by the plugin and, unless forced, that
+// the configuration does not downgrade the installed one.
+func (s *ConfigurationStore) CheckVersions(ctx context.Context, config *PluginConfiguration, force bool) error {
+	if err := CheckPlatformVersion(config, PlatformVersion()); err != nil {
+		return err
+	}
+	if force {
+		return nil
+	}
+	installed, err := s.GetRoutesAndReducers(ctx, config.PluginName)
 	if err != nil {
-		log.Println("Error while marshalling the plugin configuration:", err)
 		return err
 	}
+	return CheckUpgrade(installed, config)
+}
 
-	pair := &api.KVPair{
-		Key:   consulPrefix + config.PluginName,
-		Value: bytes,
+// Checks the dependencies of the configuration against the plugins of the
+// tenant of the context (see CheckDependencies).
+func (s *ConfigurationStore) CheckDependencies(ctx context.Context, config *PluginConfiguration) error {
+	installed, err := s.ListRoutesAndReducers(ctx)
+	if err != nil {
+		return err
 	}
-
-	_, err = kv.Put(pair, &api.WriteOptions{})
-	return err
+	return CheckDependencies(config, installed)
 }
 
-// Loads all the plugins configuration from Consul store.
-func ListRoutesAndReducers() ([]PluginConfiguration, error) {
-	client, err := store()
+// Checks that the configuration does not conflict with the ones of the other
+// plugins of the tenant of the context (see CheckConflicts).
+func (s *ConfigurationStore) CheckConflicts(ctx context.Context, config *PluginConfiguration) error {
+	installed, err := s.ListRoutesAndReducers(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return CheckConflicts(config, installed)
+}
 
-	pairs, _, err := client.KV().List(consulPrefix, nil)
+// Returns the operation storing the routes and the reducers for the tenant of
+// the context, to apply with other operations (see store.Apply).
+func StoreOperation(ctx context.Context, config *PluginConfiguration) (store.Operation, error) {
+	if err := Validate(config); err != nil {
+		return store.Operation{}, err
+	}
+	return store.SetJSON(tenant.Key(ctx, consulPrefix+config.PluginName), config)
+}
+
+// Loads all the plugins configuration of the tenant of the context.
+func (s *ConfigurationStore) ListRoutesAndReducers(ctx context.Context) ([]PluginConfiguration, error) {
+	pairs, err := s.kv.List(tenant.Key(ctx, consulPrefix))
 	if err != nil {
-		log.Println("Error while listing plugins configurations in Consul:", err)
+		log.Println("Error while listing plugins configurations:", err)
 		return nil, err
 	}
 
@@ -84,32 +162,202 @@ func ListRoutesAndReducers() ([]PluginConfiguration, error) {
 	return configurations, nil
 }
 
+// Loads the configuration of the plugin, for the tenant of the context.
+// Returns nil if there is none.
+func (s *ConfigurationStore) GetRoutesAndReducers(ctx context.Context, pluginName string) (*PluginConfiguration, error) {
+	config := &PluginConfiguration{}
+	found, err := store.GetJSON(s.kv, tenant.Key(ctx, consulPrefix+pluginName), config)
+	if err != nil {
+		log.Println("Error while loading the plugin configuration:", err)
+		return nil, err
+	}
+	if !found {
+		return nil, nil
+	}
+	return config, nil
+}
+
+// Lists the versions of the plugins of the tenant of the context, sorted by
+// plugin name.
+func (s *ConfigurationStore) ListPluginVersions(ctx context.Context) ([]PluginVersion, error) {
+	configurations, err := s.ListRoutesAndReducers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]PluginVersion, len(configurations))
+	for i, config := range configurations {
+		versions[i] = PluginVersion{
+			PluginName:      config.PluginName,
+			Version:         config.Version,
+			PlatformVersion: config.PlatformVersion,
+		}
+	}
+	sort.Sort(byPluginName(versions))
+	return versions, nil
+}
+
+// Deletes all the routes and the reducers of the given plugin, for the tenant
+// of the context.
+func (s *ConfigurationStore) DeleteRoutesAndReducers(ctx context.Context, pluginName string) error {
+	var err error
+	for attempt := 0; attempt < maxStoreAttempts; attempt++ {
+		var installed *Installed
+		if installed, err = s.Installed(ctx); err != nil {
+			return err
+		}
+		err = store.Apply(s.kv, installed.DeleteOperations(ctx, pluginName))
+		if _, conflict := err.(*store.ConflictError); !conflict {
+			return err
+		}
+	}
+	return err
+}
+
+// A change of the configuration of a plugin.
+type ConfigurationEvent struct {
+	Type store.EventType
+
+	// The configuration after the change, or the last known configuration
+	// when it is removed.
+	Configuration PluginConfiguration
+}
+
+// Watches the plugins configurations of the tenant of the context until the
+// context is done, then closes the returned channel. The configurations
+// existing when the watch starts are notified as added.
+func (s *ConfigurationStore) Watch(ctx context.Context) <-chan ConfigurationEvent {
+	pairs := store.Watch(ctx, s.kv, tenant.Key(ctx, consulPrefix))
+	events := make(chan ConfigurationEvent)
+	go func() {
+		defer close(events)
+		for event := range pairs {
+			config := PluginConfiguration{}
+			if err := json.Unmarshal(event.Pair.Value, &config); err != nil {
+				log.Printf("Error while unmarshalling the configuration '%s': %v", event.Pair.Key, err)
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case events <- ConfigurationEvent{Type: event.Type, Configuration: config}:
+			}
+		}
+	}()
+	return events
+}
+
+// Stores the routes and the reducers into Consul store.
+func StoreRoutesAndReducers(config *PluginConfiguration) error {
+	return StoreRoutesAndReducersContext(context.Background(), config)
+}
+
+// Stores the routes and the reducers into Consul store, for the tenant of the context.
+func StoreRoutesAndReducersContext(ctx context.Context, config *PluginConfiguration) error {
+	s, err := DefaultStore()
+	if err != nil {
+		return err
+	}
+	return s.StoreRoutesAndReducers(ctx, config)
+}
+
+// Stores the routes and the reducers into Consul store, even when it
+// downgrades the plugin.
+func ForceStoreRoutesAndReducers(config *PluginConfiguration) error {
+	return ForceStoreRoutesAndReducersContext(context.Background(), config)
+}
+
+// Stores the routes and the reducers into Consul store for the tenant of the
+// context, even when it downgrades the plugin.
+func ForceStoreRoutesAndReducersContext(ctx context.Context, config *PluginConfiguration) error {
+	s, err := DefaultStore()
+	if err != nil {
+		return err
+	}
+	return s.ForceStoreRoutesAndReducers(ctx, config)
+}
+
+// Loads all the plugins configuration from Consul store.
+func ListRoutesAndReducers() ([]PluginConfiguration, error) {
+	return ListRoutesAndReducersContext(context.Background())
+}
+
+// Loads all the plugins configuration of the tenant of the context from Consul store.
+func ListRoutesAndReducersContext(ctx context.Context) ([]PluginConfiguration, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.ListRoutesAndReducers(ctx)
+}
+
+// Lists the versions of the plugins, sorted by plugin name.
+func ListPluginVersions() ([]PluginVersion, error) {
+	return ListPluginVersionsContext(context.Background())
+}
+
+// Lists the versions of the plugins of the tenant of the context, sorted by
+// plugin name.
+func ListPluginVersionsContext(ctx context.Context) ([]PluginVersion, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.ListPluginVersions(ctx)
+}
+
 // Deletes from Consul store all the routes and the reducers of the given plugin.
 func DeleteRoutesAndReducers(pluginName string) error {
-	client, err := store()
+	return DeleteRoutesAndReducersContext(context.Background(), pluginName)
+}
+
+// Deletes from Consul store all the routes and the reducers of the given plugin,
+// for the tenant of the context.
+func DeleteRoutesAndReducersContext(ctx context.Context, pluginName string) error {
+	s, err := DefaultStore()
 	if err != nil {
 		return err
 	}
+	return s.DeleteRoutesAndReducers(ctx, pluginName)
+}
 
-	kv := client.KV()
-	_, err = kv.Delete(consulPrefix + pluginName, nil)
-	return err
+// Watches the plugins configurations of the tenant of the context until the
+// context is done.
+func Watch(ctx context.Context) (<-chan ConfigurationEvent, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.Watch(ctx), nil
 }
 
-// Launches the application build.
-func LaunchApplicationBuild() error {
-	response, err := http.Post(appBuilderAddress + "/plugins", "application/json", nil)
+// Returns a configuration store backed by the Consul matching the configuration.
+func NewStore(config store.ConsulConfig) (*ConfigurationStore, error) {
+	kv, err := store.NewConsulStoreFromConfig(config)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if response.StatusCode != 200 {
-		return errors.New("Invalid status code: " + strconv.Itoa(response.StatusCode))
+	return NewConfigurationStore(kv), nil
+}
+
+// The key/value store of the package-level functions.
+var defaultKV store.Override
+
+// Returns the store used by the package-level functions, backed by the default
+// key/value store unless another one is set (see store.Override).
+func DefaultStore() (*ConfigurationStore, error) {
+	kv, err := defaultKV.Store()
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return NewConfigurationStore(kv), nil
 }
 
-func store() (*api.Client, error) {
-	config := api.DefaultConfig()
-	config.Address = consulAddress
-	return api.NewClient(config)
+// Changes the store used by the package-level functions. A nil store restores
+// the default one.
+func SetDefaultStore(s *ConfigurationStore) {
+	if s == nil {
+		defaultKV.Set(nil)
+		return
+	}
+	defaultKV.Set(s.kv)
 }
@@ -1,8 +1,11 @@
 package registration_test
 
 import (
+	"context"
 	"testing"
 	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
 	"encoding/json"
 	"github.com/stretchr/testify/require"
 	"net/http"
@@ -161,6 +164,29 @@ func TestDeleteRoutesAndReducers(t *testing.T) {
 	validateConfig(t, &config1, &configurations[0])
 }
 
+func TestListRoutesAndReducers_Tenants(t *testing.T) {
+	deleteAll(t)
+	acme := tenant.NewContext(context.Background(), "acme")
+
+	require.Nil(t, registration.StoreRoutesAndReducers(&config1))
+	require.Nil(t, registration.StoreRoutesAndReducersContext(acme, &config2))
+
+	configurations, err := registration.ListRoutesAndReducers()
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configurations))
+	validateConfig(t, &config1, &configurations[0])
+
+	configurations, err = registration.ListRoutesAndReducersContext(acme)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configurations))
+	validateConfig(t, &config2, &configurations[0])
+
+	require.Nil(t, registration.DeleteRoutesAndReducersContext(acme, config2.PluginName))
+	configurations, err = registration.ListRoutesAndReducersContext(acme)
+	require.Nil(t, err)
+	require.Equal(t, 0, len(configurations))
+}
+
 func TestLaunchApplicationBuild(t *testing.T) {
 	mux := http.NewServeMux()
 	server := httptest.NewServer(mux)
@@ -182,15 +208,139 @@ func TestLaunchApplicationBuild_Error(t *testing.T) {
 	mux := http.NewServeMux()
 	server := httptest.NewServer(mux)
 	defer server.Close()
-	registration.SetAppBuilderAddress(server.URL)
 
 	mux.HandleFunc("/plugins", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
 	})
 
-	err := registration.LaunchApplicationBuild()
+	err := registration.NewAppBuilder(server.URL, nil).LaunchApplicationBuild()
 	require.NotNil(t, err)
 	require.Equal(t, "Invalid status code: 500", err.Error())
 
 
-}
\ No newline at end of file
+}
+
+func TestConfigurationStore_MemoryStore(t *testing.T) {
+	configurationStore := registration.NewConfigurationStore(store.NewMemoryStore())
+	ctx := context.Background()
+
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &config1))
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &config3))
+
+	configs, err := configurationStore.ListRoutesAndReducers(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(configs))
+	validateConfig(t, &config1, &configs[0])
+	validateConfig(t, &config3, &configs[1])
+
+	require.Nil(t, configurationStore.DeleteRoutesAndReducers(ctx, config1.PluginName))
+	configs, err = configurationStore.ListRoutesAndReducers(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configs))
+	validateConfig(t, &config3, &configs[0])
+}
+
+func TestConfigurationStore_Watch(t *testing.T) {
+	configurationStore := registration.NewConfigurationStore(store.NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := configurationStore.Watch(ctx)
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &config1))
+	event := <-events
+	require.Equal(t, store.Added, event.Type)
+	require.Equal(t, config1, event.Configuration)
+
+	updated := config1
+	updated.Reducers = []string{"newReducer"}
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &updated))
+	event = <-events
+	require.Equal(t, store.Updated, event.Type)
+	require.Equal(t, updated, event.Configuration)
+
+	require.Nil(t, configurationStore.DeleteRoutesAndReducers(ctx, config1.PluginName))
+	event = <-events
+	require.Equal(t, store.Removed, event.Type)
+	require.Equal(t, config1.PluginName, event.Configuration.PluginName)
+}
+
+func TestStoreOperation(t *testing.T) {
+	kv := store.NewMemoryStore()
+	configurationStore := registration.NewConfigurationStore(kv)
+	acme := tenant.NewContext(context.Background(), "acme")
+
+	operation, err := registration.StoreOperation(acme, &config1)
+	require.Nil(t, err)
+	require.Nil(t, store.Apply(kv, []store.Operation{operation}))
+
+	configs, err := configurationStore.ListRoutesAndReducers(acme)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configs))
+	validateConfig(t, &config1, &configs[0])
+
+	_, err = registration.StoreOperation(acme, &registration.PluginConfiguration{PluginName: " "})
+	require.NotNil(t, err)
+	require.Equal(t, "Plugin name must not be blank", err.Error())
+}
+
+func versionedConfig(name, version, platformVersion string) *registration.PluginConfiguration {
+	return &registration.PluginConfiguration{
+		PluginName:      name,
+		Reducers:        []string{},
+		Routes:          []registration.Route{},
+		Version:         version,
+		PlatformVersion: platformVersion,
+	}
+}
+
+func TestConfigurationStore_Downgrade(t *testing.T) {
+	configurationStore := registration.NewConfigurationStore(store.NewMemoryStore())
+	ctx := context.Background()
+
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, versionedConfig("accounts", "1.2.0", "")))
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, versionedConfig("accounts", "1.3.0", "")))
+
+	err := configurationStore.StoreRoutesAndReducers(ctx, versionedConfig("accounts", "1.2.0", ""))
+	require.NotNil(t, err)
+	require.Equal(t, "Unable to downgrade the plugin \"accounts\" from 1.3.0 to 1.2.0", err.Error())
+	installed, err := configurationStore.GetRoutesAndReducers(ctx, "accounts")
+	require.Nil(t, err)
+	require.Equal(t, "1.3.0", installed.Version)
+
+	require.Nil(t, configurationStore.ForceStoreRoutesAndReducers(ctx, versionedConfig("accounts", "1.2.0", "")))
+	installed, err = configurationStore.GetRoutesAndReducers(ctx, "accounts")
+	require.Nil(t, err)
+	require.Equal(t, "1.2.0", installed.Version)
+
+	installed, err = configurationStore.GetRoutesAndReducers(ctx, "unknown")
+	require.Nil(t, err)
+	require.Nil(t, installed)
+}
+
+func TestConfigurationStore_PlatformVersion(t *testing.T) {
+	configurationStore := registration.NewConfigurationStore(store.NewMemoryStore())
+	ctx := context.Background()
+	registration.SetPlatformVersion("1.4.0")
+	defer registration.SetPlatformVersion("")
+
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, versionedConfig("accounts", "1.0.0", "1.4.0")))
+	err := configurationStore.ForceStoreRoutesAndReducers(ctx, versionedConfig("accounts", "1.1.0", "1.5.0"))
+	require.NotNil(t, err)
+	require.Equal(t, "The plugin \"accounts\" requires the platform version 1.5.0, not 1.4.0", err.Error())
+}
+
+func TestListPluginVersions(t *testing.T) {
+	deleteAll(t)
+
+	require.Nil(t, registration.StoreRoutesAndReducers(versionedConfig("users", "2.0.0", "1.4.0")))
+	require.Nil(t, registration.StoreRoutesAndReducers(versionedConfig("accounts", "1.2.0", "")))
+	require.Nil(t, registration.StoreRoutesAndReducers(&config2))
+
+	versions, err := registration.ListPluginVersions()
+	require.Nil(t, err)
+	require.Equal(t, []registration.PluginVersion{
+		{PluginName: config2.PluginName},
+		{PluginName: "accounts", Version: "1.2.0"},
+		{PluginName: "users", Version: "2.0.0", PlatformVersion: "1.4.0"},
+	}, versions)
+}
@@ -0,0 +1,75 @@
+package registration
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// The address of the application builder in the agilestack network.
+const DefaultAppBuilderAddress = "http://agilestack-root-app-builder:8080"
+
+// AppBuilder is a client of the application builder.
+type AppBuilder struct {
+	address    string
+	httpClient *http.Client
+}
+
+// Returns a client of the application builder listening to the given address.
+// The default HTTP client is used if httpClient is nil.
+func NewAppBuilder(address string, httpClient *http.Client) *AppBuilder {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &AppBuilder{address: address, httpClient: httpClient}
+}
+
+// Launches the application build.
+func (b *AppBuilder) LaunchApplicationBuild() error {
+	response, err := b.httpClient.Post(b.address+"/plugins", "application/json", nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode != 200 {
+		return errors.New("Invalid status code: " + strconv.Itoa(response.StatusCode))
+	}
+	return nil
+}
+
+var (
+	defaultAppBuilderMutex sync.RWMutex
+	defaultAppBuilder      = NewAppBuilder(DefaultAppBuilderAddress, nil)
+)
+
+// Returns the application builder used by the package-level functions.
+func DefaultAppBuilder() *AppBuilder {
+	defaultAppBuilderMutex.RLock()
+	defer defaultAppBuilderMutex.RUnlock()
+	return defaultAppBuilder
+}
+
+// Changes the application builder used by the package-level functions.
+func SetDefaultAppBuilder(b *AppBuilder) {
+	defaultAppBuilderMutex.Lock()
+	defer defaultAppBuilderMutex.Unlock()
+	defaultAppBuilder = b
+}
+
+// Change the address of the application builder.
+// Default is "http://agilestack-root-app-builder:8080".
+//
+// Deprecated: use NewAppBuilder and SetDefaultAppBuilder instead.
+func SetAppBuilderAddress(address string) {
+	SetDefaultAppBuilder(NewAppBuilder(address, nil))
+}
+
+// Launches the application build.
+func LaunchApplicationBuild() error {
+	return DefaultAppBuilder().LaunchApplicationBuild()
+}
@@ -0,0 +1,207 @@
+package registration
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The kinds of elements two plugins may both provide.
+const (
+	RouteConflict     = "route"
+	ReducerConflict   = "reducer"
+	ComponentConflict = "component"
+)
+
+// An element provided by two plugins, which would replace each other in the
+// application.
+type Conflict struct {
+	// RouteConflict, ReducerConflict or ComponentConflict
+	Kind string
+
+	// The full path of the route, or the name of the reducer or component
+	Name string
+
+	PluginName      string
+	OtherPluginName string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("The %s \"%s\" of the plugin \"%s\" is already provided by the plugin \"%s\"",
+		c.Kind, c.Name, c.PluginName, c.OtherPluginName)
+}
+
+// The error of a configuration conflicting with the ones of other plugins.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	conflicts := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		conflicts[i] = conflict.String()
+	}
+	return strings.Join(conflicts, ", ")
+}
+
+/*
+Checks that the configuration does not provide the routes, the reducers or the
+components of the routes provided by the other installed plugins. The routes are
+compared by full path, the paths of the sub-routes being resolved against the
+paths of their parents, and the names of the parameters (":id") are ignored.
+The reducers and the components of the plugins the configuration depends on
+(see PluginConfiguration.Dependencies) are not conflicts, as the plugin may
+reference them, but their routes are. Returns a *ConflictError listing the
+conflicts.
+*/
+func CheckConflicts(config *PluginConfiguration, installed []PluginConfiguration) error {
+	others := append([]PluginConfiguration{}, installed...)
+	sort.Sort(byName(others))
+	dependencies := map[string]bool{}
+	for _, dependency := range config.Dependencies {
+		dependencies[dependency.PluginName] = true
+	}
+
+	routes := map[string]string{}
+	reducers := map[string]string{}
+	components := map[string]string{}
+	for _, other := range others {
+		if other.PluginName == config.PluginName {
+			continue
+		}
+		for _, path := range routePaths(other.Routes) {
+			setOnce(routes, routeKey(path), other.PluginName)
+		}
+		if dependencies[other.PluginName] {
+			continue
+		}
+		for _, reducer := range other.Reducers {
+			setOnce(reducers, reducer, other.PluginName)
+		}
+		for _, component := range componentNames(other.Routes) {
+			setOnce(components, component, other.PluginName)
+		}
+	}
+
+	conflictError := &ConflictError{}
+	conflict := func(kind, name, otherPluginName string) {
+		conflictError.Conflicts = append(conflictError.Conflicts, Conflict{
+			Kind:            kind,
+			Name:            name,
+			PluginName:      config.PluginName,
+			OtherPluginName: otherPluginName,
+		})
+	}
+	for _, path := range routePaths(config.Routes) {
+		if other, found := routes[routeKey(path)]; found {
+			conflict(RouteConflict, path, other)
+		}
+	}
+	for _, reducer := range distinct(config.Reducers) {
+		if other, found := reducers[reducer]; found {
+			conflict(ReducerConflict, reducer, other)
+		}
+	}
+	for _, component := range componentNames(config.Routes) {
+		if other, found := components[component]; found {
+			conflict(ComponentConflict, component, other)
+		}
+	}
+
+	if len(conflictError.Conflicts) > 0 {
+		return conflictError
+	}
+	return nil
+}
+
+func setOnce(values map[string]string, key, value string) {
+	if _, found := values[key]; !found {
+		values[key] = value
+	}
+}
+
+// Returns the distinct full paths of the routes and their sub-routes, in
+// order. The routes without path are left out, except the index routes.
+func routePaths(routes []Route) []string {
+	paths := []string{}
+	for _, route := range routes {
+		if route.IsIndex || route.Href != "" {
+			paths = append(paths, joinPath("/", route.Href))
+		}
+		paths = appendSubRoutePaths(paths, joinPath("/", route.Href), route.Routes)
+	}
+	return distinct(paths)
+}
+
+func appendSubRoutePaths(paths []string, parent string, subRoutes []SubRoute) []string {
+	for _, subRoute := range subRoutes {
+		path := joinPath(parent, subRoute.Href)
+		if subRoute.Href != "" {
+			paths = append(paths, path)
+		}
+		paths = appendSubRoutePaths(paths, path, subRoute.Routes)
+	}
+	return paths
+}
+
+// Resolves the path against the parent path: an absolute path replaces it.
+func joinPath(parent, path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = parent + "/" + path
+	}
+	segments := []string{}
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// Returns the path with the same key for all the parameter names.
+func routeKey(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = ":"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Returns the distinct component names of the routes and their sub-routes, in
+// order.
+func componentNames(routes []Route) []string {
+	names := []string{}
+	for _, route := range routes {
+		names = append(names, route.ComponentName)
+		names = appendSubRouteComponents(names, route.Routes)
+	}
+	return distinct(names)
+}
+
+func appendSubRouteComponents(names []string, subRoutes []SubRoute) []string {
+	for _, subRoute := range subRoutes {
+		names = append(names, subRoute.ComponentName)
+		names = appendSubRouteComponents(names, subRoute.Routes)
+	}
+	return names
+}
+
+func distinct(values []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+type byName []PluginConfiguration
+
+func (c byName) Len() int           { return len(c) }
+func (c byName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byName) Less(i, j int) bool { return c[i].PluginName < c[j].PluginName }
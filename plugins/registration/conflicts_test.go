@@ -0,0 +1,142 @@
+package registration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	accountsConfig = registration.PluginConfiguration{
+		PluginName: "accounts",
+		Reducers:   []string{"accounts", "session"},
+		Routes: []registration.Route{
+			{Href: "admin", ComponentName: "Admin", Type: "content-route", Routes: []registration.SubRoute{
+				{Href: "accounts/:id", ComponentName: "Account", Routes: []registration.SubRoute{}},
+				{ComponentName: "AccountsLayout", Routes: []registration.SubRoute{
+					{Href: "accounts", ComponentName: "AccountsList", Routes: []registration.SubRoute{}},
+				}},
+			}},
+		},
+	}
+
+	usersConfig = registration.PluginConfiguration{
+		PluginName: "users",
+		Reducers:   []string{"users", "session"},
+		Routes: []registration.Route{
+			{Href: "/admin/users", ComponentName: "UsersList", Type: "content-route", Routes: []registration.SubRoute{
+				{Href: "/admin/accounts//:user_id/", ComponentName: "Account", Routes: []registration.SubRoute{}},
+				{Href: "accounts", ComponentName: "UserAccounts", Routes: []registration.SubRoute{}},
+			}},
+		},
+	}
+)
+
+func TestCheckConflicts(t *testing.T) {
+	err := registration.CheckConflicts(&usersConfig, []registration.PluginConfiguration{accountsConfig, usersConfig})
+	require.NotNil(t, err)
+	conflictError, ok := err.(*registration.ConflictError)
+	require.True(t, ok)
+	require.Equal(t, []registration.Conflict{
+		{Kind: registration.RouteConflict, Name: "/admin/accounts/:user_id", PluginName: "users", OtherPluginName: "accounts"},
+		{Kind: registration.ReducerConflict, Name: "session", PluginName: "users", OtherPluginName: "accounts"},
+		{Kind: registration.ComponentConflict, Name: "Account", PluginName: "users", OtherPluginName: "accounts"},
+	}, conflictError.Conflicts)
+	require.Equal(t, "The route \"/admin/accounts/:user_id\" of the plugin \"users\" is already provided by the plugin \"accounts\", "+
+		"The reducer \"session\" of the plugin \"users\" is already provided by the plugin \"accounts\", "+
+		"The component \"Account\" of the plugin \"users\" is already provided by the plugin \"accounts\"", err.Error())
+}
+
+func TestCheckConflicts_NestedPaths(t *testing.T) {
+	other := registration.PluginConfiguration{
+		PluginName: "lists",
+		Reducers:   []string{},
+		Routes: []registration.Route{
+			{Href: "/admin/accounts", ComponentName: "Lists", Type: "content-route", Routes: []registration.SubRoute{}},
+			{IsIndex: true, ComponentName: "Home", Type: "content-route", Routes: []registration.SubRoute{}},
+		},
+	}
+	err := registration.CheckConflicts(&accountsConfig, []registration.PluginConfiguration{other})
+	require.NotNil(t, err)
+	require.Equal(t, "The route \"/admin/accounts\" of the plugin \"accounts\" is already provided by the plugin \"lists\"", err.Error())
+
+	index := registration.PluginConfiguration{
+		PluginName: "dashboard",
+		Reducers:   []string{},
+		Routes: []registration.Route{
+			{IsIndex: true, ComponentName: "Dashboard", Type: "content-route", Routes: []registration.SubRoute{}},
+		},
+	}
+	err = registration.CheckConflicts(&index, []registration.PluginConfiguration{other})
+	require.NotNil(t, err)
+	require.Equal(t, "The route \"/\" of the plugin \"dashboard\" is already provided by the plugin \"lists\"", err.Error())
+}
+
+func TestCheckConflicts_SamePlugin(t *testing.T) {
+	// The installed configuration of the plugin is replaced
+	require.Nil(t, registration.CheckConflicts(&accountsConfig, []registration.PluginConfiguration{accountsConfig}))
+	require.Nil(t, registration.CheckConflicts(&config1, []registration.PluginConfiguration{config2, config3}))
+}
+
+func TestCheckConflicts_Dependencies(t *testing.T) {
+	// The users plugin references the reducer and the component of the
+	// accounts plugin it depends on
+	dependent := usersConfig
+	dependent.Dependencies = []registration.Dependency{{PluginName: "accounts"}}
+	dependent.Routes = []registration.Route{
+		{Href: "/admin/users", ComponentName: "UsersList", Type: "content-route", Routes: []registration.SubRoute{
+			{Href: ":user_id/accounts", ComponentName: "Account", Routes: []registration.SubRoute{}},
+		}},
+	}
+	require.Nil(t, registration.CheckConflicts(&dependent, []registration.PluginConfiguration{accountsConfig}))
+
+	// The routes of the dependencies still conflict
+	dependent.Routes = usersConfig.Routes
+	err := registration.CheckConflicts(&dependent, []registration.PluginConfiguration{accountsConfig})
+	require.NotNil(t, err)
+	require.Equal(t, "The route \"/admin/accounts/:user_id\" of the plugin \"users\" is already provided by the plugin \"accounts\"", err.Error())
+}
+
+func TestConfigurationStore_Conflicts(t *testing.T) {
+	configurationStore := registration.NewConfigurationStore(store.NewMemoryStore())
+	ctx := context.Background()
+
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &accountsConfig))
+	err := configurationStore.StoreRoutesAndReducers(ctx, &usersConfig)
+	require.NotNil(t, err)
+	_, ok := err.(*registration.ConflictError)
+	require.True(t, ok)
+
+	configs, err := configurationStore.ListRoutesAndReducers(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(configs))
+	require.Equal(t, "accounts", configs[0].PluginName)
+}
+
+func TestInstalled_ConcurrentChange(t *testing.T) {
+	configurationStore := registration.NewConfigurationStore(store.NewMemoryStore())
+	ctx := context.Background()
+
+	installed, err := configurationStore.Installed(ctx)
+	require.Nil(t, err)
+	require.Nil(t, installed.Check(&usersConfig, false))
+
+	// Another plugin is stored meanwhile
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &accountsConfig))
+	operations, err := installed.StoreOperations(ctx, &usersConfig)
+	require.Nil(t, err)
+	err = store.Apply(configurationStore.KV(), operations)
+	require.NotNil(t, err)
+	_, ok := err.(*store.ConflictError)
+	require.True(t, ok)
+
+	// Checked again, the configuration conflicts
+	installed, err = configurationStore.Installed(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(installed.Configurations))
+	_, ok = installed.Check(&usersConfig, false).(*registration.ConflictError)
+	require.True(t, ok)
+}
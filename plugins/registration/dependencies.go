@@ -0,0 +1,194 @@
+package registration
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A plugin another plugin depends on.
+type Dependency struct {
+	PluginName string `json:"pluginName"`
+
+	// The minimal version of the plugin, such as "1.2.0". The compatible
+	// versions are the greater ones of the same major version (of the same
+	// minor version for the 0.x versions). Any version is compatible when it
+	// is empty.
+	Version string `json:"version,omitempty"`
+}
+
+// Returns whether the installed version satisfies the required version of a
+// dependency (see Dependency.Version).
+func Compatible(required, installed Version) bool {
+	if installed.Major != required.Major || installed.Compare(required) < 0 {
+		return false
+	}
+	return required.Major > 0 || installed.Minor == required.Minor
+}
+
+func validateDependencies(config *PluginConfiguration) error {
+	for _, dependency := range config.Dependencies {
+		if strings.TrimSpace(dependency.PluginName) == "" {
+			return errors.New("The name of a dependency must not be blank")
+		}
+		if dependency.PluginName == config.PluginName {
+			return errors.New("A plugin cannot depend on itself")
+		}
+		if dependency.Version != "" {
+			if _, err := ParseVersion(dependency.Version); err != nil {
+				return fmt.Errorf("Invalid version of the dependency \"%s\": \"%s\"", dependency.PluginName, dependency.Version)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Checks the dependencies of the configuration against the installed plugins,
+the configuration replacing the installed one of the plugin: the dependencies
+of the plugin must be installed at compatible versions, its version must be
+compatible with the plugins depending on it, and the dependencies must not form
+a cycle. The problems are listed in the returned error.
+*/
+func CheckDependencies(config *PluginConfiguration, installed []PluginConfiguration) error {
+	configurations := map[string]PluginConfiguration{}
+	for _, other := range installed {
+		configurations[other.PluginName] = other
+	}
+	configurations[config.PluginName] = *config
+
+	problems := []string{}
+	for _, dependency := range config.Dependencies {
+		dependencyConfig, found := configurations[dependency.PluginName]
+		if !found {
+			problems = append(problems, fmt.Sprintf("The plugin \"%s\" depends on the plugin \"%s\", which is not registered",
+				config.PluginName, dependency.PluginName))
+		} else if problem := checkDependencyVersion(config.PluginName, dependency, dependencyConfig); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+	for _, name := range sortedNames(configurations) {
+		for _, dependency := range configurations[name].Dependencies {
+			if name != config.PluginName && dependency.PluginName == config.PluginName {
+				if problem := checkDependencyVersion(name, dependency, *config); problem != "" {
+					problems = append(problems, problem)
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, ", "))
+	}
+
+	all := make([]PluginConfiguration, 0, len(configurations))
+	for _, name := range sortedNames(configurations) {
+		all = append(all, configurations[name])
+	}
+	_, err := InstallOrder(all)
+	return err
+}
+
+// Returns the problem of the version of the dependency, or "" when it is
+// compatible or unknown.
+func checkDependencyVersion(pluginName string, dependency Dependency, dependencyConfig PluginConfiguration) string {
+	if dependency.Version == "" || dependencyConfig.Version == "" {
+		return ""
+	}
+	required, err := ParseVersion(dependency.Version)
+	if err != nil {
+		return err.Error()
+	}
+	version, err := ParseVersion(dependencyConfig.Version)
+	if err != nil {
+		return err.Error()
+	}
+	if !Compatible(required, version) {
+		return fmt.Sprintf("The plugin \"%s\" requires a version of the plugin \"%s\" compatible with %s, not %s",
+			pluginName, dependency.PluginName, dependency.Version, dependencyConfig.Version)
+	}
+	return ""
+}
+
+/*
+Returns the names of the plugins in an order installing the dependencies of a
+plugin before it. The plugins are otherwise sorted by name. The dependencies
+missing from the configurations are ignored. Fails when the dependencies form
+a cycle.
+*/
+func InstallOrder(configurations []PluginConfiguration) ([]string, error) {
+	dependencies := map[string][]string{}
+	for _, config := range configurations {
+		names := []string{}
+		for _, dependency := range config.Dependencies {
+			names = append(names, dependency.PluginName)
+		}
+		sort.Strings(names)
+		dependencies[config.PluginName] = names
+	}
+
+	order := []string{}
+	// The plugins being visited, in the order of the visit, and the visited ones
+	path := []string{}
+	visited := map[string]bool{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		for i, visiting := range path {
+			if visiting == name {
+				cycle := append(append([]string{}, path[i:]...), name)
+				return fmt.Errorf("Dependency cycle between the plugins: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		if visited[name] {
+			return nil
+		}
+		path = append(path, name)
+		for _, dependency := range dependencies[name] {
+			if _, found := dependencies[dependency]; found {
+				if err := visit(dependency); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		visited[name] = true
+		order = append(order, name)
+		return nil
+	}
+
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Returns the names of the plugins depending on the plugin, sorted.
+func Dependents(pluginName string, configurations []PluginConfiguration) []string {
+	dependents := []string{}
+	for _, config := range configurations {
+		for _, dependency := range config.Dependencies {
+			if dependency.PluginName == pluginName && config.PluginName != pluginName {
+				dependents = append(dependents, config.PluginName)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+func sortedNames(configurations map[string]PluginConfiguration) []string {
+	names := make([]string, 0, len(configurations))
+	for name := range configurations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
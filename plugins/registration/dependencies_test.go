@@ -0,0 +1,114 @@
+package registration_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/stretchr/testify/require"
+)
+
+func dependentConfig(name, version string, dependencies ...registration.Dependency) registration.PluginConfiguration {
+	config := versionedConfig(name, version, "")
+	config.Dependencies = dependencies
+	return *config
+}
+
+func TestCompatible(t *testing.T) {
+	cases := []struct {
+		required, installed string
+		compatible          bool
+	}{
+		{"1.2.0", "1.2.0", true}, {"1.2.0", "1.3.5", true}, {"1.2.0", "1.1.9", false}, {"1.2.0", "2.0.0", false},
+		{"1.2.0", "1.2.0-rc.1", false}, {"0.2.0", "0.2.7", true}, {"0.2.0", "0.3.0", false},
+	}
+	for _, c := range cases {
+		required, err := registration.ParseVersion(c.required)
+		require.Nil(t, err)
+		installed, err := registration.ParseVersion(c.installed)
+		require.Nil(t, err)
+		require.Equal(t, c.compatible, registration.Compatible(required, installed), "%s %s", c.required, c.installed)
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	installed := []registration.PluginConfiguration{
+		dependentConfig("users", "2.1.0"),
+		dependentConfig("layout", ""),
+		dependentConfig("reports", "1.0.0", registration.Dependency{PluginName: "accounts", Version: "1.0.0"}),
+	}
+
+	config := dependentConfig("accounts", "1.2.0",
+		registration.Dependency{PluginName: "users", Version: "2.0.0"}, registration.Dependency{PluginName: "layout"})
+	require.Nil(t, registration.CheckDependencies(&config, installed))
+
+	config = dependentConfig("accounts", "2.0.0",
+		registration.Dependency{PluginName: "users", Version: "3.0.0"}, registration.Dependency{PluginName: "mail"})
+	err := registration.CheckDependencies(&config, installed)
+	require.NotNil(t, err)
+	require.Equal(t, "The plugin \"accounts\" requires a version of the plugin \"users\" compatible with 3.0.0, not 2.1.0, "+
+		"The plugin \"accounts\" depends on the plugin \"mail\", which is not registered, "+
+		"The plugin \"reports\" requires a version of the plugin \"accounts\" compatible with 1.0.0, not 2.0.0", err.Error())
+}
+
+func TestCheckDependencies_Cycle(t *testing.T) {
+	installed := []registration.PluginConfiguration{
+		dependentConfig("accounts", "", registration.Dependency{PluginName: "users"}),
+		dependentConfig("reports", "", registration.Dependency{PluginName: "accounts"}),
+		dependentConfig("users", ""),
+	}
+	config := dependentConfig("users", "", registration.Dependency{PluginName: "reports"})
+
+	err := registration.CheckDependencies(&config, installed)
+	require.NotNil(t, err)
+	require.Equal(t, "Dependency cycle between the plugins: accounts -> users -> reports -> accounts", err.Error())
+}
+
+func TestInstallOrder(t *testing.T) {
+	order, err := registration.InstallOrder([]registration.PluginConfiguration{
+		dependentConfig("reports", "", registration.Dependency{PluginName: "accounts"}, registration.Dependency{PluginName: "mail"}),
+		dependentConfig("accounts", "", registration.Dependency{PluginName: "users"}, registration.Dependency{PluginName: "layout"}),
+		dependentConfig("users", "", registration.Dependency{PluginName: "layout"}),
+		dependentConfig("layout", ""),
+		dependentConfig("help", ""),
+	})
+	require.Nil(t, err)
+	require.Equal(t, []string{"layout", "users", "accounts", "help", "reports"}, order)
+
+	_, err = registration.InstallOrder([]registration.PluginConfiguration{
+		dependentConfig("a", "", registration.Dependency{PluginName: "b"}),
+		dependentConfig("b", "", registration.Dependency{PluginName: "a"}),
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Dependency cycle between the plugins: a -> b -> a", err.Error())
+}
+
+func TestDependents(t *testing.T) {
+	configurations := []registration.PluginConfiguration{
+		dependentConfig("reports", "", registration.Dependency{PluginName: "users"}),
+		dependentConfig("accounts", "", registration.Dependency{PluginName: "users"}, registration.Dependency{PluginName: "layout"}),
+		dependentConfig("users", ""),
+	}
+	require.Equal(t, []string{"accounts", "reports"}, registration.Dependents("users", configurations))
+	require.Empty(t, registration.Dependents("reports", configurations))
+}
+
+func TestConfigurationStore_Dependencies(t *testing.T) {
+	configurationStore := registration.NewConfigurationStore(store.NewMemoryStore())
+	ctx := context.Background()
+
+	accounts := dependentConfig("accounts", "1.0.0", registration.Dependency{PluginName: "users", Version: "1.0.0"})
+	err := configurationStore.StoreRoutesAndReducers(ctx, &accounts)
+	require.NotNil(t, err)
+	require.Equal(t, "The plugin \"accounts\" depends on the plugin \"users\", which is not registered", err.Error())
+
+	users := dependentConfig("users", "1.1.0")
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &users))
+	require.Nil(t, configurationStore.StoreRoutesAndReducers(ctx, &accounts))
+
+	users = dependentConfig("users", "2.0.0")
+	err = configurationStore.StoreRoutesAndReducers(ctx, &users)
+	require.NotNil(t, err)
+	require.Equal(t, "The plugin \"accounts\" requires a version of the plugin \"users\" compatible with 1.0.0, not 2.0.0", err.Error())
+}
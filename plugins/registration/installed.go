@@ -0,0 +1,145 @@
+package registration
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
+)
+
+// The revision of the configurations of a tenant, written along with each
+// change of the configurations, so that a configuration checked against
+// configurations changed meanwhile is not stored.
+const revisionKey = "agilestack/registration-revision"
+
+// The number of times a configuration is checked and stored while the
+// configurations are changed concurrently.
+const maxStoreAttempts = 10
+
+/*
+The configurations installed for a tenant, read along with their revision: a
+configuration checked against them (see Check) is stored with operations
+failing when the configurations changed meanwhile (see StoreOperations).
+*/
+type Installed struct {
+	Configurations []PluginConfiguration
+
+	// The ModifyIndex of the configurations, by plugin name
+	indexes map[string]uint64
+
+	// The ModifyIndex and the value of the revision, 0 when there is none
+	revisionIndex uint64
+	revision      uint64
+}
+
+// Reads the configurations installed for the tenant of the context.
+func (s *ConfigurationStore) Installed(ctx context.Context) (*Installed, error) {
+	// The revision is read first: a change made while the configurations are
+	// listed changes it
+	installed := &Installed{Configurations: []PluginConfiguration{}, indexes: map[string]uint64{}}
+	pair, err := s.kv.Get(tenant.Key(ctx, revisionKey))
+	if err != nil {
+		return nil, err
+	}
+	if pair != nil {
+		installed.revisionIndex = pair.ModifyIndex
+		if installed.revision, err = strconv.ParseUint(string(pair.Value), 10, 64); err != nil {
+			log.Printf("Invalid revision of the configurations '%s': %v", pair.Key, err)
+		}
+	}
+
+	pairs, err := s.kv.List(tenant.Key(ctx, consulPrefix))
+	if err != nil {
+		log.Println("Error while listing plugins configurations:", err)
+		return nil, err
+	}
+	for _, pair := range pairs {
+		config := PluginConfiguration{}
+		if err := json.Unmarshal(pair.Value, &config); err != nil {
+			log.Printf("Error while unmarshalling the configuration '%s': %v", pair.Key, err)
+			return nil, err
+		}
+		installed.Configurations = append(installed.Configurations, config)
+		installed.indexes[config.PluginName] = pair.ModifyIndex
+	}
+	return installed, nil
+}
+
+// Returns the installed configuration of the plugin, nil if there is none.
+func (i *Installed) Get(pluginName string) *PluginConfiguration {
+	for _, config := range i.Configurations {
+		if config.PluginName == pluginName {
+			return &config
+		}
+	}
+	return nil
+}
+
+/*
+Checks the configuration against the installed ones: the platform version it
+requires, that it does not downgrade the installed configuration of the plugin
+unless it is forced (see CheckUpgrade), its dependencies (see
+CheckDependencies) and its conflicts (see CheckConflicts).
+*/
+func (i *Installed) Check(config *PluginConfiguration, force bool) error {
+	if err := CheckPlatformVersion(config, PlatformVersion()); err != nil {
+		return err
+	}
+	if !force {
+		if err := CheckUpgrade(i.Get(config.PluginName), config); err != nil {
+			return err
+		}
+	}
+	if err := CheckDependencies(config, i.Configurations); err != nil {
+		return err
+	}
+	return CheckConflicts(config, i.Configurations)
+}
+
+/*
+Returns the operations storing the configuration for the tenant of the context,
+to apply with other operations (see store.Apply). Applying them fails with a
+*store.ConflictError when the installed configurations changed since they were
+read: they must then be read and checked again.
+*/
+func (i *Installed) StoreOperations(ctx context.Context, config *PluginConfiguration) ([]store.Operation, error) {
+	if err := Validate(config); err != nil {
+		return nil, err
+	}
+	value, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return []store.Operation{
+		{
+			Verb:        store.KVCAS,
+			Key:         tenant.Key(ctx, consulPrefix+config.PluginName),
+			Value:       value,
+			ModifyIndex: i.indexes[config.PluginName],
+		},
+		i.revisionOperation(ctx),
+	}, nil
+}
+
+// Returns the operations deleting the configuration of the plugin for the
+// tenant of the context (see StoreOperations).
+func (i *Installed) DeleteOperations(ctx context.Context, pluginName string) []store.Operation {
+	return []store.Operation{
+		{Verb: store.KVDelete, Key: tenant.Key(ctx, consulPrefix+pluginName)},
+		i.revisionOperation(ctx),
+	}
+}
+
+// Returns the operation changing the revision, failing when it changed since
+// the configurations were read.
+func (i *Installed) revisionOperation(ctx context.Context) store.Operation {
+	return store.Operation{
+		Verb:        store.KVCAS,
+		Key:         tenant.Key(ctx, revisionKey),
+		Value:       []byte(strconv.FormatUint(i.revision+1, 10)),
+		ModifyIndex: i.revisionIndex,
+	}
+}
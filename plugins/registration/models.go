@@ -19,5 +19,20 @@ type (
 		PluginName string   `json:"pluginName"`
 		Reducers   []string `json:"reducers"`
 		Routes     []Route  `json:"routes"`
+
+		// The keys of the security resources of the plugin, deleted when the
+		// plugin is unregistered.
+		Resources []string `json:"resources,omitempty"`
+
+		// The semantic version of the plugin, such as "1.2.0".
+		Version string `json:"version,omitempty"`
+
+		// The minimal version of the platform the plugin requires, such as
+		// "1.4.0" (see PlatformVersion).
+		PlatformVersion string `json:"platformVersion,omitempty"`
+
+		// The plugins this plugin depends on, such as the plugins providing
+		// components or reducers it uses.
+		Dependencies []Dependency `json:"dependencies,omitempty"`
 	}
 )
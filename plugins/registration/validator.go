@@ -41,6 +41,22 @@ func Validate(config *PluginConfiguration) error {
 		return err
 	}
 
+	if config.Version != "" {
+		if _, err := ParseVersion(config.Version); err != nil {
+			return fmt.Errorf("Invalid plugin version: \"%s\"", config.Version)
+		}
+	}
+
+	if config.PlatformVersion != "" {
+		if _, err := ParseVersion(config.PlatformVersion); err != nil {
+			return fmt.Errorf("Invalid platform version: \"%s\"", config.PlatformVersion)
+		}
+	}
+
+	if err := validateDependencies(config); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -394,3 +394,50 @@ func TestValidate(t *testing.T) {
 	}
 	require.Nil(t, registration.Validate(&config))
 }
+
+func TestValidate_Versions(t *testing.T) {
+	config := registration.PluginConfiguration{
+		PluginName:      "My wonderful plugin",
+		Reducers:        []string{},
+		Routes:          []registration.Route{},
+		Version:         "1.2.0",
+		PlatformVersion: "1.4.0",
+	}
+	require.Nil(t, registration.Validate(&config))
+
+	config.Version = "1.2"
+	err := registration.Validate(&config)
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid plugin version: \"1.2\"", err.Error())
+
+	config.Version = "1.2.0"
+	config.PlatformVersion = "latest"
+	err = registration.Validate(&config)
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid platform version: \"latest\"", err.Error())
+}
+
+func TestValidate_Dependencies(t *testing.T) {
+	config := registration.PluginConfiguration{
+		PluginName:   "accounts",
+		Reducers:     []string{},
+		Routes:       []registration.Route{},
+		Dependencies: []registration.Dependency{{PluginName: "users", Version: "1.0.0"}, {PluginName: "layout"}},
+	}
+	require.Nil(t, registration.Validate(&config))
+
+	config.Dependencies = []registration.Dependency{{PluginName: " "}}
+	err := registration.Validate(&config)
+	require.NotNil(t, err)
+	require.Equal(t, "The name of a dependency must not be blank", err.Error())
+
+	config.Dependencies = []registration.Dependency{{PluginName: "accounts"}}
+	err = registration.Validate(&config)
+	require.NotNil(t, err)
+	require.Equal(t, "A plugin cannot depend on itself", err.Error())
+
+	config.Dependencies = []registration.Dependency{{PluginName: "users", Version: "1"}}
+	err = registration.Validate(&config)
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid version of the dependency \"users\": \"1\"", err.Error())
+}
@@ -0,0 +1,191 @@
+package registration
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The environment variable giving the version of the platform, checked
+// against the platform version required by the plugins.
+const EnvPlatformVersion = "AGILESTACK_PLATFORM_VERSION"
+
+var versionRegexp = regexp.MustCompile(`^v?(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)` +
+	`(?:-([0-9A-Za-z\-]+(?:\.[0-9A-Za-z\-]+)*))?(?:\+[0-9A-Za-z\-]+(?:\.[0-9A-Za-z\-]+)*)?$`)
+
+// A semantic version, such as "1.4.0" or "2.0.0-beta.1" (see https://semver.org).
+type Version struct {
+	Major, Minor, Patch int
+
+	// The pre-release identifiers, such as ["beta", "1"]
+	PreRelease []string
+}
+
+// Parses a semantic version. The leading "v" and the build metadata ("+...")
+// are accepted and ignored.
+func ParseVersion(version string) (Version, error) {
+	matches := versionRegexp.FindStringSubmatch(version)
+	if matches == nil {
+		return Version{}, fmt.Errorf("Invalid version: \"%s\"", version)
+	}
+	v := Version{}
+	for i, number := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return Version{}, fmt.Errorf("Invalid version: \"%s\"", version)
+		}
+		*number = n
+	}
+	if matches[4] != "" {
+		v.PreRelease = strings.Split(matches[4], ".")
+	}
+	return v, nil
+}
+
+func (v Version) String() string {
+	version := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.PreRelease) > 0 {
+		version += "-" + strings.Join(v.PreRelease, ".")
+	}
+	return version
+}
+
+/*
+Compares the versions by semantic versioning precedence: returns a negative
+number when v is lower than other, 0 when they are equal, and a positive number
+when v is greater. A pre-release version is lower than the release.
+*/
+func (v Version) Compare(other Version) int {
+	for _, diff := range []int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		if diff != 0 {
+			return diff
+		}
+	}
+	if len(v.PreRelease) == 0 || len(other.PreRelease) == 0 {
+		return len(other.PreRelease) - len(v.PreRelease)
+	}
+	for i := 0; i < len(v.PreRelease) && i < len(other.PreRelease); i++ {
+		if diff := comparePreRelease(v.PreRelease[i], other.PreRelease[i]); diff != 0 {
+			return diff
+		}
+	}
+	return len(v.PreRelease) - len(other.PreRelease)
+}
+
+// The numeric identifiers are compared numerically and are lower than the
+// alphanumeric ones, which are compared lexically.
+func comparePreRelease(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return na - nb
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Compares two semantic versions (see Version.Compare).
+func CompareVersions(a, b string) (int, error) {
+	va, err := ParseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := ParseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+var (
+	platformMutex   sync.RWMutex
+	platformVersion string
+)
+
+// Returns the version of the platform. Unless another one is set, it is read
+// from the AGILESTACK_PLATFORM_VERSION environment variable. Returns "" when
+// it is unknown, and the platform version required by the plugins is then not
+// checked.
+func PlatformVersion() string {
+	platformMutex.RLock()
+	defer platformMutex.RUnlock()
+	if platformVersion != "" {
+		return platformVersion
+	}
+	return os.Getenv(EnvPlatformVersion)
+}
+
+// Changes the version of the platform.
+func SetPlatformVersion(version string) {
+	platformMutex.Lock()
+	defer platformMutex.Unlock()
+	platformVersion = version
+}
+
+// Checks that the platform version is at least the one required by the
+// plugin. Succeeds when one of them is unknown.
+func CheckPlatformVersion(config *PluginConfiguration, platformVersion string) error {
+	if config.PlatformVersion == "" || platformVersion == "" {
+		return nil
+	}
+	diff, err := CompareVersions(platformVersion, config.PlatformVersion)
+	if err != nil {
+		return err
+	}
+	if diff < 0 {
+		return fmt.Errorf("The plugin \"%s\" requires the platform version %s, not %s",
+			config.PluginName, config.PlatformVersion, platformVersion)
+	}
+	return nil
+}
+
+// The error of a configuration older than the installed one.
+type DowngradeError struct {
+	PluginName string
+	Installed  string
+	Version    string
+}
+
+func (e *DowngradeError) Error() string {
+	return fmt.Sprintf("Unable to downgrade the plugin \"%s\" from %s to %s", e.PluginName, e.Installed, e.Version)
+}
+
+// Checks that the configuration does not downgrade the installed one, if any.
+// Returns a *DowngradeError when it does. Succeeds when a version is unknown.
+func CheckUpgrade(installed, config *PluginConfiguration) error {
+	if installed == nil || installed.Version == "" || config.Version == "" {
+		return nil
+	}
+	diff, err := CompareVersions(config.Version, installed.Version)
+	if err != nil {
+		return err
+	}
+	if diff < 0 {
+		return &DowngradeError{PluginName: config.PluginName, Installed: installed.Version, Version: config.Version}
+	}
+	return nil
+}
+
+// The versions of an installed plugin.
+type PluginVersion struct {
+	PluginName string `json:"pluginName"`
+
+	// The version of the plugin, "" when unknown
+	Version string `json:"version"`
+
+	// The platform version the plugin requires, "" when unknown
+	PlatformVersion string `json:"platformVersion"`
+}
+
+type byPluginName []PluginVersion
+
+func (v byPluginName) Len() int           { return len(v) }
+func (v byPluginName) Swap(i, j int)      { v[i], v[j] = v[j], v[i] }
+func (v byPluginName) Less(i, j int) bool { return v[i].PluginName < v[j].PluginName }
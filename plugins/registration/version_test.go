@@ -0,0 +1,78 @@
+package registration_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	version, err := registration.ParseVersion("v1.12.3-beta.2+build.5")
+	require.Nil(t, err)
+	require.Equal(t, registration.Version{Major: 1, Minor: 12, Patch: 3, PreRelease: []string{"beta", "2"}}, version)
+	require.Equal(t, "1.12.3-beta.2", version.String())
+
+	for _, invalid := range []string{"", "1", "1.2", "1.2.3.4", "01.2.3", "1.2.3-", "1.2.x", "latest"} {
+		_, err := registration.ParseVersion(invalid)
+		require.NotNil(t, err, invalid)
+		require.Equal(t, "Invalid version: \""+invalid+"\"", err.Error())
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	// By increasing precedence
+	versions := []string{
+		"0.9.9", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.2.0", "1.10.0", "2.0.0",
+	}
+	for i := range versions {
+		for j := range versions {
+			diff, err := registration.CompareVersions(versions[i], versions[j])
+			require.Nil(t, err)
+			switch {
+			case i < j:
+				require.True(t, diff < 0, "%s < %s", versions[i], versions[j])
+			case i > j:
+				require.True(t, diff > 0, "%s > %s", versions[i], versions[j])
+			default:
+				require.Equal(t, 0, diff)
+			}
+		}
+	}
+
+	diff, err := registration.CompareVersions("1.0.0+build.1", "v1.0.0")
+	require.Nil(t, err)
+	require.Equal(t, 0, diff)
+
+	_, err = registration.CompareVersions("1.0.0", "next")
+	require.NotNil(t, err)
+}
+
+func TestCheckPlatformVersion(t *testing.T) {
+	config := &registration.PluginConfiguration{PluginName: "accounts", PlatformVersion: "1.4.0"}
+
+	require.Nil(t, registration.CheckPlatformVersion(config, "1.4.0"))
+	require.Nil(t, registration.CheckPlatformVersion(config, "2.0.0"))
+	require.Nil(t, registration.CheckPlatformVersion(config, ""))
+	require.Nil(t, registration.CheckPlatformVersion(&registration.PluginConfiguration{PluginName: "accounts"}, "1.0.0"))
+
+	err := registration.CheckPlatformVersion(config, "1.4.0-rc.1")
+	require.NotNil(t, err)
+	require.Equal(t, "The plugin \"accounts\" requires the platform version 1.4.0, not 1.4.0-rc.1", err.Error())
+}
+
+func TestCheckUpgrade(t *testing.T) {
+	installed := &registration.PluginConfiguration{PluginName: "accounts", Version: "1.2.0"}
+
+	require.Nil(t, registration.CheckUpgrade(nil, &registration.PluginConfiguration{PluginName: "accounts", Version: "1.0.0"}))
+	require.Nil(t, registration.CheckUpgrade(installed, &registration.PluginConfiguration{PluginName: "accounts", Version: "1.2.0"}))
+	require.Nil(t, registration.CheckUpgrade(installed, &registration.PluginConfiguration{PluginName: "accounts", Version: "1.3.0"}))
+	require.Nil(t, registration.CheckUpgrade(installed, &registration.PluginConfiguration{PluginName: "accounts"}))
+
+	err := registration.CheckUpgrade(installed, &registration.PluginConfiguration{PluginName: "accounts", Version: "1.2.0-rc.1"})
+	require.NotNil(t, err)
+	require.Equal(t, "Unable to downgrade the plugin \"accounts\" from 1.2.0 to 1.2.0-rc.1", err.Error())
+	_, ok := err.(*registration.DowngradeError)
+	require.True(t, ok)
+}
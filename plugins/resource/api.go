@@ -1,10 +1,14 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/hashicorp/consul/api"
-	"log"
 	"errors"
+	"log"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
+	"github.com/hashicorp/consul/api"
 )
 
 const resourcesPrefix = "agilestack/security/resources/"
@@ -26,16 +30,38 @@ type PluginResourcesStorageClient interface {
 
 type ConsulResourcesStorageClient struct {
 	consulClient *api.Client
+	tenantId     string
 }
 
 // NewPluginResourcesStorageClient returns a fresh ConsulStorageClient
 func NewPluginResourcesStorageClient() PluginResourcesStorageClient {
-	client, err := api.NewClient(api.DefaultConfig())
+	return NewTenantResourcesStorageClient(context.Background())
+}
+
+// NewTenantResourcesStorageClient returns a fresh ConsulStorageClient, scoped to the tenant of the context.
+// Consul is configured by the environment variables (see store.ConsulConfigFromEnv).
+func NewTenantResourcesStorageClient(ctx context.Context) PluginResourcesStorageClient {
+	config, err := store.NewConsulConfig()
+	if err != nil {
+		log.Println("Invalid Consul configuration", err)
+		return nil
+	}
+	client, err := NewResourcesStorageClientWithConfig(ctx, config)
 	if err != nil {
 		log.Println("Got error when trying to create consulClient", err)
 		return nil
 	}
-	return &ConsulResourcesStorageClient{client}
+	return client
+}
+
+// NewResourcesStorageClientWithConfig returns a fresh ConsulStorageClient using the given Consul configuration,
+// scoped to the tenant of the context
+func NewResourcesStorageClientWithConfig(ctx context.Context, config store.ConsulConfig) (PluginResourcesStorageClient, error) {
+	client, err := config.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return &ConsulResourcesStorageClient{client, tenant.FromContext(ctx)}, nil
 }
 
 //StoreResource store a resource in consul Store
@@ -49,7 +75,7 @@ func (c *ConsulResourcesStorageClient) StoreResource(resource Resource) error {
 	if resource.Key == "" {
 		return errors.New("Unable to store a resource with an empty name")
 	}
-	p := &api.KVPair{Key: resourcesPrefix + resource.Key, Value: resourceBytes}
+	p := &api.KVPair{Key: tenant.TenantKey(c.tenantId, resourcesPrefix+resource.Key), Value: resourceBytes}
 	_, err := kv.Put(p, &api.WriteOptions{})
 	if err != nil {
 		return err
@@ -57,11 +83,26 @@ func (c *ConsulResourcesStorageClient) StoreResource(resource Resource) error {
 	return nil
 }
 
+// StoreOperation returns the operation storing the resource for the tenant of the context,
+// to apply with other operations (see store.Apply)
+func StoreOperation(ctx context.Context, resource Resource) (store.Operation, error) {
+	if resource.Key == "" {
+		return store.Operation{}, errors.New("Unable to store a resource with an empty name")
+	}
+	return store.SetJSON(tenant.Key(ctx, resourcesPrefix+resource.Key), resource)
+}
+
+// DeleteOperation returns the operation deleting the resource for the tenant of the context,
+// to apply with other operations (see store.Apply)
+func DeleteOperation(ctx context.Context, name string) store.Operation {
+	return store.Operation{Verb: store.KVDelete, Key: tenant.Key(ctx, resourcesPrefix+name)}
+}
+
 //GetResource retrieve a resource in consul store given a name
 func (c *ConsulResourcesStorageClient) GetResource(name string) (*Resource, error) {
 	kv := c.consulClient.KV()
 
-	pair, _, err := kv.Get(resourcesPrefix+name, nil)
+	pair, _, err := kv.Get(tenant.TenantKey(c.tenantId, resourcesPrefix+name), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +118,7 @@ func (c *ConsulResourcesStorageClient) GetResource(name string) (*Resource, erro
 func (c *ConsulResourcesStorageClient) ListResources() ([]Resource, error) {
 	kv := c.consulClient.KV()
 
-	pairs, _, err := kv.List(resourcesPrefix, nil)
+	pairs, _, err := kv.List(tenant.TenantKey(c.tenantId, resourcesPrefix), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -98,6 +139,6 @@ func (c *ConsulResourcesStorageClient) ListResources() ([]Resource, error) {
 func (c *ConsulResourcesStorageClient) DeleteResource(name string) error {
 	kv := c.consulClient.KV()
 
-	_, err := kv.Delete(resourcesPrefix+name, nil)
+	_, err := kv.Delete(tenant.TenantKey(c.tenantId, resourcesPrefix+name), nil)
 	return err
 }
@@ -232,8 +232,8 @@ func TestDeleteResource(t *testing.T) {
 func TestMain(m *testing.M) {
 	log.Println("Launching tests agilestack/utils/plugins")
 
-	consulTestClient, _ = TestUtils.NewConsulClient()
-	consulTestStorageClient = &ConsulResourcesStorageClient{consulTestClient}
-
-	test.DoTestMain(m)
+	test.DoTestMain(m, func(consul *TestUtils.ConsulInfo) {
+		consulTestClient, _ = consul.NewClient()
+		consulTestStorageClient = &ConsulResourcesStorageClient{consulClient: consulTestClient}
+	})
 }
@@ -0,0 +1,129 @@
+package role
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/tenant"
+)
+
+const rolesPrefix = "agilestack/security/roles/"
+
+// RolesStorageClient is an interface for the operations on the role catalog
+type RolesStorageClient interface {
+	//StoreRole insert or modify a role, after checking that it only references existing resources and permissions
+	StoreRole(role secu.Role) error
+
+	//GetRole retrieve a role by its id
+	GetRole(id string) (*secu.Role, error)
+
+	//ListRoles retrieve all the roles of the catalog
+	ListRoles() ([]secu.Role, error)
+
+	//DeleteRole delete a role given its id
+	DeleteRole(id string) error
+}
+
+// RolesStore stores the role catalog of a tenant in a key/value store.
+type RolesStore struct {
+	kv        store.KVStore
+	resources resource.PluginResourcesStorageClient
+	tenantId  string
+}
+
+// NewRolesStore returns a roles store backed by the given key/value store, scoped to the tenant of the context.
+// The given resources storage client is used to validate the roles grants.
+func NewRolesStore(ctx context.Context, kv store.KVStore, resources resource.PluginResourcesStorageClient) *RolesStore {
+	return &RolesStore{kv, resources, tenant.FromContext(ctx)}
+}
+
+// NewRolesStorageClient returns a fresh RolesStore backed by the default store (see DefaultStore).
+// The given resources storage client is used to validate the roles grants.
+func NewRolesStorageClient(resources resource.PluginResourcesStorageClient) RolesStorageClient {
+	return NewTenantRolesStorageClient(context.Background(), resources)
+}
+
+// NewTenantRolesStorageClient returns a fresh RolesStore backed by the default store, scoped to the tenant of the context.
+// The resources storage client is expected to be scoped to the same tenant.
+func NewTenantRolesStorageClient(ctx context.Context, resources resource.PluginResourcesStorageClient) RolesStorageClient {
+	kv, err := DefaultStore()
+	if err != nil {
+		log.Println("Unable to create the store", err)
+		return nil
+	}
+	return NewRolesStore(ctx, kv, resources)
+}
+
+// NewRolesStorageClientWithConfig returns a fresh RolesStore using the given Consul configuration,
+// scoped to the tenant of the context.
+func NewRolesStorageClientWithConfig(ctx context.Context, config store.ConsulConfig, resources resource.PluginResourcesStorageClient) (RolesStorageClient, error) {
+	kv, err := store.NewConsulStoreFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewRolesStore(ctx, kv, resources), nil
+}
+
+// StoreRole store a role in the store
+func (s *RolesStore) StoreRole(role secu.Role) error {
+	if err := Validate(&role, s.resources); err != nil {
+		return err
+	}
+	return store.PutJSON(s.kv, s.key(role.Id), role)
+}
+
+// GetRole retrieve a role in the store given its id
+func (s *RolesStore) GetRole(id string) (*secu.Role, error) {
+	role := &secu.Role{}
+	found, err := store.GetJSON(s.kv, s.key(id), role)
+	if err != nil || !found {
+		return nil, err
+	}
+	return role, nil
+}
+
+// List all the roles
+func (s *RolesStore) ListRoles() ([]secu.Role, error) {
+	pairs, err := s.kv.List(s.key(""))
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]secu.Role, len(pairs))
+	for i, pair := range pairs {
+		if err := json.Unmarshal(pair.Value, &roles[i]); err != nil {
+			log.Printf("Error while unmarshalling role '%s': %v", pair.Key, err)
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+// DeleteRole Delete a role in the store given its id
+func (s *RolesStore) DeleteRole(id string) error {
+	return s.kv.Delete(s.key(id))
+}
+
+// Returns the key of the given role, scoped to the tenant of the store.
+func (s *RolesStore) key(id string) string {
+	return tenant.TenantKey(s.tenantId, rolesPrefix+id)
+}
+
+// The key/value store of the clients created by NewRolesStorageClient and
+// NewTenantRolesStorageClient.
+var defaultKV store.Override
+
+// Returns the key/value store of the clients created by NewRolesStorageClient and
+// NewTenantRolesStorageClient (see store.Override).
+func DefaultStore() (store.KVStore, error) {
+	return defaultKV.Store()
+}
+
+// Changes the key/value store of the clients created by NewRolesStorageClient and
+// NewTenantRolesStorageClient. A nil store restores the default one.
+func SetDefaultStore(kv store.KVStore) {
+	defaultKV.Set(kv)
+}
@@ -0,0 +1,100 @@
+package role
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/tenant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreRole(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreRole(role1))
+
+	pair, _, err := consulTestClient.KV().Get("agilestack/security/roles/accounts-admin", nil)
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+
+	var result secu.Role
+	require.Nil(t, json.Unmarshal(pair.Value, &result))
+	require.Equal(t, role1, result)
+}
+
+func TestStoreRole_Invalid(t *testing.T) {
+	deleteAll(t)
+	err := storageClient().StoreRole(secu.Role{
+		Id: "role",
+		Grants: []secu.Grant{
+			secu.Grant{Resource: "unknown", Permissions: []string{"get"}},
+		},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Unknown resource: \"unknown\"", err.Error())
+
+	pair, _, err := consulTestClient.KV().Get("agilestack/security/roles/role", nil)
+	require.Nil(t, err)
+	require.Nil(t, pair)
+}
+
+func TestGetRole(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreRole(role2))
+
+	result, err := storageClient().GetRole(role2.Id)
+	require.Nil(t, err)
+	require.NotNil(t, result)
+	require.Equal(t, role2, *result)
+}
+
+func TestGetRole_NotFound(t *testing.T) {
+	deleteAll(t)
+	result, err := storageClient().GetRole(role2.Id)
+	require.Nil(t, err)
+	require.Nil(t, result)
+}
+
+func TestListRoles(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreRole(role1))
+	require.Nil(t, storageClient().StoreRole(role2))
+
+	roles, err := storageClient().ListRoles()
+	require.Nil(t, err)
+	require.Equal(t, []secu.Role{role1, role2}, roles)
+}
+
+func TestDeleteRole(t *testing.T) {
+	deleteAll(t)
+	require.Nil(t, storageClient().StoreRole(role1))
+	require.Nil(t, storageClient().StoreRole(role2))
+	require.Nil(t, storageClient().DeleteRole(role1.Id))
+
+	roles, err := storageClient().ListRoles()
+	require.Nil(t, err)
+	require.Equal(t, []secu.Role{role2}, roles)
+}
+
+func TestRolesStore_MemoryStore(t *testing.T) {
+	kv := store.NewMemoryStore()
+	SetDefaultStore(kv)
+	defer SetDefaultStore(nil)
+
+	client := NewTenantRolesStorageClient(tenant.NewContext(context.Background(), "acme"), resources)
+	require.Nil(t, client.StoreRole(role1))
+
+	pair, err := kv.Get("agilestack/tenants/acme/security/roles/accounts-admin")
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+
+	roles, err := NewRolesStorageClient(resources).ListRoles()
+	require.Nil(t, err)
+	require.Equal(t, 0, len(roles))
+
+	roles, err = client.ListRoles()
+	require.Nil(t, err)
+	require.Equal(t, []secu.Role{role1}, roles)
+}
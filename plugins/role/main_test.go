@@ -0,0 +1,104 @@
+package role
+
+import (
+	"context"
+	"log"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/test"
+	"github.com/eogile/agilestack-utils/secu"
+	TestUtils "github.com/eogile/agilestack-utils/test"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+var consulTestClient *api.Client
+
+var (
+	// The resources known by the tests
+	resources = fakeResourcesStorageClient{
+		"accounts": resource.Resource{
+			Key:         "accounts",
+			SecurityKey: "rn:hydra:accounts",
+			Permissions: []string{"create", "get", "delete"},
+		},
+		"policies": resource.Resource{
+			Key:         "policies",
+			SecurityKey: "rn:hydra:policies",
+			Permissions: []string{"create", "get"},
+		},
+	}
+
+	role1 = secu.Role{
+		Id:          "accounts-admin",
+		Description: "Administration of the user accounts",
+		Grants: []secu.Grant{
+			secu.Grant{
+				Resource:    "accounts",
+				Permissions: []string{"create", "get", "delete"},
+			},
+		},
+	}
+
+	role2 = secu.Role{
+		Id:          "reader",
+		Description: "Read only access",
+		Grants: []secu.Grant{
+			secu.Grant{
+				Resource:    "accounts",
+				Permissions: []string{"get"},
+			},
+			secu.Grant{
+				Resource:    "policies",
+				Permissions: []string{"get"},
+			},
+		},
+	}
+)
+
+// An in-memory resources storage client, by resource key.
+type fakeResourcesStorageClient map[string]resource.Resource
+
+func (c fakeResourcesStorageClient) StoreResource(res resource.Resource) error {
+	c[res.Key] = res
+	return nil
+}
+
+func (c fakeResourcesStorageClient) GetResource(name string) (*resource.Resource, error) {
+	res, found := c[name]
+	if !found {
+		return nil, nil
+	}
+	return &res, nil
+}
+
+func (c fakeResourcesStorageClient) ListResources() ([]resource.Resource, error) {
+	list := make([]resource.Resource, 0, len(c))
+	for _, res := range c {
+		list = append(list, res)
+	}
+	return list, nil
+}
+
+func (c fakeResourcesStorageClient) DeleteResource(name string) error {
+	delete(c, name)
+	return nil
+}
+
+func TestMain(m *testing.M) {
+	log.Println("Launching tests agilestack/utils/plugins/role")
+	test.DoTestMain(m, func(consul *TestUtils.ConsulInfo) {
+		consulTestClient, _ = consul.NewClient()
+	})
+}
+
+func storageClient() *RolesStore {
+	return NewRolesStore(context.Background(), store.NewConsulStore(consulTestClient), resources)
+}
+
+func deleteAll(t *testing.T) {
+	_, err := consulTestClient.KV().DeleteTree("agilestack/", &api.WriteOptions{})
+	require.Nil(t, err)
+}
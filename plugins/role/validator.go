@@ -0,0 +1,65 @@
+package role
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/eogile/agilestack-utils/slices"
+)
+
+// Validates the given role: each grant must reference a stored resource
+// and only permissions declared by this resource.
+func Validate(role *secu.Role, resources resource.PluginResourcesStorageClient) error {
+	if role == nil {
+		return errors.New("Role must not be nil")
+	}
+
+	// The role id is a part of the ids of its policies (see secu.RolePolicyId)
+	if matched, err := regexp.MatchString(secu.RolePolicyPartPattern, role.Id); !matched || err != nil {
+		return fmt.Errorf("The role id does not match the pattern \"%s\": \"%s\"", secu.RolePolicyPartPattern, role.Id)
+	}
+
+	if role.Grants == nil {
+		return errors.New("The grants slice must not be nil")
+	}
+
+	granted := make(map[string]bool, len(role.Grants))
+	for _, grant := range role.Grants {
+		if granted[grant.Resource] {
+			return fmt.Errorf("The resource \"%s\" is granted several times", grant.Resource)
+		}
+		granted[grant.Resource] = true
+
+		if err := validateGrant(grant, resources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateGrant(grant secu.Grant, resources resource.PluginResourcesStorageClient) error {
+	if matched, err := regexp.MatchString(secu.RolePolicyPartPattern, grant.Resource); !matched || err != nil {
+		return fmt.Errorf("The resource key does not match the pattern \"%s\": \"%s\"", secu.RolePolicyPartPattern, grant.Resource)
+	}
+
+	res, err := resources.GetResource(grant.Resource)
+	if err != nil {
+		return err
+	}
+	if res == nil {
+		return fmt.Errorf("Unknown resource: \"%s\"", grant.Resource)
+	}
+
+	if len(grant.Permissions) == 0 {
+		return fmt.Errorf("No permission granted on resource \"%s\"", grant.Resource)
+	}
+	for _, permission := range grant.Permissions {
+		if !slices.StringInSlice(permission, res.Permissions) {
+			return fmt.Errorf("Unknown permission \"%s\" on resource \"%s\"", permission, grant.Resource)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package role
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/secu"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate_Nil(t *testing.T) {
+	err := Validate(nil, resources)
+	require.NotNil(t, err)
+	require.Equal(t, "Role must not be nil", err.Error())
+}
+
+func TestValidate_InvalidId(t *testing.T) {
+	for _, id := range []string{"", "	", "rôle", "my role", "role:1"} {
+		err := Validate(&secu.Role{Id: id, Grants: []secu.Grant{}}, resources)
+		require.NotNil(t, err)
+		require.Equal(t, "The role id does not match the pattern \"^[a-zA-Z0-9\\-_.]+$\": \""+id+"\"", err.Error())
+	}
+}
+
+func TestValidate_IdWithDots(t *testing.T) {
+	// The role ids accepted in the policy ids are accepted
+	role := &secu.Role{Id: "accounts.admin", Grants: []secu.Grant{}}
+	require.Nil(t, Validate(role, resources))
+	require.Nil(t, secu.ValidateRolePolicyId(role.Id, "accounts"))
+}
+
+func TestValidate_GrantsNil(t *testing.T) {
+	err := Validate(&secu.Role{Id: "role"}, resources)
+	require.NotNil(t, err)
+	require.Equal(t, "The grants slice must not be nil", err.Error())
+}
+
+func TestValidate_UnknownResource(t *testing.T) {
+	err := Validate(&secu.Role{
+		Id: "role",
+		Grants: []secu.Grant{
+			secu.Grant{Resource: "accounts", Permissions: []string{"get"}},
+			secu.Grant{Resource: "clients", Permissions: []string{"get"}},
+		},
+	}, resources)
+	require.NotNil(t, err)
+	require.Equal(t, "Unknown resource: \"clients\"", err.Error())
+}
+
+func TestValidate_UnknownPermission(t *testing.T) {
+	err := Validate(&secu.Role{
+		Id: "role",
+		Grants: []secu.Grant{
+			secu.Grant{Resource: "policies", Permissions: []string{"get", "delete"}},
+		},
+	}, resources)
+	require.NotNil(t, err)
+	require.Equal(t, "Unknown permission \"delete\" on resource \"policies\"", err.Error())
+}
+
+func TestValidate_NoPermission(t *testing.T) {
+	err := Validate(&secu.Role{
+		Id: "role",
+		Grants: []secu.Grant{
+			secu.Grant{Resource: "policies", Permissions: []string{}},
+		},
+	}, resources)
+	require.NotNil(t, err)
+	require.Equal(t, "No permission granted on resource \"policies\"", err.Error())
+}
+
+func TestValidate_DuplicateResource(t *testing.T) {
+	err := Validate(&secu.Role{
+		Id: "role",
+		Grants: []secu.Grant{
+			secu.Grant{Resource: "accounts", Permissions: []string{"get"}},
+			secu.Grant{Resource: "accounts", Permissions: []string{"create"}},
+		},
+	}, resources)
+	require.NotNil(t, err)
+	require.Equal(t, "The resource \"accounts\" is granted several times", err.Error())
+}
+
+func TestValidate(t *testing.T) {
+	require.Nil(t, Validate(&role1, resources))
+	require.Nil(t, Validate(&role2, resources))
+}
+
+func TestValidate_InvalidResourceKey(t *testing.T) {
+	for _, key := range []string{"accounts/1", "accounts?all", "role:accounts", ""} {
+		err := Validate(&secu.Role{
+			Id:     "role",
+			Grants: []secu.Grant{secu.Grant{Resource: key, Permissions: []string{"get"}}},
+		}, resources)
+		require.NotNil(t, err)
+		require.Equal(t, "The resource key does not match the pattern \"^[a-zA-Z0-9\\-_.]+$\": \""+key+"\"", err.Error())
+	}
+}
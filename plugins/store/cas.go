@@ -0,0 +1,40 @@
+package store
+
+// CASStore is a KVStore able to replace a pair only when it was not modified
+// in the meantime (check-and-set).
+type CASStore interface {
+	KVStore
+
+	// CAS stores the value when the ModifyIndex of the pair is still the given
+	// one, or when the index is 0 and there is no such pair. Returns false,
+	// storing nothing, when the pair was modified.
+	CAS(key string, value []byte, modifyIndex uint64) (bool, error)
+
+	// DeleteCAS deletes the pair when its ModifyIndex is still the given one.
+	// Returns false, deleting nothing, when the pair was modified or deleted.
+	DeleteCAS(key string, modifyIndex uint64) (bool, error)
+}
+
+/*
+Stores the value when the pair was not modified since it was read with the given
+ModifyIndex (0 when there was no such pair), if the store is a CASStore.
+Otherwise, the value is stored whatever the modifications of the pair.
+*/
+func CheckAndSet(kv KVStore, key string, value []byte, modifyIndex uint64) (bool, error) {
+	if cas, ok := kv.(CASStore); ok {
+		return cas.CAS(key, value, modifyIndex)
+	}
+	return true, kv.Put(key, value)
+}
+
+/*
+Deletes the pair when it was not modified since it was read with the given
+ModifyIndex, if the store is a CASStore. Otherwise, the pair is deleted whatever
+its modifications.
+*/
+func CheckAndDelete(kv KVStore, key string, modifyIndex uint64) (bool, error) {
+	if cas, ok := kv.(CASStore); ok {
+		return cas.DeleteCAS(key, modifyIndex)
+	}
+	return true, kv.Delete(key)
+}
@@ -0,0 +1,81 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/test"
+	"github.com/stretchr/testify/require"
+)
+
+// Checks that CheckAndSet only stores the values of unmodified pairs.
+func testCheckAndSet(t *testing.T, kv store.KVStore) {
+	stored, err := store.CheckAndSet(kv, "agilestack/groups/team", []byte("v1"), 0)
+	require.Nil(t, err)
+	require.True(t, stored)
+
+	// The pair now exists
+	stored, err = store.CheckAndSet(kv, "agilestack/groups/team", []byte("v2"), 0)
+	require.Nil(t, err)
+	require.False(t, stored)
+
+	pair, err := kv.Get("agilestack/groups/team")
+	require.Nil(t, err)
+	require.Equal(t, "v1", string(pair.Value))
+
+	stored, err = store.CheckAndSet(kv, "agilestack/groups/team", []byte("v3"), pair.ModifyIndex)
+	require.Nil(t, err)
+	require.True(t, stored)
+
+	// The pair was modified since it was read
+	stored, err = store.CheckAndSet(kv, "agilestack/groups/team", []byte("v4"), pair.ModifyIndex)
+	require.Nil(t, err)
+	require.False(t, stored)
+
+	pair, err = kv.Get("agilestack/groups/team")
+	require.Nil(t, err)
+	require.Equal(t, "v3", string(pair.Value))
+
+	stored, err = store.CheckAndSet(kv, "agilestack/groups/missing", []byte("v5"), pair.ModifyIndex)
+	require.Nil(t, err)
+	require.False(t, stored)
+
+	// Deleting
+	require.Nil(t, kv.Put("agilestack/groups/team", []byte("v6")))
+	deleted, err := store.CheckAndDelete(kv, "agilestack/groups/team", pair.ModifyIndex)
+	require.Nil(t, err)
+	require.False(t, deleted)
+
+	pair, err = kv.Get("agilestack/groups/team")
+	require.Nil(t, err)
+	deleted, err = store.CheckAndDelete(kv, "agilestack/groups/team", pair.ModifyIndex)
+	require.Nil(t, err)
+	require.True(t, deleted)
+
+	pair, err = kv.Get("agilestack/groups/team")
+	require.Nil(t, err)
+	require.Nil(t, pair)
+}
+
+func TestCheckAndSet_MemoryStore(t *testing.T) {
+	testCheckAndSet(t, store.NewMemoryStore())
+}
+
+func TestCheckAndSet_ConsulStore(t *testing.T) {
+	fake := test.NewFakeConsul()
+	defer fake.Close()
+	client, err := fake.Info().NewClient()
+	require.Nil(t, err)
+
+	testCheckAndSet(t, store.NewConsulStore(client))
+}
+
+func TestCheckAndSet_FileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "file-store")
+	require.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	testCheckAndSet(t, store.NewFileStore(root))
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"net/http"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// The address of Consul in the agilestack network.
+const DefaultConsulAddress = "consul.agilestacknet:8500"
+
+// ConsulConfig describes how to access Consul.
+type ConsulConfig struct {
+	// The address of Consul ("host:port"), DefaultConsulAddress if empty.
+	Address string
+
+	// "http" or "https", "http" if empty.
+	Scheme string
+
+	// The ACL token sent with the requests.
+	Token string
+
+	// The datacenter to query, the one of the Consul agent if empty.
+	Datacenter string
+
+	// The TLS configuration, used when the scheme is "https".
+	TLS ConsulTLSConfig
+
+	// The HTTP client sending the requests. When it is set, the TLS
+	// configuration is ignored and must be set in the client transport.
+	HTTPClient *http.Client
+}
+
+// ConsulTLSConfig describes the TLS connection to Consul.
+type ConsulTLSConfig struct {
+	// The server name checked in the certificate of Consul, the host of the
+	// address if empty.
+	ServerName string
+
+	// The CA certificate checking the certificate of Consul (PEM file).
+	CAFile string
+
+	// The client certificate and its key (PEM files).
+	CertFile string
+	KeyFile  string
+
+	// Disables the check of the certificate of Consul. For tests only.
+	InsecureSkipVerify bool
+}
+
+// Returns a Consul client matching the configuration.
+func (c ConsulConfig) NewClient() (*api.Client, error) {
+	config := api.DefaultConfig()
+	config.Address = c.Address
+	if config.Address == "" {
+		config.Address = DefaultConsulAddress
+	}
+	if c.Scheme != "" {
+		config.Scheme = c.Scheme
+	}
+	if c.Token != "" {
+		config.Token = c.Token
+	}
+	if c.Datacenter != "" {
+		config.Datacenter = c.Datacenter
+	}
+	if c.TLS != (ConsulTLSConfig{}) {
+		config.TLSConfig = api.TLSConfig{
+			Address:            c.TLS.ServerName,
+			CAFile:             c.TLS.CAFile,
+			CertFile:           c.TLS.CertFile,
+			KeyFile:            c.TLS.KeyFile,
+			InsecureSkipVerify: c.TLS.InsecureSkipVerify,
+		}
+	}
+	if c.HTTPClient != nil {
+		config.HttpClient = c.HTTPClient
+	}
+	return api.NewClient(config)
+}
+
+// Returns a store using the Consul matching the configuration.
+func NewConsulStoreFromConfig(config ConsulConfig) (*ConsulStore, error) {
+	client, err := config.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewConsulStore(client), nil
+}
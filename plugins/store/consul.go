@@ -0,0 +1,115 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// A WatchableStore, TransactionalStore and CASStore backed by the KV store of Consul.
+type ConsulStore struct {
+	kv *api.KV
+}
+
+// Returns a store using the given Consul client.
+func NewConsulStore(client *api.Client) *ConsulStore {
+	return &ConsulStore{kv: client.KV()}
+}
+
+func (s *ConsulStore) Get(key string) (*Pair, error) {
+	pair, _, err := s.kv.Get(normalizeKey(key), nil)
+	if err != nil || pair == nil {
+		return nil, err
+	}
+	return &Pair{Key: pair.Key, Value: pair.Value, ModifyIndex: pair.ModifyIndex}, nil
+}
+
+func (s *ConsulStore) List(prefix string) ([]Pair, error) {
+	pairs, _, err := s.kv.List(normalizeKey(prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Pair, 0, len(pairs))
+	for _, pair := range pairs {
+		list = append(list, Pair{Key: pair.Key, Value: pair.Value, ModifyIndex: pair.ModifyIndex})
+	}
+	return list, nil
+}
+
+// WaitList runs a blocking query of Consul on the prefix.
+func (s *ConsulStore) WaitList(prefix string, index uint64, wait time.Duration) ([]Pair, uint64, error) {
+	pairs, meta, err := s.kv.List(normalizeKey(prefix), &api.QueryOptions{WaitIndex: index, WaitTime: wait})
+	if err != nil {
+		return nil, 0, err
+	}
+	list := make([]Pair, 0, len(pairs))
+	for _, pair := range pairs {
+		list = append(list, Pair{Key: pair.Key, Value: pair.Value, ModifyIndex: pair.ModifyIndex})
+	}
+	return list, meta.LastIndex, nil
+}
+
+func (s *ConsulStore) Put(key string, value []byte) error {
+	_, err := s.kv.Put(&api.KVPair{Key: normalizeKey(key), Value: value}, nil)
+	return err
+}
+
+func (s *ConsulStore) CAS(key string, value []byte, modifyIndex uint64) (bool, error) {
+	stored, _, err := s.kv.CAS(&api.KVPair{Key: normalizeKey(key), Value: value, ModifyIndex: modifyIndex}, nil)
+	return stored, err
+}
+
+func (s *ConsulStore) DeleteCAS(key string, modifyIndex uint64) (bool, error) {
+	deleted, _, err := s.kv.DeleteCAS(&api.KVPair{Key: normalizeKey(key), ModifyIndex: modifyIndex}, nil)
+	return deleted, err
+}
+
+func (s *ConsulStore) Delete(key string) error {
+	_, err := s.kv.Delete(normalizeKey(key), nil)
+	return err
+}
+
+// The maximal number of operations of a Consul transaction.
+const MaxConsulTxnOperations = 64
+
+// Txn runs a Consul transaction. Consul limits the number of operations of a
+// transaction: Txn fails without sending the transaction when there are more
+// than MaxConsulTxnOperations.
+func (s *ConsulStore) Txn(operations []Operation) error {
+	if len(operations) > MaxConsulTxnOperations {
+		return fmt.Errorf("Unable to apply %d operations in a transaction: Consul accepts %d operations at most",
+			len(operations), MaxConsulTxnOperations)
+	}
+	ops := make(api.KVTxnOps, 0, len(operations))
+	for _, operation := range operations {
+		op := &api.KVTxnOp{Verb: api.KVSet, Key: normalizeKey(operation.Key), Value: operation.Value}
+		switch operation.Verb {
+		case KVDelete:
+			op.Verb = api.KVDelete
+		case KVCAS:
+			op.Verb = api.KVCAS
+			op.Index = operation.ModifyIndex
+		}
+		ops = append(ops, op)
+	}
+	ok, response, _, err := s.kv.Txn(ops, nil)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		problems := []string{}
+		if response != nil {
+			for _, txnError := range response.Errors {
+				if txnError.OpIndex < len(operations) && operations[txnError.OpIndex].Verb == KVCAS {
+					return &ConflictError{Key: normalizeKey(operations[txnError.OpIndex].Key)}
+				}
+				problems = append(problems, txnError.What)
+			}
+		}
+		return errors.New("Transaction rolled back: " + strings.Join(problems, ", "))
+	}
+	return nil
+}
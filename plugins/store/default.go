@@ -0,0 +1,64 @@
+package store
+
+import "sync"
+
+var (
+	defaultMutex    sync.Mutex
+	defaultInstance KVStore
+)
+
+/*
+Returns the default key/value store, used by the package-level functions of the
+plugins packages. Unless another one is set, it is backed by the Consul
+configured by the environment variables (see ConsulConfigFromEnv), created on
+first use.
+*/
+func Default() (KVStore, error) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	if defaultInstance == nil {
+		config, err := NewConsulConfig()
+		if err != nil {
+			return nil, err
+		}
+		kv, err := NewConsulStoreFromConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		defaultInstance = kv
+	}
+	return defaultInstance, nil
+}
+
+// Changes the default key/value store. A nil store restores the Consul
+// configured by the environment variables.
+func SetDefault(kv KVStore) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultInstance = kv
+}
+
+// An Override is the key/value store of a package, which is the default one
+// (see Default) unless another one is set. Its zero value is ready to use.
+type Override struct {
+	mutex sync.RWMutex
+	kv    KVStore
+}
+
+// Returns the store set, or the default one.
+func (o *Override) Store() (KVStore, error) {
+	o.mutex.RLock()
+	kv := o.kv
+	o.mutex.RUnlock()
+	if kv != nil {
+		return kv, nil
+	}
+	return Default()
+}
+
+// Changes the store. A nil store restores the default one.
+func (o *Override) Set(kv KVStore) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.kv = kv
+}
@@ -0,0 +1,31 @@
+package store_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverride(t *testing.T) {
+	defaultKV := store.NewMemoryStore()
+	store.SetDefault(defaultKV)
+	defer store.SetDefault(nil)
+
+	// The zero value uses the default store
+	var override store.Override
+	kv, err := override.Store()
+	require.Nil(t, err)
+	require.True(t, kv == defaultKV)
+
+	other := store.NewMemoryStore()
+	override.Set(other)
+	kv, err = override.Store()
+	require.Nil(t, err)
+	require.True(t, kv == other)
+
+	override.Set(nil)
+	kv, err = override.Store()
+	require.Nil(t, err)
+	require.True(t, kv == defaultKV)
+}
@@ -0,0 +1,134 @@
+package store
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// The environment variables configuring the access to Consul. They are the
+// ones of the Consul command line, except the datacenter one.
+const (
+	EnvConsulAddress       = "CONSUL_HTTP_ADDR"
+	EnvConsulToken         = "CONSUL_HTTP_TOKEN"
+	EnvConsulSSL           = "CONSUL_HTTP_SSL"
+	EnvConsulSSLVerify     = "CONSUL_HTTP_SSL_VERIFY"
+	EnvConsulCACert        = "CONSUL_CACERT"
+	EnvConsulClientCert    = "CONSUL_CLIENT_CERT"
+	EnvConsulClientKey     = "CONSUL_CLIENT_KEY"
+	EnvConsulTLSServerName = "CONSUL_TLS_SERVER_NAME"
+	EnvConsulDatacenter    = "CONSUL_DATACENTER"
+)
+
+// A ConsulOption overrides a part of a Consul configuration.
+type ConsulOption func(config *ConsulConfig)
+
+// Sets the address of Consul ("host:port", or "https://host:port").
+func WithAddress(address string) ConsulOption {
+	return func(config *ConsulConfig) {
+		config.Scheme, config.Address = splitAddress(address, config.Scheme)
+	}
+}
+
+// Sets the ACL token sent with the requests.
+func WithToken(token string) ConsulOption {
+	return func(config *ConsulConfig) {
+		config.Token = token
+	}
+}
+
+// Sets the datacenter to query.
+func WithDatacenter(datacenter string) ConsulOption {
+	return func(config *ConsulConfig) {
+		config.Datacenter = datacenter
+	}
+}
+
+// Connects Consul over HTTPS with the given TLS configuration.
+func WithTLS(tls ConsulTLSConfig) ConsulOption {
+	return func(config *ConsulConfig) {
+		config.Scheme = "https"
+		config.TLS = tls
+	}
+}
+
+// Sets the HTTP client sending the requests.
+func WithHTTPClient(client *http.Client) ConsulOption {
+	return func(config *ConsulConfig) {
+		config.HTTPClient = client
+	}
+}
+
+/*
+Returns the Consul configuration read from the environment variables, then
+overridden by the given options.
+
+The address defaults to DefaultConsulAddress.
+*/
+func NewConsulConfig(options ...ConsulOption) (ConsulConfig, error) {
+	config, err := ConsulConfigFromEnv()
+	if err != nil {
+		return config, err
+	}
+	for _, option := range options {
+		option(&config)
+	}
+	return config, nil
+}
+
+/*
+Returns the Consul configuration read from the environment variables:
+  - CONSUL_HTTP_ADDR: the address of Consul, DefaultConsulAddress if empty
+  - CONSUL_HTTP_TOKEN: the ACL token
+  - CONSUL_DATACENTER: the datacenter
+  - CONSUL_HTTP_SSL: "true" to use HTTPS
+  - CONSUL_HTTP_SSL_VERIFY: "false" to disable the check of the certificate of Consul
+  - CONSUL_CACERT, CONSUL_CLIENT_CERT, CONSUL_CLIENT_KEY: the certificate files
+  - CONSUL_TLS_SERVER_NAME: the server name checked in the certificate of Consul
+*/
+func ConsulConfigFromEnv() (ConsulConfig, error) {
+	config := ConsulConfig{
+		Address:    DefaultConsulAddress,
+		Token:      os.Getenv(EnvConsulToken),
+		Datacenter: os.Getenv(EnvConsulDatacenter),
+		TLS: ConsulTLSConfig{
+			ServerName: os.Getenv(EnvConsulTLSServerName),
+			CAFile:     os.Getenv(EnvConsulCACert),
+			CertFile:   os.Getenv(EnvConsulClientCert),
+			KeyFile:    os.Getenv(EnvConsulClientKey),
+		},
+	}
+	if address := os.Getenv(EnvConsulAddress); address != "" {
+		config.Scheme, config.Address = splitAddress(address, config.Scheme)
+	}
+
+	if value := os.Getenv(EnvConsulSSL); value != "" {
+		ssl, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("Invalid %s: \"%s\"", EnvConsulSSL, value)
+		}
+		if ssl {
+			config.Scheme = "https"
+		}
+	}
+	if value := os.Getenv(EnvConsulSSLVerify); value != "" {
+		verify, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, fmt.Errorf("Invalid %s: \"%s\"", EnvConsulSSLVerify, value)
+		}
+		config.TLS.InsecureSkipVerify = !verify
+	}
+	return config, nil
+}
+
+// Splits an address into its scheme (if any) and "host:port".
+func splitAddress(address, defaultScheme string) (scheme, hostPort string) {
+	for _, s := range []string{"http", "https"} {
+		if strings.HasPrefix(address, s+"://") {
+			return s, strings.TrimPrefix(address, s+"://")
+		}
+	}
+	return defaultScheme, address
+}
@@ -0,0 +1,124 @@
+package store_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/stretchr/testify/require"
+)
+
+var consulEnv = []string{
+	store.EnvConsulAddress,
+	store.EnvConsulToken,
+	store.EnvConsulSSL,
+	store.EnvConsulSSLVerify,
+	store.EnvConsulCACert,
+	store.EnvConsulClientCert,
+	store.EnvConsulClientKey,
+	store.EnvConsulTLSServerName,
+	store.EnvConsulDatacenter,
+}
+
+// Sets the given consul environment variables, unsets the other ones, and
+// returns a function restoring the previous environment.
+func setConsulEnv(values map[string]string) func() {
+	previous := map[string]string{}
+	for _, name := range consulEnv {
+		if value, found := os.LookupEnv(name); found {
+			previous[name] = value
+		}
+		os.Unsetenv(name)
+	}
+	for name, value := range values {
+		os.Setenv(name, value)
+	}
+	return func() {
+		for _, name := range consulEnv {
+			os.Unsetenv(name)
+		}
+		for name, value := range previous {
+			os.Setenv(name, value)
+		}
+	}
+}
+
+func TestConsulConfigFromEnv_Defaults(t *testing.T) {
+	defer setConsulEnv(nil)()
+
+	config, err := store.ConsulConfigFromEnv()
+	require.Nil(t, err)
+	require.Equal(t, store.ConsulConfig{Address: store.DefaultConsulAddress}, config)
+}
+
+func TestConsulConfigFromEnv(t *testing.T) {
+	defer setConsulEnv(map[string]string{
+		store.EnvConsulAddress:       "https://consul.example.com:8501",
+		store.EnvConsulToken:         "secret-token",
+		store.EnvConsulDatacenter:    "dc2",
+		store.EnvConsulSSLVerify:     "false",
+		store.EnvConsulCACert:        "/etc/consul/ca.pem",
+		store.EnvConsulClientCert:    "/etc/consul/client.pem",
+		store.EnvConsulClientKey:     "/etc/consul/client-key.pem",
+		store.EnvConsulTLSServerName: "consul.example.com",
+	})()
+
+	config, err := store.ConsulConfigFromEnv()
+	require.Nil(t, err)
+	require.Equal(t, store.ConsulConfig{
+		Address:    "consul.example.com:8501",
+		Scheme:     "https",
+		Token:      "secret-token",
+		Datacenter: "dc2",
+		TLS: store.ConsulTLSConfig{
+			ServerName:         "consul.example.com",
+			CAFile:             "/etc/consul/ca.pem",
+			CertFile:           "/etc/consul/client.pem",
+			KeyFile:            "/etc/consul/client-key.pem",
+			InsecureSkipVerify: true,
+		},
+	}, config)
+}
+
+func TestConsulConfigFromEnv_SSL(t *testing.T) {
+	defer setConsulEnv(map[string]string{
+		store.EnvConsulAddress: "consul:8501",
+		store.EnvConsulSSL:     "true",
+	})()
+
+	config, err := store.ConsulConfigFromEnv()
+	require.Nil(t, err)
+	require.Equal(t, "consul:8501", config.Address)
+	require.Equal(t, "https", config.Scheme)
+}
+
+func TestConsulConfigFromEnv_InvalidBool(t *testing.T) {
+	defer setConsulEnv(map[string]string{store.EnvConsulSSL: "maybe"})()
+
+	_, err := store.ConsulConfigFromEnv()
+	require.NotNil(t, err)
+
+	_, err = store.NewConsulConfig()
+	require.NotNil(t, err)
+}
+
+func TestNewConsulConfig_OptionsOverrideEnv(t *testing.T) {
+	defer setConsulEnv(map[string]string{
+		store.EnvConsulAddress:    "consul:8500",
+		store.EnvConsulToken:      "env-token",
+		store.EnvConsulDatacenter: "dc1",
+	})()
+
+	config, err := store.NewConsulConfig(
+		store.WithAddress("other-consul:8500"),
+		store.WithToken("option-token"),
+		store.WithDatacenter("dc2"),
+		store.WithTLS(store.ConsulTLSConfig{ServerName: "other-consul"}),
+	)
+	require.Nil(t, err)
+	require.Equal(t, "other-consul:8500", config.Address)
+	require.Equal(t, "https", config.Scheme)
+	require.Equal(t, "option-token", config.Token)
+	require.Equal(t, "dc2", config.Datacenter)
+	require.Equal(t, "other-consul", config.TLS.ServerName)
+}
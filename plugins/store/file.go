@@ -0,0 +1,210 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The suffix of the files holding the values, so that a key can be both a
+// value and the prefix of other keys ("a" and "a/b").
+const fileSuffix = ".kv"
+
+/*
+A KVStore keeping each pair in a file under a root directory:
+the value of "agilestack/menu/my-plugin" is stored in
+"<root>/agilestack/menu/my-plugin.kv".
+
+The ModifyIndex of a pair is the modification time of its file in nanoseconds.
+The check of CAS is only reliable among the users of the same FileStore.
+*/
+type FileStore struct {
+	root  string
+	mutex sync.RWMutex
+}
+
+// Returns a store keeping its files under the given directory.
+// The directory is created when the first pair is stored.
+func NewFileStore(root string) *FileStore {
+	return &FileStore{root: root}
+}
+
+// Returns the path of the file holding the value of the key.
+func (s *FileStore) path(key string) (string, error) {
+	key = normalizeKey(key)
+	if key == "" {
+		return "", fmt.Errorf("Invalid key: \"%s\"", key)
+	}
+	for _, element := range strings.Split(key, "/") {
+		if element == "" || element == "." || element == ".." {
+			return "", fmt.Errorf("Invalid key: \"%s\"", key)
+		}
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)) + fileSuffix, nil
+}
+
+func (s *FileStore) Get(key string) (*Pair, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.read(normalizeKey(key), path)
+}
+
+func (s *FileStore) read(key, path string) (*Pair, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	value, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Pair{Key: key, Value: value, ModifyIndex: uint64(info.ModTime().UnixNano())}, nil
+}
+
+func (s *FileStore) List(prefix string) ([]Pair, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	prefix = normalizeKey(prefix)
+	list := []Pair{}
+	err := filepath.Walk(s.root, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if info.IsDir() || !strings.HasSuffix(path, fileSuffix) {
+			return nil
+		}
+		relativePath, err := filepath.Rel(s.root, path)
+		if err != nil {
+			return err
+		}
+		key := strings.TrimSuffix(filepath.ToSlash(relativePath), fileSuffix)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		pair, err := s.read(key, path)
+		if err != nil || pair == nil {
+			return err
+		}
+		list = append(list, *pair)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(byKey(list))
+	return list, nil
+}
+
+func (s *FileStore) Put(key string, value []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.write(path, value)
+}
+
+func (s *FileStore) CAS(key string, value []byte, modifyIndex uint64) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pair, err := s.read(normalizeKey(key), path)
+	if err != nil {
+		return false, err
+	}
+	if (pair == nil && modifyIndex != 0) || (pair != nil && pair.ModifyIndex != modifyIndex) {
+		return false, nil
+	}
+	return true, s.write(path, value)
+}
+
+// The mutex must be held.
+func (s *FileStore) write(path string, value []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// Writing a temporary file first, so that readers never see a partial value
+	tmp, err := ioutil.TempFile(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// The clock of the filesystem may be coarse: the modification time must
+	// grow, so that CAS detects the modification.
+	if previous, err := os.Stat(path); err == nil {
+		if info, err := os.Stat(tmp.Name()); err == nil && !info.ModTime().After(previous.ModTime()) {
+			modTime := previous.ModTime().Add(time.Nanosecond)
+			if err := os.Chtimes(tmp.Name(), modTime, modTime); err != nil {
+				os.Remove(tmp.Name())
+				return err
+			}
+		}
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *FileStore) DeleteCAS(key string, modifyIndex uint64) (bool, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return false, err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	pair, err := s.read(normalizeKey(key), path)
+	if err != nil || pair == nil || pair.ModifyIndex != modifyIndex {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *FileStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A WatchableStore, TransactionalStore and CASStore keeping the pairs in memory.
+type MemoryStore struct {
+	mutex sync.RWMutex
+	index uint64
+	pairs map[string]Pair
+	// closed on every write, to wake up the blocked WaitList calls
+	changed chan struct{}
+}
+
+// Returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{index: 1, pairs: map[string]Pair{}, changed: make(chan struct{})}
+}
+
+func (s *MemoryStore) Get(key string) (*Pair, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	pair, found := s.pairs[normalizeKey(key)]
+	if !found {
+		return nil, nil
+	}
+	return copyPair(pair), nil
+}
+
+func (s *MemoryStore) List(prefix string) ([]Pair, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.list(prefix), nil
+}
+
+// WaitList waits for a write when the index is not 0 and no write happened
+// since the index.
+func (s *MemoryStore) WaitList(prefix string, index uint64, wait time.Duration) ([]Pair, uint64, error) {
+	timeout := time.After(wait)
+	for {
+		s.mutex.RLock()
+		current, changed, list := s.index, s.changed, s.list(prefix)
+		s.mutex.RUnlock()
+		if index == 0 || current > index {
+			return list, current, nil
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return list, current, nil
+		}
+	}
+}
+
+// The mutex must be held.
+func (s *MemoryStore) list(prefix string) []Pair {
+	prefix = normalizeKey(prefix)
+	list := []Pair{}
+	for key, pair := range s.pairs {
+		if strings.HasPrefix(key, prefix) {
+			list = append(list, *copyPair(pair))
+		}
+	}
+	sort.Sort(byKey(list))
+	return list
+}
+
+func (s *MemoryStore) Put(key string, value []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key = normalizeKey(key)
+	s.pairs[key] = *copyPair(Pair{Key: key, Value: value, ModifyIndex: s.nextIndex()})
+	return nil
+}
+
+func (s *MemoryStore) CAS(key string, value []byte, modifyIndex uint64) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key = normalizeKey(key)
+	// The index of a missing pair is 0
+	if s.pairs[key].ModifyIndex != modifyIndex {
+		return false, nil
+	}
+	s.pairs[key] = *copyPair(Pair{Key: key, Value: value, ModifyIndex: s.nextIndex()})
+	return true, nil
+}
+
+func (s *MemoryStore) DeleteCAS(key string, modifyIndex uint64) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key = normalizeKey(key)
+	if pair, found := s.pairs[key]; !found || pair.ModifyIndex != modifyIndex {
+		return false, nil
+	}
+	delete(s.pairs, key)
+	s.nextIndex()
+	return true, nil
+}
+
+func (s *MemoryStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key = normalizeKey(key)
+	if _, found := s.pairs[key]; found {
+		delete(s.pairs, key)
+		s.nextIndex()
+	}
+	return nil
+}
+
+// Txn applies the operations while holding the lock, so that the readers see
+// all of them or none of them.
+func (s *MemoryStore) Txn(operations []Operation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, operation := range operations {
+		key := normalizeKey(operation.Key)
+		// The index of a missing pair is 0
+		if operation.Verb == KVCAS && s.pairs[key].ModifyIndex != operation.ModifyIndex {
+			return &ConflictError{Key: key}
+		}
+	}
+
+	index := s.nextIndex()
+	for _, operation := range operations {
+		key := normalizeKey(operation.Key)
+		if operation.Verb == KVDelete {
+			delete(s.pairs, key)
+		} else {
+			s.pairs[key] = *copyPair(Pair{Key: key, Value: operation.Value, ModifyIndex: index})
+		}
+	}
+	return nil
+}
+
+// Increments the index after a write and wakes up the blocked WaitList calls.
+// The mutex must be held.
+func (s *MemoryStore) nextIndex() uint64 {
+	s.index++
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return s.index
+}
+
+// The values are copied so that the stored pairs cannot be modified by the callers.
+func copyPair(pair Pair) *Pair {
+	value := make([]byte, len(pair.Value))
+	copy(value, pair.Value)
+	pair.Value = value
+	return &pair
+}
+
+type byKey []Pair
+
+func (p byKey) Len() int           { return len(p) }
+func (p byKey) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byKey) Less(i, j int) bool { return p[i].Key < p[j].Key }
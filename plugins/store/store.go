@@ -0,0 +1,66 @@
+// Package store abstracts the key/value stores holding the configurations of
+// the plugins (menus, routes, components...).
+//
+// Consul is the store used in production. The in-memory and the filesystem
+// stores are meant for tests and for local development.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// A key/value pair.
+type Pair struct {
+	Key   string
+	Value []byte
+
+	// The index of the last modification of the pair. Indexes only grow, but
+	// their values depend on the store.
+	ModifyIndex uint64
+}
+
+// KVStore is the interface of the key/value stores.
+//
+// The keys are slash separated paths, such as "agilestack/menu/my-plugin".
+// A leading slash is ignored.
+type KVStore interface {
+	// Get returns the pair matching the key, or nil if there is no such pair.
+	Get(key string) (*Pair, error)
+
+	// List returns the pairs whose key starts with the prefix, sorted by key.
+	List(prefix string) ([]Pair, error)
+
+	// Put creates or replaces the pair matching the key.
+	Put(key string, value []byte) error
+
+	// Delete deletes the pair matching the key, if any.
+	Delete(key string) error
+}
+
+// Stores the JSON representation of the value.
+func PutJSON(kv KVStore, key string, value interface{}) error {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return kv.Put(key, bytes)
+}
+
+// Loads the JSON representation stored under the key into the value.
+// Returns false if there is no such key.
+func GetJSON(kv KVStore, key string, value interface{}) (bool, error) {
+	pair, err := kv.Get(key)
+	if err != nil || pair == nil {
+		return false, err
+	}
+	if err := json.Unmarshal(pair.Value, value); err != nil {
+		return false, fmt.Errorf("Error while unmarshalling '%s': %v", pair.Key, err)
+	}
+	return true, nil
+}
+
+func normalizeKey(key string) string {
+	return strings.TrimLeft(key, "/")
+}
@@ -0,0 +1,141 @@
+package store_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/test"
+	"github.com/stretchr/testify/require"
+)
+
+// Checks the behaviour shared by all the stores.
+func testKVStore(t *testing.T, kv store.KVStore) {
+	pair, err := kv.Get("agilestack/menu/plugin")
+	require.Nil(t, err)
+	require.Nil(t, pair)
+
+	require.Nil(t, kv.Put("agilestack/menu/plugin", []byte("v1")))
+	require.Nil(t, kv.Put("agilestack/menu/other plugin éé", []byte("v2")))
+	require.Nil(t, kv.Put("/agilestack/components", []byte("v3")))
+	require.Nil(t, kv.Put("agilestack/components/nested", []byte("v4")))
+
+	pair, err = kv.Get("/agilestack/menu/plugin")
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+	require.Equal(t, "agilestack/menu/plugin", pair.Key)
+	require.Equal(t, "v1", string(pair.Value))
+
+	pairs, err := kv.List("agilestack/menu/")
+	require.Nil(t, err)
+	require.Equal(t, 2, len(pairs))
+	require.Equal(t, "agilestack/menu/other plugin éé", pairs[0].Key)
+	require.Equal(t, "v2", string(pairs[0].Value))
+	require.Equal(t, "agilestack/menu/plugin", pairs[1].Key)
+
+	pairs, err = kv.List("agilestack/components")
+	require.Nil(t, err)
+	require.Equal(t, 2, len(pairs))
+
+	pairs, err = kv.List("agilestack/unknown/")
+	require.Nil(t, err)
+	require.Equal(t, 0, len(pairs))
+
+	// Updating
+	require.Nil(t, kv.Put("agilestack/menu/plugin", []byte("v5")))
+	pair, err = kv.Get("agilestack/menu/plugin")
+	require.Nil(t, err)
+	require.Equal(t, "v5", string(pair.Value))
+
+	// Deleting
+	require.Nil(t, kv.Delete("agilestack/menu/plugin"))
+	require.Nil(t, kv.Delete("agilestack/menu/plugin"))
+	pair, err = kv.Get("agilestack/menu/plugin")
+	require.Nil(t, err)
+	require.Nil(t, pair)
+
+	// JSON helpers
+	require.Nil(t, store.PutJSON(kv, "agilestack/json", map[string]int{"a": 1}))
+	var value map[string]int
+	found, err := store.GetJSON(kv, "agilestack/json", &value)
+	require.Nil(t, err)
+	require.True(t, found)
+	require.Equal(t, map[string]int{"a": 1}, value)
+	found, err = store.GetJSON(kv, "agilestack/missing", &value)
+	require.Nil(t, err)
+	require.False(t, found)
+}
+
+func TestMemoryStore(t *testing.T) {
+	testKVStore(t, store.NewMemoryStore())
+}
+
+func TestMemoryStore_ValuesAreCopied(t *testing.T) {
+	kv := store.NewMemoryStore()
+	value := []byte("v1")
+	require.Nil(t, kv.Put("key", value))
+	value[0] = 'x'
+
+	pair, err := kv.Get("key")
+	require.Nil(t, err)
+	require.Equal(t, "v1", string(pair.Value))
+}
+
+func TestFileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "file-store")
+	require.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	testKVStore(t, store.NewFileStore(root))
+}
+
+func TestFileStore_InvalidKeys(t *testing.T) {
+	kv := store.NewFileStore("/nonexistent")
+	for _, key := range []string{"", "../etc/passwd", "agilestack/../../x", "agilestack//x"} {
+		require.NotNil(t, kv.Put(key, []byte("v")), key)
+	}
+
+	pairs, err := kv.List("")
+	require.Nil(t, err)
+	require.Equal(t, 0, len(pairs))
+}
+
+func TestConsulStore(t *testing.T) {
+	fake := test.NewFakeConsul()
+	defer fake.Close()
+	client, err := fake.Info().NewClient()
+	require.Nil(t, err)
+
+	testKVStore(t, store.NewConsulStore(client))
+}
+
+func TestConsulConfig_NewClient(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r
+		w.Header().Set("X-Consul-Index", "1")
+		w.Header().Set("X-Consul-LastContact", "0")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	config := store.ConsulConfig{
+		Address:    strings.TrimPrefix(server.URL, "http://"),
+		Token:      "secret-token",
+		Datacenter: "dc2",
+	}
+	kv, err := store.NewConsulStoreFromConfig(config)
+	require.Nil(t, err)
+
+	pair, err := kv.Get("agilestack/menu/plugin")
+	require.Nil(t, err)
+	require.Nil(t, pair)
+
+	request := <-requests
+	require.Equal(t, "dc2", request.URL.Query().Get("dc"))
+	require.Equal(t, "secret-token", request.Header.Get("X-Consul-Token"))
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// The kinds of operations of a transaction.
+type OperationVerb int
+
+const (
+	// Creates or replaces the pair.
+	KVSet OperationVerb = iota + 1
+
+	// Deletes the pair, if any.
+	KVDelete
+
+	// Creates or replaces the pair when its ModifyIndex is still the one of the
+	// operation, or when the index is 0 and there is no such pair (see
+	// CASStore). The transaction fails with a *ConflictError otherwise.
+	KVCAS
+)
+
+func (v OperationVerb) String() string {
+	switch v {
+	case KVSet:
+		return "set"
+	case KVDelete:
+		return "delete"
+	case KVCAS:
+		return "cas"
+	}
+	return "unknown"
+}
+
+// An operation of a transaction.
+type Operation struct {
+	Verb  OperationVerb
+	Key   string
+	Value []byte
+
+	// The expected ModifyIndex of the pair, for KVCAS
+	ModifyIndex uint64
+}
+
+// The error of a transaction whose KVCAS operation failed, the pair having been
+// modified since it was read. Nothing is applied.
+type ConflictError struct {
+	Key string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("The key \"%s\" was modified concurrently", e.Key)
+}
+
+// Returns the operation storing the JSON representation of the value.
+func SetJSON(key string, value interface{}) (Operation, error) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return Operation{}, err
+	}
+	return Operation{Verb: KVSet, Key: key, Value: bytes}, nil
+}
+
+// TransactionalStore is a KVStore able to apply several operations atomically.
+type TransactionalStore interface {
+	KVStore
+
+	// Txn applies all the operations, or none of them when one fails. Returns
+	// a *ConflictError when the pair of a KVCAS operation was modified.
+	Txn(operations []Operation) error
+}
+
+/*
+Applies the operations to the store: atomically if it is a TransactionalStore,
+otherwise one after the other, restoring the previous values of the pairs when
+an operation fails. Returns a *ConflictError when the pair of a KVCAS operation
+was modified.
+*/
+func Apply(kv KVStore, operations []Operation) error {
+	if txn, ok := kv.(TransactionalStore); ok {
+		return txn.Txn(operations)
+	}
+
+	previous := make([]*Pair, 0, len(operations))
+	for _, operation := range operations {
+		pair, err := kv.Get(operation.Key)
+		if err == nil {
+			previous = append(previous, pair)
+			err = applyOperation(kv, operation)
+		}
+		if err != nil {
+			if _, conflict := err.(*ConflictError); conflict {
+				// The pair was not written, and may be written by another client
+				previous = previous[:len(previous)-1]
+			}
+			rollback(kv, operations[:len(previous)], previous)
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOperation(kv KVStore, operation Operation) error {
+	switch operation.Verb {
+	case KVDelete:
+		return kv.Delete(operation.Key)
+	case KVCAS:
+		stored, err := CheckAndSet(kv, operation.Key, operation.Value, operation.ModifyIndex)
+		if err == nil && !stored {
+			err = &ConflictError{Key: normalizeKey(operation.Key)}
+		}
+		return err
+	}
+	return kv.Put(operation.Key, operation.Value)
+}
+
+// Restores the previous pairs of the keys of the operations, in reverse order.
+// The errors are logged.
+func rollback(kv KVStore, operations []Operation, previous []*Pair) {
+	for i := len(operations) - 1; i >= 0; i-- {
+		var err error
+		if previous[i] == nil {
+			err = kv.Delete(operations[i].Key)
+		} else {
+			err = kv.Put(operations[i].Key, previous[i].Value)
+		}
+		if err != nil {
+			log.Printf("Error while restoring the key '%s': %v", operations[i].Key, err)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package store_test
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/test"
+	"github.com/stretchr/testify/require"
+)
+
+func txnOperations(t *testing.T) []store.Operation {
+	menu, err := store.SetJSON("agilestack/menu/plugin", map[string]string{"pluginName": "plugin"})
+	require.Nil(t, err)
+	return []store.Operation{
+		menu,
+		{Verb: store.KVSet, Key: "/agilestack/components", Value: []byte("v2")},
+		{Verb: store.KVDelete, Key: "agilestack/registration/plugin"},
+	}
+}
+
+// Checks that the operations are applied.
+func testApply(t *testing.T, kv store.KVStore) {
+	require.Nil(t, kv.Put("agilestack/registration/plugin", []byte("v0")))
+
+	require.Nil(t, store.Apply(kv, txnOperations(t)))
+
+	pair, err := kv.Get("agilestack/menu/plugin")
+	require.Nil(t, err)
+	require.Equal(t, `{"pluginName":"plugin"}`, string(pair.Value))
+	pair, err = kv.Get("agilestack/components")
+	require.Nil(t, err)
+	require.Equal(t, "v2", string(pair.Value))
+	pair, err = kv.Get("agilestack/registration/plugin")
+	require.Nil(t, err)
+	require.Nil(t, pair)
+}
+
+func TestApply_MemoryStore(t *testing.T) {
+	testApply(t, store.NewMemoryStore())
+}
+
+func TestApply_ConsulStore(t *testing.T) {
+	fake := test.NewFakeConsul()
+	defer fake.Close()
+	client, err := fake.Info().NewClient()
+	require.Nil(t, err)
+
+	testApply(t, store.NewConsulStore(client))
+}
+
+func TestApply_FileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "file-store")
+	require.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	testApply(t, store.NewFileStore(root))
+}
+
+// A store failing to put a key.
+type failingPutStore struct {
+	store.KVStore
+	failingKey string
+}
+
+func (s *failingPutStore) Put(key string, value []byte) error {
+	if key == s.failingKey {
+		return errors.New("Connection refused")
+	}
+	return s.KVStore.Put(key, value)
+}
+
+func TestApply_Rollback(t *testing.T) {
+	kv := &failingPutStore{KVStore: store.NewMemoryStore(), failingKey: "agilestack/registration/plugin"}
+	require.Nil(t, kv.Put("agilestack/menu/plugin", []byte("v0")))
+
+	err := store.Apply(kv, []store.Operation{
+		{Verb: store.KVSet, Key: "agilestack/menu/plugin", Value: []byte("v1")},
+		{Verb: store.KVSet, Key: "agilestack/components", Value: []byte("v2")},
+		{Verb: store.KVSet, Key: "agilestack/registration/plugin", Value: []byte("v3")},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Connection refused", err.Error())
+
+	// The previous values are restored
+	pair, err := kv.Get("agilestack/menu/plugin")
+	require.Nil(t, err)
+	require.Equal(t, "v0", string(pair.Value))
+	pair, err = kv.Get("agilestack/components")
+	require.Nil(t, err)
+	require.Nil(t, pair)
+}
+
+func TestMemoryStore_Txn_Index(t *testing.T) {
+	kv := store.NewMemoryStore()
+	require.Nil(t, kv.Txn(txnOperations(t)))
+
+	menu, err := kv.Get("agilestack/menu/plugin")
+	require.Nil(t, err)
+	components, err := kv.Get("agilestack/components")
+	require.Nil(t, err)
+	require.Equal(t, menu.ModifyIndex, components.ModifyIndex)
+}
+
+// Checks that a transaction whose KVCAS operation conflicts applies nothing.
+func testApplyConflict(t *testing.T, kv store.KVStore) {
+	require.Nil(t, kv.Put("agilestack/catalog", []byte("v0")))
+	pair, err := kv.Get("agilestack/catalog")
+	require.Nil(t, err)
+
+	require.Nil(t, store.Apply(kv, []store.Operation{
+		{Verb: store.KVSet, Key: "agilestack/menu/plugin", Value: []byte("v1")},
+		{Verb: store.KVCAS, Key: "agilestack/catalog", Value: []byte("v1"), ModifyIndex: pair.ModifyIndex},
+		{Verb: store.KVCAS, Key: "agilestack/other", Value: []byte("v1")},
+	}))
+
+	// The catalog was modified since it was read
+	err = store.Apply(kv, []store.Operation{
+		{Verb: store.KVSet, Key: "agilestack/menu/plugin", Value: []byte("v2")},
+		{Verb: store.KVCAS, Key: "agilestack/catalog", Value: []byte("v2"), ModifyIndex: pair.ModifyIndex},
+	})
+	require.NotNil(t, err)
+	conflict, ok := err.(*store.ConflictError)
+	require.True(t, ok, err.Error())
+	require.Equal(t, "agilestack/catalog", conflict.Key)
+
+	// The other key exists
+	err = store.Apply(kv, []store.Operation{{Verb: store.KVCAS, Key: "/agilestack/other", Value: []byte("v2")}})
+	require.NotNil(t, err)
+	require.Equal(t, "The key \"agilestack/other\" was modified concurrently", err.Error())
+
+	for _, key := range []string{"agilestack/menu/plugin", "agilestack/catalog", "agilestack/other"} {
+		pair, err := kv.Get(key)
+		require.Nil(t, err)
+		require.Equal(t, "v1", string(pair.Value), key)
+	}
+}
+
+func TestApply_Conflict_MemoryStore(t *testing.T) {
+	testApplyConflict(t, store.NewMemoryStore())
+}
+
+func TestApply_Conflict_ConsulStore(t *testing.T) {
+	fake := test.NewFakeConsul()
+	defer fake.Close()
+	client, err := fake.Info().NewClient()
+	require.Nil(t, err)
+
+	testApplyConflict(t, store.NewConsulStore(client))
+}
+
+func TestApply_Conflict_FileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "file-store")
+	require.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	testApplyConflict(t, store.NewFileStore(root))
+}
+
+func TestConsulStore_Txn_TooManyOperations(t *testing.T) {
+	fake := test.NewFakeConsul()
+	defer fake.Close()
+	client, err := fake.Info().NewClient()
+	require.Nil(t, err)
+	kv := store.NewConsulStore(client)
+
+	operations := []store.Operation{}
+	for i := 0; i <= store.MaxConsulTxnOperations; i++ {
+		operations = append(operations, store.Operation{Verb: store.KVSet, Key: fmt.Sprintf("agilestack/key%d", i), Value: []byte("v")})
+	}
+	err = store.Apply(kv, operations)
+	require.NotNil(t, err)
+	require.Equal(t, "Unable to apply 65 operations in a transaction: Consul accepts 64 operations at most", err.Error())
+
+	pairs, err := kv.List("agilestack/")
+	require.Nil(t, err)
+	require.Equal(t, 0, len(pairs))
+
+	require.Nil(t, store.Apply(kv, operations[:store.MaxConsulTxnOperations]))
+}
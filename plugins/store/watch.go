@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+)
+
+// WatchableStore is a KVStore able to wait for the changes of its pairs.
+type WatchableStore interface {
+	KVStore
+
+	// WaitList returns the pairs whose key starts with the prefix, like List,
+	// once the index of the store is greater than the given index or after the
+	// wait duration. It also returns the index of the store, to give to the
+	// next call. An index of 0 does not wait.
+	WaitList(prefix string, index uint64, wait time.Duration) ([]Pair, uint64, error)
+}
+
+// The kinds of changes of a pair.
+type EventType int
+
+const (
+	Added EventType = iota + 1
+	Updated
+	Removed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Added:
+		return "added"
+	case Updated:
+		return "updated"
+	case Removed:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// A change of a watched pair.
+type Event struct {
+	Type EventType
+
+	// The pair after the change, or the last known pair when it is removed.
+	Pair Pair
+}
+
+/*
+A Watcher notifies the changes of the pairs under a prefix.
+
+The watchable stores are watched with blocking queries. The other stores are
+listed periodically. The errors are logged and the store is queried again after
+a delay doubling after each consecutive failure.
+*/
+type Watcher struct {
+	// The maximum duration of a blocking query.
+	WaitTime time.Duration
+
+	// The delay between two listings of the stores which cannot block.
+	PollInterval time.Duration
+
+	// The delays before querying the store again after an error.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// The watcher used by Watch.
+var DefaultWatcher = Watcher{
+	WaitTime:     5 * time.Minute,
+	PollInterval: 5 * time.Second,
+	MinBackoff:   time.Second,
+	MaxBackoff:   time.Minute,
+}
+
+// Watches the pairs under the prefix with the DefaultWatcher.
+func Watch(ctx context.Context, kv KVStore, prefix string) <-chan Event {
+	return DefaultWatcher.Watch(ctx, kv, prefix)
+}
+
+/*
+Watches the pairs under the prefix until the context is done, then closes the
+returned channel.
+
+The pairs existing when the watch starts are notified as added. Then each
+change is notified once, in the order of the keys: the changes happening
+between two queries of the store are merged.
+*/
+func (w Watcher) Watch(ctx context.Context, kv KVStore, prefix string) <-chan Event {
+	events := make(chan Event)
+	go func() {
+		defer close(events)
+		w.run(ctx, kv, prefix, events)
+	}()
+	return events
+}
+
+// The result of a query of the store.
+type listing struct {
+	pairs []Pair
+	index uint64
+	err   error
+}
+
+func (w Watcher) run(ctx context.Context, kv KVStore, prefix string, events chan<- Event) {
+	watchable, blocking := kv.(WatchableStore)
+	known := map[string]Pair{}
+	var index uint64
+	backoff := time.Duration(0)
+	for {
+		// The query runs in its own goroutine so that a blocking query does not
+		// delay the end of the watch.
+		results := make(chan listing, 1)
+		go func(index uint64) {
+			if blocking {
+				pairs, next, err := watchable.WaitList(prefix, index, w.WaitTime)
+				results <- listing{pairs, next, err}
+				return
+			}
+			pairs, err := kv.List(prefix)
+			results <- listing{pairs: pairs, err: err}
+		}(index)
+
+		var result listing
+		select {
+		case <-ctx.Done():
+			return
+		case result = <-results:
+		}
+
+		delay := w.PollInterval
+		if result.err != nil {
+			backoff = nextBackoff(backoff, w.MinBackoff, w.MaxBackoff)
+			log.Printf("Error while watching '%s', retrying in %v: %v", prefix, backoff, result.err)
+			delay = backoff
+		} else {
+			backoff = 0
+			for _, event := range changes(known, result.pairs) {
+				select {
+				case <-ctx.Done():
+					return
+				case events <- event:
+				}
+			}
+			if blocking {
+				index = nextIndex(index, result.index)
+				delay = 0
+			}
+		}
+
+		if delay > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+	}
+}
+
+// Returns the index of the next blocking query. As recommended by Consul, the
+// index is reset when it goes backwards, and never is 0 after the first query
+// so that the next query blocks.
+func nextIndex(previous, current uint64) uint64 {
+	if current < previous || current == 0 {
+		return 1
+	}
+	return current
+}
+
+func nextBackoff(backoff, min, max time.Duration) time.Duration {
+	if backoff < min {
+		return min
+	}
+	backoff *= 2
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
+
+// Returns the events turning the known pairs into the listed ones, sorted by
+// key, and updates the known pairs.
+func changes(known map[string]Pair, pairs []Pair) []Event {
+	events := []Event{}
+	listed := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		listed[pair.Key] = true
+		previous, found := known[pair.Key]
+		switch {
+		case !found:
+			events = append(events, Event{Type: Added, Pair: pair})
+		case previous.ModifyIndex != pair.ModifyIndex:
+			events = append(events, Event{Type: Updated, Pair: pair})
+		default:
+			continue
+		}
+		known[pair.Key] = pair
+	}
+	for key, pair := range known {
+		if !listed[key] {
+			events = append(events, Event{Type: Removed, Pair: pair})
+			delete(known, key)
+		}
+	}
+	sort.Sort(byEventKey(events))
+	return events
+}
+
+type byEventKey []Event
+
+func (e byEventKey) Len() int           { return len(e) }
+func (e byEventKey) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
+func (e byEventKey) Less(i, j int) bool { return e[i].Pair.Key < e[j].Pair.Key }
@@ -0,0 +1,148 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/test"
+	"github.com/stretchr/testify/require"
+)
+
+var testWatcher = store.Watcher{
+	WaitTime:     time.Second,
+	PollInterval: 10 * time.Millisecond,
+	MinBackoff:   10 * time.Millisecond,
+	MaxBackoff:   50 * time.Millisecond,
+}
+
+// Returns the next event, failing after a timeout.
+func nextEvent(t *testing.T, events <-chan store.Event) store.Event {
+	select {
+	case event, open := <-events:
+		require.True(t, open, "The events channel is closed")
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "No event received")
+	}
+	return store.Event{}
+}
+
+func requireEvent(t *testing.T, events <-chan store.Event, eventType store.EventType, key, value string) {
+	event := nextEvent(t, events)
+	require.Equal(t, eventType, event.Type)
+	require.Equal(t, key, event.Pair.Key)
+	require.Equal(t, value, string(event.Pair.Value))
+}
+
+// Checks the events notified by a watch of the store.
+func testWatch(t *testing.T, kv store.KVStore) {
+	require.Nil(t, kv.Put("agilestack/menu/plugin-1", []byte("v1")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := testWatcher.Watch(ctx, kv, "agilestack/menu/")
+	requireEvent(t, events, store.Added, "agilestack/menu/plugin-1", "v1")
+
+	require.Nil(t, kv.Put("agilestack/menu/plugin-2", []byte("v2")))
+	requireEvent(t, events, store.Added, "agilestack/menu/plugin-2", "v2")
+
+	// The pairs out of the prefix are ignored
+	require.Nil(t, kv.Put("agilestack/components", []byte("c")))
+
+	require.Nil(t, kv.Put("agilestack/menu/plugin-1", []byte("v1 bis")))
+	requireEvent(t, events, store.Updated, "agilestack/menu/plugin-1", "v1 bis")
+
+	require.Nil(t, kv.Delete("agilestack/menu/plugin-2"))
+	requireEvent(t, events, store.Removed, "agilestack/menu/plugin-2", "v2")
+
+	cancel()
+	select {
+	case event, open := <-events:
+		require.False(t, open, "Unexpected event %v", event)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "The events channel is not closed")
+	}
+}
+
+func TestWatch_MemoryStore(t *testing.T) {
+	testWatch(t, store.NewMemoryStore())
+}
+
+func TestWatch_ConsulStore(t *testing.T) {
+	fake := test.NewFakeConsul()
+	defer fake.Close()
+	client, err := fake.Info().NewClient()
+	require.Nil(t, err)
+
+	testWatch(t, store.NewConsulStore(client))
+}
+
+func TestWatch_FileStore(t *testing.T) {
+	root, err := ioutil.TempDir("", "filestore")
+	require.Nil(t, err)
+	defer os.RemoveAll(root)
+
+	testWatch(t, store.NewFileStore(root))
+}
+
+// A store failing the first listings.
+type failingStore struct {
+	*store.MemoryStore
+
+	mutex    sync.Mutex
+	failures int
+}
+
+func (s *failingStore) WaitList(prefix string, index uint64, wait time.Duration) ([]store.Pair, uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, 0, errors.New("Connection refused")
+	}
+	return s.MemoryStore.WaitList(prefix, index, wait)
+}
+
+func TestWatch_Reconnect(t *testing.T) {
+	kv := &failingStore{MemoryStore: store.NewMemoryStore(), failures: 3}
+	require.Nil(t, kv.Put("agilestack/menu/plugin-1", []byte("v1")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := testWatcher.Watch(ctx, kv, "agilestack/menu/")
+	requireEvent(t, events, store.Added, "agilestack/menu/plugin-1", "v1")
+
+	kv.mutex.Lock()
+	kv.failures = 2
+	kv.mutex.Unlock()
+	require.Nil(t, kv.Put("agilestack/menu/plugin-1", []byte("v2")))
+	requireEvent(t, events, store.Updated, "agilestack/menu/plugin-1", "v2")
+}
+
+func TestMemoryStore_WaitList(t *testing.T) {
+	kv := store.NewMemoryStore()
+	_, index, err := kv.WaitList("agilestack/", 0, time.Second)
+	require.Nil(t, err)
+
+	// No change: returns after the wait duration
+	start := time.Now()
+	pairs, next, err := kv.WaitList("agilestack/", index, 50*time.Millisecond)
+	require.Nil(t, err)
+	require.Equal(t, 0, len(pairs))
+	require.Equal(t, index, next)
+	require.True(t, time.Since(start) >= 50*time.Millisecond)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		kv.Put("agilestack/menu/plugin", []byte("v1"))
+	}()
+	pairs, next, err = kv.WaitList("agilestack/", index, 5*time.Second)
+	require.Nil(t, err)
+	require.Equal(t, 1, len(pairs))
+	require.True(t, next > index)
+}
@@ -9,45 +9,80 @@ import (
 	"github.com/eogile/agilestack-utils/test"
 )
 
-var dockerClient *dockerclient.DockerClient
-
 const (
 	network = "networkPluginsTest"
+
+	// Set this environment variable to "docker" to run the tests against a
+	// Consul container instead of the in-memory fake.
+	consulModeEnv = "AGILESTACK_TEST_CONSUL"
 )
 
 /*
-Starts the Consul container and initializes the Consul client.
- */
-func setupResources() {
-	test.StartConsulContainer(dockerClient, network)
-}
+Starts Consul, runs the tests, then stops Consul.
 
-/*
-Stops and removes the Consul container.
- */
-func tearDownResources() {
-	test.RemoveConsulContainer(dockerClient)
+By default, Consul is an in-memory fake of its KV API, so no Docker is
+required. A Consul container is used when the AGILESTACK_TEST_CONSUL
+environment variable is "docker".
+
+The given setUp functions are called with the connection information of
+Consul before the tests are run.
+*/
+func DoTestMain(m *testing.M, setUps ...func(consul *test.ConsulInfo)) {
+	if os.Getenv(consulModeEnv) == "docker" {
+		doTestMainWithContainer(m, setUps)
+		return
+	}
+
+	fake := test.NewFakeConsul()
+	for _, setUp := range setUps {
+		setUp(fake.Info())
+	}
+
+	exitCode := m.Run()
+	fake.Close()
+
+	os.Exit(exitCode)
 }
 
-func DoTestMain(m *testing.M) {
+func doTestMainWithContainer(m *testing.M, setUps []func(consul *test.ConsulInfo)) {
 	/*
 	Docker client for test utilities
 	 */
-	dockerClient = dockerclient.NewClient()
+	dockerClient := dockerclient.NewClient()
 
 	/*
 	Creating the Docker network if it does not exist.
 	 */
 	dockerClient.CreateNetWorkIfNeeded(network)
 
-	setupResources()
+	fixture := test.NewFixture(dockerClient, network)
+	fixture.CleanupOnInterrupt()
+
+	consul, err := fixture.StartConsul()
+	if err != nil {
+		log.Println("Cannot start consul : ", err)
+		tearDown(fixture)
+		os.Exit(1)
+	}
+	for _, setUp := range setUps {
+		setUp(consul)
+	}
 
 	exitCode := m.Run()
-	tearDownResources()
+	tearDown(fixture)
 
 	os.Exit(exitCode)
 }
 
+/*
+Stops and removes the Consul container.
+ */
+func tearDown(fixture *test.Fixture) {
+	if err := fixture.Cleanup(); err != nil {
+		log.Println(err)
+	}
+}
+
 func init() {
 	log.SetFlags(log.Lshortfile | log.Ldate | log.Ltime)
 }
@@ -0,0 +1,356 @@
+package translations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
+)
+
+const (
+	// The contributions of the plugins: agilestack/translations/<plugin>/<lang>
+	contributionsPrefix = "agilestack/translations/"
+
+	// The merged catalogs: agileStack/translations/<lang>. The prefix keeps its
+	// historical case, so tenant.Key does not trim it: the catalogs of a tenant
+	// are stored under agilestack/tenants/<tenant>/agileStack/translations/<lang>,
+	// apart from its contributions under agilestack/tenants/<tenant>/translations/
+	catalogPrefix = "agileStack/translations/"
+
+	// Number of attempts to rebuild a catalog modified concurrently
+	maxRebuildAttempts = 10
+)
+
+// TranslationsStore stores the translations contributed by the plugins in a
+// key/value store, and the catalogs merging them for each language.
+type TranslationsStore struct {
+	kv store.KVStore
+}
+
+// Returns a translations store backed by the given key/value store.
+func NewTranslationsStore(kv store.KVStore) *TranslationsStore {
+	return &TranslationsStore{kv: kv}
+}
+
+/*
+Stores the translations contributed by the plugin to the language, replacing
+its previous contribution, then updates the catalog of the language, for the
+tenant of the context.
+*/
+func (s *TranslationsStore) StoreTranslations(ctx context.Context, pluginName, lang string, translations Translations) error {
+	if err := Validate(pluginName, lang, translations); err != nil {
+		return err
+	}
+
+	if err := store.PutJSON(s.kv, contributionKey(ctx, pluginName, lang), translations); err != nil {
+		log.Println("Error while storing the translations:", err)
+		return err
+	}
+	return s.rebuildCatalog(ctx, lang)
+}
+
+// Loads the catalog of the language, merging the translations of all the
+// plugins, for the tenant of the context. The catalog is empty when no plugin
+// contributes to the language.
+func (s *TranslationsStore) GetTranslations(ctx context.Context, lang string) (Translations, error) {
+	if err := ValidateLanguage(lang); err != nil {
+		return nil, err
+	}
+	translations := Translations{}
+	if _, err := store.GetJSON(s.kv, catalogKey(ctx, lang), &translations); err != nil {
+		log.Println("Error while loading the translations:", err)
+		return nil, err
+	}
+	return translations, nil
+}
+
+// Loads the translations contributed by the plugin to the language, for the
+// tenant of the context. Returns nil if there is none.
+func (s *TranslationsStore) GetPluginTranslations(ctx context.Context, pluginName, lang string) (Translations, error) {
+	translations := Translations{}
+	found, err := store.GetJSON(s.kv, contributionKey(ctx, pluginName, lang), &translations)
+	if err != nil || !found {
+		return nil, err
+	}
+	return translations, nil
+}
+
+// Lists the languages some plugins contribute to, for the tenant of the context.
+func (s *TranslationsStore) ListLanguages(ctx context.Context) ([]string, error) {
+	contributions, err := s.listContributions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	languages := []string{}
+	for lang := range contributions {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages, nil
+}
+
+/*
+Deletes the translations contributed by the plugin to all the languages, then
+updates the catalogs of these languages, for the tenant of the context.
+*/
+func (s *TranslationsStore) DeleteTranslations(ctx context.Context, pluginName string) error {
+	contributions, err := s.listContributions(ctx)
+	if err != nil {
+		return err
+	}
+	for lang, byPlugin := range contributions {
+		if _, found := byPlugin[pluginName]; !found {
+			continue
+		}
+		if err := s.kv.Delete(contributionKey(ctx, pluginName, lang)); err != nil {
+			return err
+		}
+		if err := s.rebuildCatalog(ctx, lang); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Merges the contributions to the language into its catalog. The catalog is
+deleted when there is no contribution left.
+
+The catalog is written with a check-and-set on the index it had before the
+contributions were listed (see store.CASStore): when another rebuild wrote it
+in the meantime, possibly with contributions listed later, the rebuild starts
+over so that no contribution is lost.
+*/
+func (s *TranslationsStore) rebuildCatalog(ctx context.Context, lang string) error {
+	key := catalogKey(ctx, lang)
+	for attempt := 0; attempt < maxRebuildAttempts; attempt++ {
+		var modifyIndex uint64
+		pair, err := s.kv.Get(key)
+		if err != nil {
+			return err
+		}
+		if pair != nil {
+			modifyIndex = pair.ModifyIndex
+		}
+
+		contributions, err := s.listContributions(ctx)
+		if err != nil {
+			return err
+		}
+		byPlugin := contributions[lang]
+
+		var stored bool
+		if len(byPlugin) == 0 {
+			if pair == nil {
+				return nil
+			}
+			stored, err = store.CheckAndDelete(s.kv, key, modifyIndex)
+		} else {
+			var catalog []byte
+			if catalog, err = json.Marshal(mergeContributions(byPlugin)); err != nil {
+				return err
+			}
+			stored, err = store.CheckAndSet(s.kv, key, catalog, modifyIndex)
+		}
+		if err != nil {
+			log.Println("Error while storing the translations catalog:", err)
+			return err
+		}
+		if stored {
+			return nil
+		}
+		log.Printf("Translations catalog '%s' was modified concurrently, retrying", key)
+	}
+	return fmt.Errorf("Unable to update the translations catalog \"%s\": too many concurrent modifications", lang)
+}
+
+/*
+Returns the operations storing the translations contributed by the plugin to the
+languages, replacing its previous contributions, and the catalogs of these
+languages, for the tenant of the context, to apply with other operations (see
+store.Apply).
+
+The catalogs are written with KVCAS operations on the index they had before the
+contributions were listed: when another plugin updates a catalog meanwhile,
+applying the operations fails with a *store.ConflictError, and the operations
+must be computed again.
+*/
+func (s *TranslationsStore) StoreOperations(ctx context.Context, pluginName string, catalogs map[string]Translations) ([]store.Operation, error) {
+	languages := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		if err := Validate(pluginName, lang, catalogs[lang]); err != nil {
+			return nil, err
+		}
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+
+	indexes := map[string]uint64{}
+	for _, lang := range languages {
+		pair, err := s.kv.Get(catalogKey(ctx, lang))
+		if err != nil {
+			return nil, err
+		}
+		if pair != nil {
+			indexes[lang] = pair.ModifyIndex
+		}
+	}
+	contributions, err := s.listContributions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := []store.Operation{}
+	for _, lang := range languages {
+		operation, err := store.SetJSON(contributionKey(ctx, pluginName, lang), catalogs[lang])
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+
+		byPlugin := map[string]Translations{pluginName: catalogs[lang]}
+		for otherPluginName, translations := range contributions[lang] {
+			if otherPluginName != pluginName {
+				byPlugin[otherPluginName] = translations
+			}
+		}
+		catalog, err := json.Marshal(mergeContributions(byPlugin))
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, store.Operation{
+			Verb:        store.KVCAS,
+			Key:         catalogKey(ctx, lang),
+			Value:       catalog,
+			ModifyIndex: indexes[lang],
+		})
+	}
+	return operations, nil
+}
+
+// Merges the contributions of the plugins, by plugin name.
+func mergeContributions(byPlugin map[string]Translations) Translations {
+	list := make([]contribution, 0, len(byPlugin))
+	for pluginName, translations := range byPlugin {
+		list = append(list, contribution{pluginName: pluginName, translations: translations})
+	}
+	return merge(list)
+}
+
+// Lists the contributions of the tenant of the context, by language then
+// plugin name.
+func (s *TranslationsStore) listContributions(ctx context.Context) (map[string]map[string]Translations, error) {
+	prefix := tenant.Key(ctx, contributionsPrefix)
+	pairs, err := s.kv.List(prefix)
+	if err != nil {
+		log.Println("Error while listing the translations:", err)
+		return nil, err
+	}
+
+	contributions := map[string]map[string]Translations{}
+	for _, pair := range pairs {
+		parts := strings.Split(strings.TrimPrefix(pair.Key, prefix), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		pluginName, lang := parts[0], parts[1]
+		translations := Translations{}
+		if err := json.Unmarshal(pair.Value, &translations); err != nil {
+			return nil, fmt.Errorf("Error while unmarshalling '%s': %v", pair.Key, err)
+		}
+		if contributions[lang] == nil {
+			contributions[lang] = map[string]Translations{}
+		}
+		contributions[lang][pluginName] = translations
+	}
+	return contributions, nil
+}
+
+func contributionKey(ctx context.Context, pluginName, lang string) string {
+	return tenant.Key(ctx, contributionsPrefix+pluginName+"/"+lang)
+}
+
+func catalogKey(ctx context.Context, lang string) string {
+	return tenant.Key(ctx, catalogPrefix+lang)
+}
+
+// Stores the translations contributed by the plugin to the language.
+func StoreTranslations(pluginName, lang string, translations Translations) error {
+	return StoreTranslationsContext(context.Background(), pluginName, lang, translations)
+}
+
+// Stores the translations contributed by the plugin to the language, for the
+// tenant of the context.
+func StoreTranslationsContext(ctx context.Context, pluginName, lang string, translations Translations) error {
+	s, err := DefaultStore()
+	if err != nil {
+		return err
+	}
+	return s.StoreTranslations(ctx, pluginName, lang, translations)
+}
+
+// Loads the catalog of the language.
+func GetTranslations(lang string) (Translations, error) {
+	return GetTranslationsContext(context.Background(), lang)
+}
+
+// Loads the catalog of the language, for the tenant of the context.
+func GetTranslationsContext(ctx context.Context, lang string) (Translations, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTranslations(ctx, lang)
+}
+
+// Deletes the translations contributed by the plugin to all the languages.
+func DeleteTranslations(pluginName string) error {
+	return DeleteTranslationsContext(context.Background(), pluginName)
+}
+
+// Deletes the translations contributed by the plugin to all the languages,
+// for the tenant of the context.
+func DeleteTranslationsContext(ctx context.Context, pluginName string) error {
+	s, err := DefaultStore()
+	if err != nil {
+		return err
+	}
+	return s.DeleteTranslations(ctx, pluginName)
+}
+
+// Returns a translations store backed by the Consul matching the configuration.
+func NewStore(config store.ConsulConfig) (*TranslationsStore, error) {
+	kv, err := store.NewConsulStoreFromConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewTranslationsStore(kv), nil
+}
+
+// The key/value store of the package-level functions.
+var defaultKV store.Override
+
+// Returns the store used by the package-level functions, backed by the default
+// key/value store unless another one is set (see store.Override).
+func DefaultStore() (*TranslationsStore, error) {
+	kv, err := defaultKV.Store()
+	if err != nil {
+		return nil, err
+	}
+	return NewTranslationsStore(kv), nil
+}
+
+// Changes the store used by the package-level functions. A nil store restores
+// the default one.
+func SetDefaultStore(s *TranslationsStore) {
+	if s == nil {
+		defaultKV.Set(nil)
+		return
+	}
+	defaultKV.Set(s.kv)
+}
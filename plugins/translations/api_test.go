@@ -0,0 +1,166 @@
+package translations_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	"github.com/eogile/agilestack-utils/tenant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreTranslations(t *testing.T) {
+	deleteAll(t)
+
+	require.Nil(t, translations.StoreTranslations("clients", "fr", clientsFr))
+	require.Nil(t, translations.StoreTranslations("accounts", "fr", accountsFr))
+
+	pair, _, err := consulClient(t).KV().Get("agileStack/translations/fr", nil)
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+	catalog := translations.Translations{}
+	require.Nil(t, json.Unmarshal(pair.Value, &catalog))
+
+	// The plugin "accounts" comes first and wins the conflict on "security.permissions.create"
+	require.Equal(t, map[string]string{
+		"security.resources.accounts": "comptes utilisateurs",
+		"security.resources.clients":  "clients",
+		"security.permissions.create": "Créer",
+	}, translations.Flatten(catalog))
+
+	pair, _, err = consulClient(t).KV().Get("agilestack/translations/clients/fr", nil)
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+}
+
+func TestStoreTranslations_Invalid(t *testing.T) {
+	deleteAll(t)
+
+	err := translations.StoreTranslations("accounts", "french", accountsFr)
+	require.NotNil(t, err)
+	require.Equal(t, "The language does not match the pattern \"^[a-z]{2,3}([_\\-][a-zA-Z0-9]+)*$\": \"french\"", err.Error())
+
+	err = translations.StoreTranslations("accounts", "fr", translations.Translations{
+		"security": map[string]interface{}{"count": 3},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Translation must be a string or an object: \"security.count\"", err.Error())
+
+	err = translations.StoreTranslations("accounts", "fr", translations.Translations{"security.count": "Nombre"})
+	require.NotNil(t, err)
+	require.Equal(t, "Translation key must not be empty nor contain a dot: \"security.count\"", err.Error())
+
+	err = translations.StoreTranslations("my/plugin", "fr", accountsFr)
+	require.NotNil(t, err)
+	require.Equal(t, "Plugin name must not contain a slash: \"my/plugin\"", err.Error())
+}
+
+func TestDeleteTranslations(t *testing.T) {
+	deleteAll(t)
+
+	require.Nil(t, translations.StoreTranslations("accounts", "fr", accountsFr))
+	require.Nil(t, translations.StoreTranslations("accounts", "en", translations.Translations{"app": "Accounts"}))
+	require.Nil(t, translations.StoreTranslations("clients", "fr", clientsFr))
+
+	require.Nil(t, translations.DeleteTranslations("accounts"))
+
+	catalog, err := translations.GetTranslations("fr")
+	require.Nil(t, err)
+	require.Equal(t, translations.Flatten(clientsFr), translations.Flatten(catalog))
+
+	// The catalog of a language without contribution is deleted
+	pair, _, err := consulClient(t).KV().Get("agileStack/translations/en", nil)
+	require.Nil(t, err)
+	require.Nil(t, pair)
+
+	catalog, err = translations.GetTranslations("en")
+	require.Nil(t, err)
+	require.Equal(t, 0, len(catalog))
+}
+
+func TestTranslationsStore_MemoryStore(t *testing.T) {
+	kv := store.NewMemoryStore()
+	translationsStore := translations.NewTranslationsStore(kv)
+	ctx := tenant.NewContext(context.Background(), "acme")
+
+	require.Nil(t, translationsStore.StoreTranslations(ctx, "accounts", "fr", accountsFr))
+	require.Nil(t, translationsStore.StoreTranslations(ctx, "accounts", "en", translations.Translations{"app": "Accounts"}))
+
+	languages, err := translationsStore.ListLanguages(ctx)
+	require.Nil(t, err)
+	require.Equal(t, []string{"en", "fr"}, languages)
+
+	contribution, err := translationsStore.GetPluginTranslations(ctx, "accounts", "fr")
+	require.Nil(t, err)
+	require.Equal(t, translations.Flatten(accountsFr), translations.Flatten(contribution))
+
+	pair, err := kv.Get("agilestack/tenants/acme/translations/accounts/fr")
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+	// The catalogs of the tenant are kept apart from its contributions
+	pair, err = kv.Get("agilestack/tenants/acme/agileStack/translations/fr")
+	require.Nil(t, err)
+	require.NotNil(t, pair)
+	pairs, err := kv.List("agilestack/tenants/acme/translations/")
+	require.Nil(t, err)
+	require.Equal(t, 2, len(pairs))
+
+	// The other tenants do not see the translations
+	languages, err = translationsStore.ListLanguages(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 0, len(languages))
+
+	require.Nil(t, translationsStore.DeleteTranslations(ctx, "accounts"))
+	languages, err = translationsStore.ListLanguages(ctx)
+	require.Nil(t, err)
+	require.Equal(t, 0, len(languages))
+}
+
+// A memory store returning its first listing late, so that the first rebuild
+// of a catalog overlaps with the next ones.
+type slowListStore struct {
+	*store.MemoryStore
+	listed *int32
+}
+
+func (s slowListStore) List(prefix string) ([]store.Pair, error) {
+	pairs, err := s.MemoryStore.List(prefix)
+	if atomic.AddInt32(s.listed, 1) == 1 {
+		time.Sleep(100 * time.Millisecond)
+	}
+	return pairs, err
+}
+
+func TestStoreTranslations_Concurrent(t *testing.T) {
+	translationsStore := translations.NewTranslationsStore(slowListStore{store.NewMemoryStore(), new(int32)})
+	ctx := context.Background()
+
+	plugins := []string{"accounts", "clients", "groups", "policies", "roles"}
+	var wg sync.WaitGroup
+	errs := make(chan error, len(plugins))
+	for i, pluginName := range plugins {
+		wg.Add(1)
+		go func(i int, pluginName string) {
+			defer wg.Done()
+			// The first plugin lists the contributions before the other ones store theirs
+			time.Sleep(time.Duration(i) * 10 * time.Millisecond)
+			errs <- translationsStore.StoreTranslations(ctx, pluginName, "fr", translations.Translations{
+				"app": map[string]interface{}{pluginName: pluginName},
+			})
+		}(i, pluginName)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.Nil(t, err)
+	}
+
+	catalog, err := translationsStore.GetTranslations(ctx, "fr")
+	require.Nil(t, err)
+	require.Equal(t, len(plugins), len(translations.Flatten(catalog)))
+}
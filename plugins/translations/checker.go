@@ -0,0 +1,390 @@
+package translations
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+)
+
+// The contexts of the translations of the plugins elements.
+const (
+	// security.resources.<resource key>
+	ResourcesContext = "security.resources"
+
+	// security.permissions.<permission>, or
+	// security.permissions.<resource key>.<permission> for a specific resource
+	PermissionsContext = "security.permissions"
+
+	// components.<component name>, for the components of the routes
+	ComponentsContext = "components"
+)
+
+// The kinds of translation issues.
+type IssueKind string
+
+const (
+	// A translation is missing in a language.
+	MissingTranslation IssueKind = "missing"
+
+	// A translation of a resource, a permission or a component does not match
+	// any registered one.
+	OrphanedTranslation IssueKind = "orphaned"
+
+	// A translation does not have the same placeholders count ("%s", "%d"...)
+	// as in the reference language.
+	PlaceholderMismatch IssueKind = "placeholders"
+)
+
+// A problem of the translations of a language.
+type Issue struct {
+	Kind     IssueKind `json:"kind"`
+	Language string    `json:"language"`
+	Key      string    `json:"key"`
+	Context  string    `json:"context,omitempty"`
+
+	// What references the key, such as "resource accounts".
+	Source string `json:"source"`
+}
+
+func (i Issue) String() string {
+	key := i.Key
+	if i.Context != "" {
+		key += " (" + i.Context + ")"
+	}
+	return fmt.Sprintf("[%s] %s %s: %s", i.Language, i.Kind, key, i.Source)
+}
+
+// What the translations are checked against.
+type CheckInput struct {
+	Resources      []resource.Resource
+	Menus          []menu.Menu
+	Configurations []registration.PluginConfiguration
+
+	// The catalogs, by language.
+	Catalogs map[string]Translations
+
+	// The language the placeholders of the other languages are compared with,
+	// which is also required to have all the translations.
+	ReferenceLanguage string
+}
+
+/*
+Checks the translations of the catalogs, language by language:
+  - the resources, their permissions, the menu entries with a translation key
+    and the components of the routes must be translated,
+  - the terms translated in a language must be translated in the other ones,
+  - the translations of the resources, the permissions and the components
+    must match registered ones,
+  - the translations must have as many placeholders as in the reference
+    language (the "other" plural form is compared for plural terms).
+
+The issues are sorted by language, key and kind.
+*/
+func Check(input CheckInput) ([]Issue, error) {
+	checker := &checker{
+		input:    input,
+		terms:    map[string]map[termId]Term{},
+		expected: map[string]string{},
+		issues:   []Issue{},
+	}
+	if err := checker.loadCatalogs(); err != nil {
+		return nil, err
+	}
+	checker.collectExpectedKeys()
+	checker.checkMissing()
+	checker.checkOrphaned()
+	checker.checkPlaceholders()
+	sort.Sort(byLanguageAndKey(checker.issues))
+	return checker.issues, nil
+}
+
+type checker struct {
+	input     CheckInput
+	languages []string
+
+	// The terms of each language
+	terms map[string]map[termId]Term
+
+	// The keys of the plugins elements, with their source
+	expected map[string]string
+
+	issues []Issue
+}
+
+func (c *checker) loadCatalogs() error {
+	languages := map[string]bool{}
+	if c.input.ReferenceLanguage != "" {
+		languages[c.input.ReferenceLanguage] = true
+	}
+	for lang := range c.input.Catalogs {
+		languages[lang] = true
+	}
+	for lang := range languages {
+		c.languages = append(c.languages, lang)
+		terms, err := ToTerms(c.input.Catalogs[lang])
+		if err != nil {
+			return fmt.Errorf("Invalid translations for the language \"%s\": %v", lang, err)
+		}
+		byId := make(map[termId]Term, len(terms))
+		for _, term := range terms {
+			byId[termId{term.Term, term.Context}] = term
+		}
+		c.terms[lang] = byId
+	}
+	sort.Strings(c.languages)
+	return nil
+}
+
+func (c *checker) collectExpectedKeys() {
+	for _, res := range c.input.Resources {
+		c.expect(ResourcesContext+"."+res.Key, "resource "+res.Key)
+	}
+	var collectEntries func(pluginName string, entries []menu.MenuEntry)
+	collectEntries = func(pluginName string, entries []menu.MenuEntry) {
+		for _, entry := range entries {
+			if entry.TranslationKey != "" {
+				c.expect(entry.TranslationKey, "menu entry "+entry.Route+" of the plugin "+pluginName)
+			}
+			collectEntries(pluginName, entry.Entries)
+		}
+	}
+	for _, m := range c.input.Menus {
+		collectEntries(m.PluginName, m.Entries)
+	}
+	for _, config := range c.input.Configurations {
+		for _, name := range componentNames(config) {
+			c.expect(ComponentsContext+"."+name, "component "+name+" of the plugin "+config.PluginName)
+		}
+	}
+}
+
+// Records a key the catalogs must translate. The first source is kept.
+func (c *checker) expect(key, source string) {
+	if _, found := c.expected[key]; !found {
+		c.expected[key] = source
+	}
+}
+
+func (c *checker) checkMissing() {
+	for _, lang := range c.languages {
+		terms := c.terms[lang]
+
+		// A term is reported once per language, with its first source
+		reported := map[termId]bool{}
+		missing := func(id termId, source string) {
+			if _, found := terms[id]; !found && !reported[id] {
+				reported[id] = true
+				c.report(MissingTranslation, lang, id, source)
+			}
+		}
+
+		for _, key := range sortedKeys(c.expected) {
+			missing(termId{key, ""}, c.expected[key])
+		}
+		for _, res := range c.input.Resources {
+			for _, permission := range res.Permissions {
+				specific := termId{PermissionsContext + "." + res.Key + "." + permission, ""}
+				generic := termId{PermissionsContext + "." + permission, ""}
+				if _, found := terms[specific]; found {
+					continue
+				}
+				missing(generic, "permission "+permission+" of the resource "+res.Key)
+			}
+		}
+
+		// The terms of the other languages
+		for _, other := range c.languages {
+			if other == lang {
+				continue
+			}
+			for id := range c.terms[other] {
+				missing(id, "translated in "+other)
+			}
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *checker) checkOrphaned() {
+	resources := map[string]resource.Resource{}
+	permissions := map[string]bool{}
+	for _, res := range c.input.Resources {
+		resources[res.Key] = res
+		for _, permission := range res.Permissions {
+			permissions[permission] = true
+		}
+	}
+
+	for _, lang := range c.languages {
+		for id := range c.terms[lang] {
+			switch {
+			case strings.HasPrefix(id.term, ResourcesContext+"."):
+				if _, found := resources[strings.TrimPrefix(id.term, ResourcesContext+".")]; !found {
+					c.report(OrphanedTranslation, lang, id, "unknown resource")
+				}
+			case strings.HasPrefix(id.term, PermissionsContext+"."):
+				if !isKnownPermission(strings.TrimPrefix(id.term, PermissionsContext+"."), resources, permissions) {
+					c.report(OrphanedTranslation, lang, id, "unknown permission")
+				}
+			case strings.HasPrefix(id.term, ComponentsContext+"."):
+				if _, found := c.expected[id.term]; !found {
+					c.report(OrphanedTranslation, lang, id, "unknown component")
+				}
+			}
+		}
+	}
+}
+
+// Tells whether the part of a key following "security.permissions." is a
+// registered permission ("create"), or a permission of a registered resource
+// ("accounts.create").
+func isKnownPermission(key string, resources map[string]resource.Resource, permissions map[string]bool) bool {
+	if permissions[key] {
+		return true
+	}
+	dot := strings.LastIndex(key, ".")
+	if dot < 0 {
+		return false
+	}
+	res, found := resources[key[:dot]]
+	if !found {
+		return false
+	}
+	for _, permission := range res.Permissions {
+		if permission == key[dot+1:] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *checker) checkPlaceholders() {
+	reference := c.input.ReferenceLanguage
+	if reference == "" {
+		return
+	}
+	for _, lang := range c.languages {
+		if lang == reference {
+			continue
+		}
+		for id, term := range c.terms[lang] {
+			referenceTerm, found := c.terms[reference][id]
+			if !found {
+				continue
+			}
+			expected := countPlaceholders(comparedForm(referenceTerm.Definition))
+			if count := countPlaceholders(comparedForm(term.Definition)); count != expected {
+				c.report(PlaceholderMismatch, lang, id,
+					fmt.Sprintf("%d placeholder(s) instead of %d in %s", count, expected, reference))
+			}
+		}
+	}
+}
+
+// Returns the form of the definition whose placeholders are compared.
+func comparedForm(definition Definition) string {
+	if definition.IsPlural() {
+		return definition.Plurals["other"]
+	}
+	return definition.Value
+}
+
+var placeholderRegexp = regexp.MustCompile("%(%|[-+# 0-9.]*[a-zA-Z])")
+
+// Counts the printf-like placeholders, such as "%s" or "%d". "%%" is not a
+// placeholder.
+func countPlaceholders(text string) int {
+	count := 0
+	for _, match := range placeholderRegexp.FindAllString(text, -1) {
+		if match != "%%" {
+			count++
+		}
+	}
+	return count
+}
+
+func (c *checker) report(kind IssueKind, lang string, id termId, source string) {
+	c.issues = append(c.issues, Issue{Kind: kind, Language: lang, Key: id.term, Context: id.context, Source: source})
+}
+
+// Returns the component names of the routes of the configuration, including
+// the sub routes.
+func componentNames(config registration.PluginConfiguration) []string {
+	names := []string{}
+	var collect func(routes []registration.SubRoute)
+	collect = func(routes []registration.SubRoute) {
+		for _, route := range routes {
+			if route.ComponentName != "" {
+				names = append(names, route.ComponentName)
+			}
+			collect(route.Routes)
+		}
+	}
+	for _, route := range config.Routes {
+		if route.ComponentName != "" {
+			names = append(names, route.ComponentName)
+		}
+		collect(route.Routes)
+	}
+	return names
+}
+
+/*
+Checks the translations stored for the tenant of the context against the
+stored resources, menus and routes (see Check).
+*/
+func (s *TranslationsStore) Check(ctx context.Context, resources resource.PluginResourcesStorageClient, menus *menu.MenuStore, configurations *registration.ConfigurationStore, referenceLang string) ([]Issue, error) {
+	input := CheckInput{Catalogs: map[string]Translations{}, ReferenceLanguage: referenceLang}
+	var err error
+	if input.Resources, err = resources.ListResources(); err != nil {
+		return nil, err
+	}
+	if input.Menus, err = menus.ListMenus(ctx); err != nil {
+		return nil, err
+	}
+	if input.Configurations, err = configurations.ListRoutesAndReducers(ctx); err != nil {
+		return nil, err
+	}
+	languages, err := s.ListLanguages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, lang := range languages {
+		if input.Catalogs[lang], err = s.GetTranslations(ctx, lang); err != nil {
+			return nil, err
+		}
+	}
+	return Check(input)
+}
+
+type byLanguageAndKey []Issue
+
+func (i byLanguageAndKey) Len() int      { return len(i) }
+func (i byLanguageAndKey) Swap(j, k int) { i[j], i[k] = i[k], i[j] }
+func (i byLanguageAndKey) Less(j, k int) bool {
+	switch {
+	case i[j].Language != i[k].Language:
+		return i[j].Language < i[k].Language
+	case i[j].Key != i[k].Key:
+		return i[j].Key < i[k].Key
+	case i[j].Context != i[k].Context:
+		return i[j].Context < i[k].Context
+	case i[j].Kind != i[k].Kind:
+		return i[j].Kind < i[k].Kind
+	}
+	return i[j].Source < i[k].Source
+}
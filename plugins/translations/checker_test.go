@@ -0,0 +1,216 @@
+package translations_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/registration"
+	"github.com/eogile/agilestack-utils/plugins/resource"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	checkedResources = []resource.Resource{
+		{Key: "accounts", SecurityKey: "rn:hydra:accounts", Permissions: []string{"create", "delete"}},
+	}
+
+	checkedMenus = []menu.Menu{
+		{PluginName: "accounts", Entries: []menu.MenuEntry{
+			{Name: "Accounts", Route: "/accounts", TranslationKey: "app.menu.accounts", Entries: []menu.MenuEntry{
+				{Name: "New account", Route: "/accounts/new", TranslationKey: "app.menu.newAccount"},
+			}},
+		}},
+	}
+
+	checkedConfigurations = []registration.PluginConfiguration{
+		{PluginName: "accounts", Routes: []registration.Route{
+			{Href: "accounts", ComponentName: "AccountsList", Routes: []registration.SubRoute{
+				{Href: "new", ComponentName: "AccountForm", Routes: []registration.SubRoute{}},
+			}},
+		}},
+	}
+)
+
+func checkedCatalog(resources, permissions, components map[string]interface{}) translations.Translations {
+	return translations.Translations{
+		"security": map[string]interface{}{
+			"resources":   resources,
+			"permissions": permissions,
+		},
+		"components": components,
+		"app": map[string]interface{}{
+			"menu": map[string]interface{}{
+				"accounts":   "Accounts",
+				"newAccount": "New account",
+			},
+		},
+	}
+}
+
+func TestCheck_Complete(t *testing.T) {
+	issues, err := translations.Check(translations.CheckInput{
+		Resources:      checkedResources,
+		Menus:          checkedMenus,
+		Configurations: checkedConfigurations,
+		Catalogs: map[string]translations.Translations{
+			"en": checkedCatalog(
+				map[string]interface{}{"accounts": "user accounts"},
+				map[string]interface{}{"create": "Create", "accounts": map[string]interface{}{"delete": "Delete %s"}},
+				map[string]interface{}{"AccountsList": "Accounts", "AccountForm": "Account"},
+			),
+		},
+		ReferenceLanguage: "en",
+	})
+	require.Nil(t, err)
+	require.Empty(t, issues)
+}
+
+func TestCheck_Missing(t *testing.T) {
+	issues, err := translations.Check(translations.CheckInput{
+		Resources:      checkedResources,
+		Menus:          checkedMenus,
+		Configurations: checkedConfigurations,
+		Catalogs: map[string]translations.Translations{
+			"en": checkedCatalog(
+				map[string]interface{}{"accounts": "user accounts"},
+				map[string]interface{}{"create": "Create", "delete": "Delete"},
+				map[string]interface{}{"AccountsList": "Accounts", "AccountForm": "Account"},
+			),
+			"fr": translations.Translations{
+				"security": map[string]interface{}{
+					"permissions": map[string]interface{}{"create": "Créer"},
+				},
+			},
+		},
+		ReferenceLanguage: "en",
+	})
+	require.Nil(t, err)
+	require.Equal(t, []translations.Issue{
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "app.menu.accounts", Source: "menu entry /accounts of the plugin accounts"},
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "app.menu.newAccount", Source: "menu entry /accounts/new of the plugin accounts"},
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "components.AccountForm", Source: "component AccountForm of the plugin accounts"},
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "components.AccountsList", Source: "component AccountsList of the plugin accounts"},
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "security.permissions.delete", Source: "permission delete of the resource accounts"},
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "security.resources.accounts", Source: "resource accounts"},
+	}, issues)
+}
+
+func TestCheck_MissingInReferenceLanguage(t *testing.T) {
+	issues, err := translations.Check(translations.CheckInput{
+		Catalogs: map[string]translations.Translations{
+			"fr": translations.Translations{"app": map[string]interface{}{"title": "Titre"}},
+		},
+		ReferenceLanguage: "en",
+	})
+	require.Nil(t, err)
+	require.Equal(t, []translations.Issue{
+		{Kind: translations.MissingTranslation, Language: "en", Key: "app.title", Source: "translated in fr"},
+	}, issues)
+}
+
+func TestCheck_Orphaned(t *testing.T) {
+	issues, err := translations.Check(translations.CheckInput{
+		Resources:      checkedResources,
+		Menus:          checkedMenus,
+		Configurations: checkedConfigurations,
+		Catalogs: map[string]translations.Translations{
+			"en": checkedCatalog(
+				map[string]interface{}{"accounts": "user accounts", "clients": "clients"},
+				map[string]interface{}{
+					"create":   "Create",
+					"delete":   "Delete",
+					"update":   "Update",
+					"accounts": map[string]interface{}{"delete": "Delete an account", "read": "Read"},
+				},
+				map[string]interface{}{"AccountsList": "Accounts", "AccountForm": "Account", "ClientsList": "Clients"},
+			),
+		},
+	})
+	require.Nil(t, err)
+	require.Equal(t, []translations.Issue{
+		{Kind: translations.OrphanedTranslation, Language: "en", Key: "components.ClientsList", Source: "unknown component"},
+		{Kind: translations.OrphanedTranslation, Language: "en", Key: "security.permissions.accounts.read", Source: "unknown permission"},
+		{Kind: translations.OrphanedTranslation, Language: "en", Key: "security.permissions.update", Source: "unknown permission"},
+		{Kind: translations.OrphanedTranslation, Language: "en", Key: "security.resources.clients", Source: "unknown resource"},
+	}, issues)
+}
+
+func TestCheck_Placeholders(t *testing.T) {
+	issues, err := translations.Check(translations.CheckInput{
+		Catalogs: map[string]translations.Translations{
+			"en": translations.Translations{
+				"app": map[string]interface{}{
+					"welcome":  "Welcome %s, you have %d messages",
+					"progress": "%d%% done",
+					"users": map[string]interface{}{
+						"@definition": map[string]interface{}{"one": "One user", "other": "%d users"},
+						"@plural":     "app.users",
+					},
+				},
+			},
+			"fr": translations.Translations{
+				"app": map[string]interface{}{
+					"welcome":  "Bienvenue %s",
+					"progress": "%d %% effectué",
+					"users": map[string]interface{}{
+						"@definition": map[string]interface{}{"one": "Un utilisateur", "other": "utilisateurs"},
+						"@plural":     "app.users",
+					},
+				},
+			},
+		},
+		ReferenceLanguage: "en",
+	})
+	require.Nil(t, err)
+	require.Equal(t, []translations.Issue{
+		{Kind: translations.PlaceholderMismatch, Language: "fr", Key: "app.users", Source: "0 placeholder(s) instead of 1 in en"},
+		{Kind: translations.PlaceholderMismatch, Language: "fr", Key: "app.welcome", Source: "1 placeholder(s) instead of 2 in en"},
+	}, issues)
+}
+
+func TestCheck_InvalidCatalog(t *testing.T) {
+	_, err := translations.Check(translations.CheckInput{
+		Catalogs: map[string]translations.Translations{
+			"en": translations.Translations{"app": 3},
+		},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid translations for the language \"en\": Translation must be a string or an object: \"app\"", err.Error())
+}
+
+func TestTranslationsStore_Check(t *testing.T) {
+	deleteAll(t)
+	config := store.ConsulConfig{Address: consulAddress}
+	ctx := context.Background()
+
+	resources, err := resource.NewResourcesStorageClientWithConfig(ctx, config)
+	require.Nil(t, err)
+	require.Nil(t, resources.StoreResource(resource.Resource{Key: "accounts", Permissions: []string{"create"}}))
+	require.Nil(t, resources.StoreResource(resource.Resource{Key: "clients", Permissions: []string{"create"}}))
+
+	menus, err := menu.NewStore(config)
+	require.Nil(t, err)
+	configurations, err := registration.NewStore(config)
+	require.Nil(t, err)
+
+	require.Nil(t, translations.StoreTranslations("accounts", "fr", accountsFr))
+	require.Nil(t, translations.StoreTranslations("accounts", "en", translations.Translations{
+		"security": map[string]interface{}{
+			"resources":   map[string]interface{}{"accounts": "user accounts", "clients": "clients"},
+			"permissions": map[string]interface{}{"create": "Create", "delete": "Delete"},
+		},
+	}))
+
+	s, err := translations.DefaultStore()
+	require.Nil(t, err)
+	issues, err := s.Check(ctx, resources, menus, configurations, "en")
+	require.Nil(t, err)
+	require.Equal(t, []translations.Issue{
+		{Kind: translations.OrphanedTranslation, Language: "en", Key: "security.permissions.delete", Source: "unknown permission"},
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "security.permissions.delete", Source: "translated in en"},
+		{Kind: translations.MissingTranslation, Language: "fr", Key: "security.resources.clients", Source: "resource clients"},
+	}, issues)
+}
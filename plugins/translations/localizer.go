@@ -0,0 +1,399 @@
+package translations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/tenant"
+)
+
+/*
+A Localizer renders the messages of the translation catalogs, such as
+"app.login.message.success", in the language of a user.
+
+The languages fall back on their base language then on the default language:
+a message missing in "fr-CA" is looked up in "fr", then in the default language.
+A Localizer is safe for concurrent use.
+*/
+type Localizer struct {
+	defaultLang string
+
+	mutex sync.RWMutex
+
+	// The context-less terms of each catalog, by normalized language
+	catalogs map[string]map[string]Term
+
+	// The languages of the catalogs, by normalized language
+	languages map[string]string
+}
+
+// Returns a localizer without catalogs, falling back on the default language.
+func NewLocalizer(defaultLang string) *Localizer {
+	return &Localizer{
+		defaultLang: defaultLang,
+		catalogs:    map[string]map[string]Term{},
+		languages:   map[string]string{},
+	}
+}
+
+// Replaces the catalog of the language.
+func (l *Localizer) SetCatalog(lang string, translations Translations) error {
+	if err := ValidateLanguage(lang); err != nil {
+		return err
+	}
+	terms, err := ToTerms(translations)
+	if err != nil {
+		return err
+	}
+	catalog := make(map[string]Term, len(terms))
+	for _, term := range terms {
+		if term.Context == "" {
+			catalog[term.Term] = term
+		}
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.catalogs[normalizeLanguage(lang)] = catalog
+	l.languages[normalizeLanguage(lang)] = lang
+	return nil
+}
+
+// Removes the catalog of the language.
+func (l *Localizer) RemoveCatalog(lang string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.catalogs, normalizeLanguage(lang))
+	delete(l.languages, normalizeLanguage(lang))
+}
+
+// Returns the languages of the catalogs, sorted.
+func (l *Localizer) Languages() []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	languages := make([]string, 0, len(l.languages))
+	for _, lang := range l.languages {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+/*
+Returns the message of the key in the language, formatted with the arguments
+like fmt.Sprintf. The message is not formatted when there is no argument.
+Only the context-less terms are looked up.
+
+The plural terms (having a term_plural) select their plural form with the first
+integer argument and the CLDR plural rule of the language, falling back on the
+"other" form:
+
+	localizer.T("fr", "app.users.active", 2) // "Il y a 2 utilisateurs en ligne"
+
+Returns the key when no catalog of the fallback chain of the language has it.
+*/
+func (l *Localizer) T(lang, key string, args ...interface{}) string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	for _, candidate := range l.fallbackChain(lang) {
+		term, found := l.catalogs[candidate][key]
+		if !found {
+			continue
+		}
+		message := term.Definition.Value
+		if term.Definition.IsPlural() {
+			message = pluralForm(candidate, term.Definition, args)
+		}
+		return format(message, args)
+	}
+	return key
+}
+
+/*
+Returns the message of the key in the first catalog of the fallback chain of the
+language having it, not formatted, and false when none has it. The plural terms
+give their "other" form.
+
+A Localizer is thus a menu.Catalog, resolving the menu entries like the other
+messages (see LocalizeMenu).
+*/
+func (l *Localizer) Translate(lang, key string) (string, bool) {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	for _, candidate := range l.fallbackChain(lang) {
+		if term, found := l.catalogs[candidate][key]; found {
+			if term.Definition.IsPlural() {
+				return term.Definition.Plurals["other"], true
+			}
+			return term.Definition.Value, true
+		}
+	}
+	return "", false
+}
+
+// Returns a copy of the application menu whose entry names are translated in
+// the language, along with the translation keys missing in the fallback chain
+// of the language (see menu.LocalizeMenu).
+func (l *Localizer) LocalizeMenu(applicationMenu *menu.ApplicationMenu, lang string) (*menu.ApplicationMenu, []menu.MissingTranslation) {
+	// The localizer falls back on the other languages itself
+	return menu.LocalizeMenu(applicationMenu, lang, l, "")
+}
+
+// Formats the message with the arguments. The extra arguments are ignored, so
+// that a plural form such as "Just one user online" may leave out the count.
+func format(message string, args []interface{}) string {
+	if len(args) == 0 {
+		return message
+	}
+	if count := countPlaceholders(message); count < len(args) && !strings.Contains(message, "%[") {
+		args = args[:count]
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+func pluralForm(lang string, definition Definition, args []interface{}) string {
+	category := "other"
+	if len(args) > 0 {
+		if n, ok := pluralCount(args[0]); ok {
+			category = PluralCategory(lang, n)
+		}
+	}
+	if form, found := definition.Plurals[category]; found {
+		return form
+	}
+	return definition.Plurals["other"]
+}
+
+// Returns the count of the argument when it is an integer.
+func pluralCount(arg interface{}) (int64, bool) {
+	switch n := arg.(type) {
+	case int:
+		return int64(n), true
+	case int8:
+		return int64(n), true
+	case int16:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint:
+		return int64(n), true
+	case uint8:
+		return int64(n), true
+	case uint16:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// Returns the languages of the catalogs to look a message up in, in order.
+func (l *Localizer) FallbackChain(lang string) []string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	chain := []string{}
+	for _, candidate := range l.fallbackChain(lang) {
+		chain = append(chain, l.languages[candidate])
+	}
+	return chain
+}
+
+// Returns the normalized languages having a catalog among the language, its
+// base languages and the default language, then its base languages.
+func (l *Localizer) fallbackChain(lang string) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+	for _, start := range []string{lang, l.defaultLang} {
+		for tag := normalizeLanguage(start); tag != ""; tag = parentLanguage(tag) {
+			if _, found := l.catalogs[tag]; found && !seen[tag] {
+				seen[tag] = true
+				chain = append(chain, tag)
+			}
+		}
+	}
+	return chain
+}
+
+/*
+Returns the language of the catalogs best matching the Accept-Language header
+of a request, such as "fr-CH, fr;q=0.9, en;q=0.8". The languages of the header
+are tried by decreasing quality, each one with its base languages. Returns the
+default language when none has a catalog.
+*/
+func (l *Localizer) Negotiate(acceptLanguage string) string {
+	l.mutex.RLock()
+	defer l.mutex.RUnlock()
+	for _, tag := range parseAcceptLanguage(acceptLanguage) {
+		if tag == "*" {
+			break
+		}
+		for ; tag != ""; tag = parentLanguage(tag) {
+			if lang, found := l.languages[tag]; found {
+				return lang
+			}
+		}
+	}
+	return l.defaultLang
+}
+
+type weightedLanguage struct {
+	tag     string
+	quality float64
+}
+
+// Returns the normalized languages of the header, by decreasing quality. The
+// languages of quality 0 are left out.
+func parseAcceptLanguage(header string) []string {
+	weighted := []weightedLanguage{}
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := normalizeLanguage(strings.TrimSpace(params[0]))
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil {
+				q = 0
+			}
+			quality = q
+		}
+		if quality > 0 {
+			weighted = append(weighted, weightedLanguage{tag, quality})
+		}
+	}
+	sort.Stable(byQuality(weighted))
+
+	tags := make([]string, len(weighted))
+	for i, language := range weighted {
+		tags[i] = language.tag
+	}
+	return tags
+}
+
+type byQuality []weightedLanguage
+
+func (w byQuality) Len() int           { return len(w) }
+func (w byQuality) Swap(i, j int)      { w[i], w[j] = w[j], w[i] }
+func (w byQuality) Less(i, j int) bool { return w[i].quality > w[j].quality }
+
+// Lower cases the language and separates its subtags with "-", so that
+// "pt_BR" and "pt-br" match.
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.Replace(lang, "_", "-", -1))
+}
+
+// Returns the normalized language without its last subtag, or "".
+func parentLanguage(tag string) string {
+	if dash := strings.LastIndex(tag, "-"); dash >= 0 {
+		return tag[:dash]
+	}
+	return ""
+}
+
+// Returns a localizer over the current catalogs of the tenant of the context.
+func (s *TranslationsStore) LoadLocalizer(ctx context.Context, defaultLang string) (*Localizer, error) {
+	localizer := NewLocalizer(defaultLang)
+	prefix := tenant.Key(ctx, catalogPrefix)
+	pairs, err := s.kv.List(prefix)
+	if err != nil {
+		log.Println("Error while listing the translations catalogs:", err)
+		return nil, err
+	}
+	for _, pair := range pairs {
+		localizer.reload(prefix, store.Event{Type: store.Added, Pair: pair})
+	}
+	return localizer, nil
+}
+
+/*
+Returns a localizer over the catalogs of the tenant of the context, reloading
+the catalogs when they change until the context is done.
+*/
+func (s *TranslationsStore) WatchLocalizer(ctx context.Context, defaultLang string) (*Localizer, error) {
+	localizer, err := s.LoadLocalizer(ctx, defaultLang)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := tenant.Key(ctx, catalogPrefix)
+	events := store.Watch(ctx, s.kv, prefix)
+	go func() {
+		for event := range events {
+			localizer.reload(prefix, event)
+		}
+	}()
+	return localizer, nil
+}
+
+/*
+Returns a copy of the application menu whose entry names are translated in the
+language with the catalogs of the tenant of the context, along with the
+translation keys missing in the fallback chain of the language (see
+Localizer.LocalizeMenu). The languages fall back on the default language.
+*/
+func (s *TranslationsStore) LocalizeMenu(ctx context.Context, applicationMenu *menu.ApplicationMenu, lang, defaultLang string) (*menu.ApplicationMenu, []menu.MissingTranslation, error) {
+	localizer, err := s.LoadLocalizer(ctx, defaultLang)
+	if err != nil {
+		return nil, nil, err
+	}
+	localized, missing := localizer.LocalizeMenu(applicationMenu, lang)
+	return localized, missing, nil
+}
+
+// Applies the change of a catalog stored under the prefix.
+func (l *Localizer) reload(prefix string, event store.Event) {
+	lang := strings.TrimPrefix(event.Pair.Key, prefix)
+	if lang == "" || strings.Contains(lang, "/") {
+		return
+	}
+	if event.Type == store.Removed {
+		l.RemoveCatalog(lang)
+		return
+	}
+	translations := Translations{}
+	if err := json.Unmarshal(event.Pair.Value, &translations); err != nil {
+		log.Printf("Error while unmarshalling the translations catalog '%s': %v", event.Pair.Key, err)
+		return
+	}
+	if err := l.SetCatalog(lang, translations); err != nil {
+		log.Printf("Error while loading the translations catalog '%s': %v", event.Pair.Key, err)
+	}
+}
+
+// Returns a localizer over the catalogs, reloading them when they change until
+// the context is done.
+func WatchLocalizer(ctx context.Context, defaultLang string) (*Localizer, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, err
+	}
+	return s.WatchLocalizer(ctx, defaultLang)
+}
+
+// Returns a copy of the application menu whose entry names are translated in
+// the language, for the tenant of the context (see TranslationsStore.LocalizeMenu).
+func LocalizeMenu(ctx context.Context, applicationMenu *menu.ApplicationMenu, lang, defaultLang string) (*menu.ApplicationMenu, []menu.MissingTranslation, error) {
+	s, err := DefaultStore()
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.LocalizeMenu(ctx, applicationMenu, lang, defaultLang)
+}
@@ -0,0 +1,264 @@
+package translations_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/eogile/agilestack-utils/plugins/menu"
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	"github.com/eogile/agilestack-utils/tenant"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	loginEn = translations.Translations{
+		"app": map[string]interface{}{
+			"login": map[string]interface{}{
+				"message": map[string]interface{}{"success": "Welcome %s!", "failure": "Wrong password"},
+			},
+			"users": map[string]interface{}{
+				"active": map[string]interface{}{
+					"@definition": map[string]interface{}{"one": "Just one user online", "other": "There are %d users online"},
+					"@plural":     "app.users.active",
+				},
+			},
+		},
+	}
+
+	loginFr = translations.Translations{
+		"app": map[string]interface{}{
+			"login": map[string]interface{}{
+				"message": map[string]interface{}{"success": "Bienvenue %s !"},
+			},
+			"users": map[string]interface{}{
+				"active": map[string]interface{}{
+					"@definition": map[string]interface{}{"one": "%d utilisateur en ligne", "other": "%d utilisateurs en ligne"},
+					"@plural":     "app.users.active",
+				},
+			},
+		},
+	}
+
+	loginFrCa = translations.Translations{
+		"app": map[string]interface{}{
+			"login": map[string]interface{}{
+				"message": map[string]interface{}{"success": "Bienvenue %s, bonjour !"},
+			},
+		},
+	}
+)
+
+func newTestLocalizer(t *testing.T) *translations.Localizer {
+	localizer := translations.NewLocalizer("en")
+	require.Nil(t, localizer.SetCatalog("en", loginEn))
+	require.Nil(t, localizer.SetCatalog("fr", loginFr))
+	require.Nil(t, localizer.SetCatalog("fr_CA", loginFrCa))
+	return localizer
+}
+
+func TestLocalizer_T(t *testing.T) {
+	localizer := newTestLocalizer(t)
+
+	require.Equal(t, "Welcome John!", localizer.T("en", "app.login.message.success", "John"))
+	require.Equal(t, "Bienvenue John !", localizer.T("fr", "app.login.message.success", "John"))
+	require.Equal(t, "Bienvenue John, bonjour !", localizer.T("fr-CA", "app.login.message.success", "John"))
+
+	// Fallback chains
+	require.Equal(t, "Bienvenue John !", localizer.T("fr-BE", "app.login.message.success", "John"))
+	require.Equal(t, "Wrong password", localizer.T("fr-CA", "app.login.message.failure"))
+	require.Equal(t, "Wrong password", localizer.T("de", "app.login.message.failure"))
+	require.Equal(t, "Wrong password", localizer.T("", "app.login.message.failure"))
+	require.Equal(t, []string{"fr_CA", "fr", "en"}, localizer.FallbackChain("fr-ca"))
+
+	// Unknown key
+	require.Equal(t, "app.unknown", localizer.T("fr", "app.unknown"))
+}
+
+func TestLocalizer_T_Plurals(t *testing.T) {
+	localizer := newTestLocalizer(t)
+
+	require.Equal(t, "Just one user online", localizer.T("en", "app.users.active", 1))
+	require.Equal(t, "There are 0 users online", localizer.T("en", "app.users.active", 0))
+	require.Equal(t, "There are 5 users online", localizer.T("en", "app.users.active", int64(5)))
+
+	// French uses "one" for 0 and 1
+	require.Equal(t, "0 utilisateur en ligne", localizer.T("fr", "app.users.active", 0))
+	require.Equal(t, "1 utilisateur en ligne", localizer.T("fr", "app.users.active", uint(1)))
+	require.Equal(t, "2 utilisateurs en ligne", localizer.T("fr", "app.users.active", 2))
+
+	// The French form is used for French Canadian
+	require.Equal(t, "0 utilisateur en ligne", localizer.T("fr-CA", "app.users.active", 0))
+
+	// Without count, the "other" form is used
+	require.Equal(t, "There are %d users online", localizer.T("en", "app.users.active"))
+}
+
+func TestLocalizer_SetCatalog(t *testing.T) {
+	localizer := newTestLocalizer(t)
+	require.Equal(t, []string{"en", "fr", "fr_CA"}, localizer.Languages())
+
+	require.Nil(t, localizer.SetCatalog("fr", translations.Translations{
+		"app": map[string]interface{}{"title": "Titre"},
+	}))
+	require.Equal(t, "Titre", localizer.T("fr", "app.title"))
+	require.Equal(t, "Welcome John!", localizer.T("fr", "app.login.message.success", "John"))
+
+	localizer.RemoveCatalog("fr_CA")
+	require.Equal(t, []string{"en", "fr"}, localizer.Languages())
+	require.Equal(t, "Welcome John!", localizer.T("fr-CA", "app.login.message.success", "John"))
+
+	err := localizer.SetCatalog("fr", translations.Translations{"app": 3})
+	require.NotNil(t, err)
+	require.Equal(t, "Translation must be a string or an object: \"app\"", err.Error())
+	require.Equal(t, "Titre", localizer.T("fr", "app.title"))
+}
+
+func TestLocalizer_Negotiate(t *testing.T) {
+	localizer := newTestLocalizer(t)
+
+	require.Equal(t, "fr_CA", localizer.Negotiate("fr-CA, fr;q=0.9, en;q=0.8"))
+	require.Equal(t, "fr", localizer.Negotiate("fr-CH, fr;q=0.9, en;q=0.8"))
+	require.Equal(t, "fr", localizer.Negotiate("de-DE, de;q=0.9, fr;q=0.8, en;q=0.7"))
+	require.Equal(t, "fr", localizer.Negotiate("de;q=0.9, en;q=0.5, fr;q=0.6, ja"))
+	require.Equal(t, "fr", localizer.Negotiate("en;q=0, fr;q=0.1"))
+	require.Equal(t, "en", localizer.Negotiate("*"))
+	require.Equal(t, "en", localizer.Negotiate("de, it"))
+	require.Equal(t, "en", localizer.Negotiate(""))
+}
+
+func TestPluralCategory(t *testing.T) {
+	cases := []struct {
+		lang     string
+		n        int64
+		category string
+	}{
+		{"en", 1, "one"}, {"en", 0, "other"}, {"en", 2, "other"}, {"en-US", 1, "one"},
+		{"fr", 0, "one"}, {"fr", 1, "one"}, {"fr", 2, "other"},
+		{"pt_BR", 0, "one"},
+		{"ja", 1, "other"}, {"zh-TW", 1, "other"},
+		{"ru", 1, "one"}, {"ru", 21, "one"}, {"ru", 11, "many"}, {"ru", 3, "few"}, {"ru", 13, "many"}, {"ru", 5, "many"},
+		{"pl", 1, "one"}, {"pl", 21, "many"}, {"pl", 22, "few"}, {"pl", 12, "many"},
+		{"cs", 3, "few"}, {"cs", 5, "other"},
+		{"ar", 0, "zero"}, {"ar", 2, "two"}, {"ar", 103, "few"}, {"ar", 111, "many"}, {"ar", 100, "other"},
+		{"en", -1, "one"},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.category, translations.PluralCategory(c.lang, c.n), "%s %d", c.lang, c.n)
+	}
+}
+
+func TestPluralCategory_Romanian(t *testing.T) {
+	cases := []struct {
+		n        int64
+		category string
+	}{
+		{0, "few"}, {1, "one"}, {2, "few"}, {19, "few"}, {20, "other"}, {99, "other"}, {100, "other"},
+		{101, "few"}, {102, "few"}, {119, "few"}, {120, "other"}, {201, "few"}, {1001, "few"},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.category, translations.PluralCategory("ro", c.n), "%d", c.n)
+	}
+}
+
+func TestPluralCategory_Portuguese(t *testing.T) {
+	cases := []struct {
+		lang     string
+		n        int64
+		category string
+	}{
+		{"pt", 0, "one"}, {"pt", 1, "one"}, {"pt", 2, "other"},
+		{"pt-BR", 0, "one"}, {"pt-BR", 1, "one"}, {"pt-BR", 2, "other"},
+		{"pt-PT", 0, "other"}, {"pt_pt", 1, "one"}, {"pt-PT", 2, "other"},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.category, translations.PluralCategory(c.lang, c.n), "%s %d", c.lang, c.n)
+	}
+}
+
+func TestTranslationsStore_WatchLocalizer(t *testing.T) {
+	s := translations.NewTranslationsStore(store.NewMemoryStore())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	require.Nil(t, s.StoreTranslations(ctx, "accounts", "en", loginEn))
+	localizer, err := s.WatchLocalizer(ctx, "en")
+	require.Nil(t, err)
+	require.Equal(t, "Welcome John!", localizer.T("fr", "app.login.message.success", "John"))
+
+	require.Nil(t, s.StoreTranslations(ctx, "accounts", "fr", loginFr))
+	waitFor(t, func() bool {
+		return localizer.T("fr", "app.login.message.success", "John") == "Bienvenue John !"
+	})
+
+	require.Nil(t, s.DeleteTranslations(ctx, "accounts"))
+	waitFor(t, func() bool {
+		return len(localizer.Languages()) == 0
+	})
+	require.Equal(t, "app.login.message.success", localizer.T("fr", "app.login.message.success"))
+}
+
+// Waits for the condition to be true, failing after a second.
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timeout while waiting for the condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLocalizer_Translate(t *testing.T) {
+	localizer := newTestLocalizer(t)
+	translation, found := localizer.Translate("FR-ca", "app.login.message.success")
+	require.True(t, found)
+	require.Equal(t, "Bienvenue %s, bonjour !", translation)
+
+	translation, found = localizer.Translate("fr_CA", "app.users.active")
+	require.True(t, found)
+	require.Equal(t, "%d utilisateurs en ligne", translation)
+
+	translation, found = localizer.Translate("de", "app.login.message.failure")
+	require.True(t, found)
+	require.Equal(t, "Wrong password", translation)
+
+	_, found = localizer.Translate("fr", "app.login.message")
+	require.False(t, found)
+}
+
+func TestTranslationsStore_LocalizeMenu(t *testing.T) {
+	s := translations.NewTranslationsStore(store.NewMemoryStore())
+	ctx := tenant.NewContext(context.Background(), "acme")
+	require.Nil(t, s.StoreTranslations(ctx, "accounts", "en", translations.Translations{
+		"app": map[string]interface{}{
+			"menu": map[string]interface{}{
+				"accounts": "Accounts",
+				"settings": map[string]interface{}{"@definition": "Settings", "@comment": "The settings"},
+			},
+		},
+	}))
+	require.Nil(t, s.StoreTranslations(ctx, "accounts", "fr", translations.Translations{
+		"app": map[string]interface{}{
+			"menu": map[string]interface{}{"accounts": "Comptes"},
+		},
+	}))
+
+	applicationMenu := &menu.ApplicationMenu{Entries: []menu.MenuEntry{
+		{Name: "Accounts", Route: "/accounts", TranslationKey: "app.menu.accounts", Entries: []menu.MenuEntry{}},
+		{Name: "Settings", Route: "/settings", TranslationKey: "app.menu.settings", Entries: []menu.MenuEntry{}},
+		{Name: "Audit", Route: "/audit", TranslationKey: "app.menu.audit", Entries: []menu.MenuEntry{}},
+	}}
+	localized, missing, err := s.LocalizeMenu(ctx, applicationMenu, "fr_CA", "en")
+	require.Nil(t, err)
+	require.Equal(t, "Comptes", localized.Entries[0].Name)
+	require.Equal(t, "Settings", localized.Entries[1].Name)
+	require.Equal(t, "Audit", localized.Entries[2].Name)
+	require.Equal(t, []menu.MissingTranslation{{Key: "app.menu.audit", Language: "fr_CA"}}, missing)
+
+	// The catalogs of the other tenants are not used
+	localized, _, err = s.LocalizeMenu(context.Background(), applicationMenu, "fr", "en")
+	require.Nil(t, err)
+	require.Equal(t, "Accounts", localized.Entries[0].Name)
+}
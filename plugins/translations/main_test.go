@@ -0,0 +1,67 @@
+package translations_test
+
+import (
+	"log"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/store"
+	"github.com/eogile/agilestack-utils/plugins/test"
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	TestUtils "github.com/eogile/agilestack-utils/test"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	accountsFr = translations.Translations{
+		"security": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"accounts": "comptes utilisateurs",
+			},
+			"permissions": map[string]interface{}{
+				"create": "Créer",
+			},
+		},
+	}
+
+	clientsFr = translations.Translations{
+		"security": map[string]interface{}{
+			"resources": map[string]interface{}{
+				"clients": "clients",
+			},
+			"permissions": map[string]interface{}{
+				"create": "Créer un client",
+			},
+		},
+	}
+)
+
+// The address of the Consul container
+var consulAddress string
+
+func TestMain(m *testing.M) {
+	log.Println("Launching tests agilestack/utils/plugins/translations")
+	test.DoTestMain(m, func(consul *TestUtils.ConsulInfo) {
+		consulAddress = consul.Address
+		s, err := translations.NewStore(store.ConsulConfig{Address: consul.Address})
+		if err != nil {
+			log.Fatalln("Unable to create the store:", err)
+		}
+		translations.SetDefaultStore(s)
+	})
+}
+
+func consulClient(t *testing.T) *api.Client {
+	config := api.DefaultConfig()
+	config.Address = consulAddress
+	client, err := api.NewClient(config)
+	require.Nil(t, err)
+	return client
+}
+
+func deleteAll(t *testing.T) {
+	_, err := consulClient(t).KV().DeleteTree("agilestack/", &api.WriteOptions{})
+	require.Nil(t, err)
+	_, err = consulClient(t).KV().DeleteTree("agileStack/", &api.WriteOptions{})
+	require.Nil(t, err)
+}
@@ -0,0 +1,93 @@
+package translations
+
+import (
+	"log"
+	"sort"
+)
+
+/*
+Returns the translations by dotted term, such as "security.resources.accounts".
+
+Only the terms without context nor plural forms are returned (see ToTerms for
+all the terms). Returns nil if the translations are invalid.
+*/
+func Flatten(translations Translations) map[string]string {
+	terms, err := ToTerms(translations)
+	if err != nil {
+		return nil
+	}
+	flat := map[string]string{}
+	for _, term := range terms {
+		if term.Context == "" && !term.Definition.IsPlural() {
+			flat[term.Term] = term.Definition.Value
+		}
+	}
+	return flat
+}
+
+// Returns the nested translations of the dotted terms.
+func Unflatten(flat map[string]string) (Translations, error) {
+	terms := make([]Term, 0, len(flat))
+	for term, translation := range flat {
+		terms = append(terms, Term{Term: term, Definition: Definition{Value: translation}})
+	}
+	return FromTerms(terms)
+}
+
+// The contribution of a plugin to the translations of a language.
+type contribution struct {
+	pluginName   string
+	translations Translations
+}
+
+/*
+Merges the contributions of the plugins into a single catalog.
+
+The contributions are merged in the order of the plugin names. When several
+plugins define the same term in the same context, the first plugin wins and
+the conflict is logged.
+*/
+func merge(contributions []contribution) Translations {
+	sort.Sort(byPluginName(contributions))
+	merged := []Term{}
+	owners := map[termId]string{}
+	for _, c := range contributions {
+		terms, err := ToTerms(c.translations)
+		if err != nil {
+			log.Printf("Ignoring the translations of the plugin \"%s\": %v", c.pluginName, err)
+			continue
+		}
+		for _, term := range terms {
+			id := termId{term.Term, term.Context}
+			if owner, found := owners[id]; found {
+				log.Printf("Ignoring the translation \"%s\" of the plugin \"%s\", already defined by the plugin \"%s\"",
+					term.Term, c.pluginName, owner)
+				continue
+			}
+			owners[id] = c.pluginName
+			merged = append(merged, term)
+		}
+	}
+	// The terms are unique and valid
+	translations, _ := FromTerms(merged)
+	return translations
+}
+
+// Identifies a term in a context.
+type termId struct {
+	term    string
+	context string
+}
+
+func joinTerm(context, key string) string {
+	if context == "" {
+		return key
+	}
+	return context + "." + key
+}
+
+type byPluginName []contribution
+
+func (c byPluginName) Len() int           { return len(c) }
+func (c byPluginName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byPluginName) Less(i, j int) bool { return c[i].pluginName < c[j].pluginName }
@@ -0,0 +1,30 @@
+package translations_test
+
+import (
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	require.Equal(t, map[string]string{
+		"security.resources.accounts": "comptes utilisateurs",
+		"security.permissions.create": "Créer",
+	}, translations.Flatten(accountsFr))
+}
+
+func TestUnflatten(t *testing.T) {
+	nested, err := translations.Unflatten(translations.Flatten(accountsFr))
+	require.Nil(t, err)
+	require.Equal(t, accountsFr, nested)
+
+	// A term can also be the context of other terms
+	nested, err = translations.Unflatten(map[string]string{"app.menu": "Menu", "app.menu.users": "Users"})
+	require.Nil(t, err)
+	require.Equal(t, translations.Translations{
+		"app": map[string]interface{}{
+			"menu": map[string]interface{}{"@definition": "Menu", "users": "Users"},
+		},
+	}, nested)
+}
@@ -0,0 +1,11 @@
+package translations
+
+/*
+The nested translations of a language, where the nested objects are the
+contexts of the translations and the strings are the translations:
+
+	{"security": {"resources": {"accounts": "comptes utilisateurs"}}}
+
+The translation above has the dotted term "security.resources.accounts".
+*/
+type Translations map[string]interface{}
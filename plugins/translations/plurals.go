@@ -0,0 +1,213 @@
+package translations
+
+import "strings"
+
+// A CLDR cardinal plural rule, for the integer counts.
+type pluralRule func(n int64) string
+
+// The plural rules by base language. The languages missing here use the rule
+// of English: "one" for 1, "other" otherwise.
+var pluralRules = map[string]pluralRule{}
+
+func init() {
+	for _, lang := range []string{"ja", "zh", "ko", "th", "vi", "id", "ms", "lo", "my", "km"} {
+		pluralRules[lang] = pluralOther
+	}
+	for _, lang := range []string{"fr", "ff", "kab", "hy", "pt"} {
+		pluralRules[lang] = pluralZeroOrOne
+	}
+	for _, lang := range []string{"ru", "uk", "be"} {
+		pluralRules[lang] = pluralEastSlavic
+	}
+	for _, lang := range []string{"sr", "hr", "bs", "sh"} {
+		pluralRules[lang] = pluralSouthSlavic
+	}
+	for _, lang := range []string{"cs", "sk"} {
+		pluralRules[lang] = pluralCzech
+	}
+	pluralRules["pl"] = pluralPolish
+	pluralRules["lt"] = pluralLithuanian
+	pluralRules["lv"] = pluralLatvian
+	pluralRules["ro"] = pluralRomanian
+	pluralRules["sl"] = pluralSlovenian
+	pluralRules["he"] = pluralHebrew
+	pluralRules["ar"] = pluralArabic
+	pluralRules["ga"] = pluralIrish
+	pluralRules["cy"] = pluralWelsh
+}
+
+/*
+Returns the CLDR plural category ("one", "few", "other"...) of the count in
+the language, such as "few" for 3 in Polish. The regional variants use the
+rule of their base language, except European Portuguese which uses the English
+rule ("one" for 1 only) while Portuguese uses the French one ("one" for 0 and 1).
+*/
+func PluralCategory(lang string, n int64) string {
+	if n < 0 {
+		n = -n
+	}
+	tag := normalizeLanguage(lang)
+	if tag == "pt-pt" || strings.HasPrefix(tag, "pt-pt-") {
+		return pluralOne(n)
+	}
+	if rule, found := pluralRules[strings.SplitN(tag, "-", 2)[0]]; found {
+		return rule(n)
+	}
+	return pluralOne(n)
+}
+
+func pluralOther(n int64) string {
+	return "other"
+}
+
+func pluralOne(n int64) string {
+	if n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func pluralZeroOrOne(n int64) string {
+	if n == 0 || n == 1 {
+		return "one"
+	}
+	return "other"
+}
+
+func pluralEastSlavic(n int64) string {
+	switch mod10, mod100 := n%10, n%100; {
+	case mod10 == 1 && mod100 != 11:
+		return "one"
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return "few"
+	}
+	return "many"
+}
+
+func pluralSouthSlavic(n int64) string {
+	switch mod10, mod100 := n%10, n%100; {
+	case mod10 == 1 && mod100 != 11:
+		return "one"
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return "few"
+	}
+	return "other"
+}
+
+func pluralCzech(n int64) string {
+	switch {
+	case n == 1:
+		return "one"
+	case n >= 2 && n <= 4:
+		return "few"
+	}
+	return "other"
+}
+
+func pluralPolish(n int64) string {
+	switch mod10, mod100 := n%10, n%100; {
+	case n == 1:
+		return "one"
+	case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+		return "few"
+	}
+	return "many"
+}
+
+func pluralLithuanian(n int64) string {
+	switch mod10, mod100 := n%10, n%100; {
+	case mod10 == 1 && (mod100 < 11 || mod100 > 19):
+		return "one"
+	case mod10 >= 2 && (mod100 < 11 || mod100 > 19):
+		return "few"
+	}
+	return "other"
+}
+
+func pluralLatvian(n int64) string {
+	switch mod10, mod100 := n%10, n%100; {
+	case mod10 == 0 || (mod100 >= 11 && mod100 <= 19):
+		return "zero"
+	case mod10 == 1 && mod100 != 11:
+		return "one"
+	}
+	return "other"
+}
+
+func pluralRomanian(n int64) string {
+	switch mod100 := n % 100; {
+	case n == 1:
+		return "one"
+	case n == 0 || (mod100 >= 1 && mod100 <= 19):
+		return "few"
+	}
+	return "other"
+}
+
+func pluralSlovenian(n int64) string {
+	switch n % 100 {
+	case 1:
+		return "one"
+	case 2:
+		return "two"
+	case 3, 4:
+		return "few"
+	}
+	return "other"
+}
+
+func pluralHebrew(n int64) string {
+	switch n {
+	case 1:
+		return "one"
+	case 2:
+		return "two"
+	}
+	return "other"
+}
+
+func pluralArabic(n int64) string {
+	switch mod100 := n % 100; {
+	case n == 0:
+		return "zero"
+	case n == 1:
+		return "one"
+	case n == 2:
+		return "two"
+	case mod100 >= 3 && mod100 <= 10:
+		return "few"
+	case mod100 >= 11:
+		return "many"
+	}
+	return "other"
+}
+
+func pluralIrish(n int64) string {
+	switch {
+	case n == 1:
+		return "one"
+	case n == 2:
+		return "two"
+	case n >= 3 && n <= 6:
+		return "few"
+	case n >= 7 && n <= 10:
+		return "many"
+	}
+	return "other"
+}
+
+func pluralWelsh(n int64) string {
+	switch n {
+	case 0:
+		return "zero"
+	case 1:
+		return "one"
+	case 2:
+		return "two"
+	case 3:
+		return "few"
+	case 6:
+		return "many"
+	}
+	return "other"
+}
@@ -0,0 +1,393 @@
+package translations
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// The PO header listing the CLDR plural categories matching the indexes
+	// of the plural forms (msgstr[0], msgstr[1]...).
+	pluralCategoriesHeader = "X-Plural-Categories"
+
+	// The flag of the entries having a plural term but a single definition.
+	singularFlag = "agilestack-singular"
+)
+
+/*
+Writes the terms of a language as a gettext PO file.
+
+The contexts, the plural terms, the comments and the references of the terms
+are written as msgctxt, msgid_plural, "#." and "#:" lines. The plural forms
+are written in the canonical order of their CLDR categories, listed by the
+X-Plural-Categories header. The forms missing from a term are empty.
+*/
+func WritePO(w io.Writer, lang string, terms []Term) error {
+	categories := usedPluralCategories(terms)
+
+	out := bufio.NewWriter(w)
+	header := "Language: " + lang + "\n" +
+		"MIME-Version: 1.0\n" +
+		"Content-Type: text/plain; charset=UTF-8\n" +
+		"Content-Transfer-Encoding: 8bit\n"
+	if len(categories) > 0 {
+		header += pluralCategoriesHeader + ": " + strings.Join(categories, ", ") + "\n"
+	}
+	writePOString(out, "msgid", "")
+	writePOString(out, "msgstr", header)
+
+	for _, term := range terms {
+		out.WriteString("\n")
+		writePOComment(out, "#. ", term.Comment)
+		writePOComment(out, "#: ", term.Reference)
+		if term.TermPlural != "" && !term.Definition.IsPlural() {
+			out.WriteString("#, " + singularFlag + "\n")
+		}
+		if term.Context != "" {
+			writePOString(out, "msgctxt", term.Context)
+		}
+		writePOString(out, "msgid", term.Term)
+		switch {
+		case term.Definition.IsPlural():
+			writePOString(out, "msgid_plural", term.TermPlural)
+			for i, category := range categories {
+				writePOString(out, "msgstr["+strconv.Itoa(i)+"]", term.Definition.Plurals[category])
+			}
+		case term.TermPlural != "":
+			writePOString(out, "msgid_plural", term.TermPlural)
+			writePOString(out, "msgstr[0]", term.Definition.Value)
+		default:
+			writePOString(out, "msgstr", term.Definition.Value)
+		}
+	}
+	return out.Flush()
+}
+
+// Returns the plural categories used by the terms, in their canonical order.
+func usedPluralCategories(terms []Term) []string {
+	used := map[string]bool{}
+	for _, term := range terms {
+		for category := range term.Definition.Plurals {
+			used[category] = true
+		}
+	}
+	categories := []string{}
+	for _, category := range PluralCategories {
+		if used[category] {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
+
+func writePOComment(out *bufio.Writer, prefix, comment string) {
+	if comment == "" {
+		return
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		out.WriteString(prefix + line + "\n")
+	}
+}
+
+// Writes a keyword and its string, one line per line of the string.
+func writePOString(out *bufio.Writer, keyword, value string) {
+	lines := strings.SplitAfter(value, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 1 {
+		out.WriteString(keyword + " " + quotePO(value) + "\n")
+		return
+	}
+	out.WriteString(keyword + " \"\"\n")
+	for _, line := range lines {
+		out.WriteString(quotePO(line) + "\n")
+	}
+}
+
+func quotePO(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n", "\r", "\\r", "\t", "\\t")
+	return "\"" + replacer.Replace(value) + "\""
+}
+
+// An entry of a PO file while it is read.
+type poEntry struct {
+	line       int
+	comments   []string
+	references []string
+	flags      []string
+	context    *string
+	id         *string
+	idPlural   *string
+	str        *string
+	plurals    map[int]*string
+
+	// The field the continuation lines are appended to.
+	current *string
+}
+
+/*
+Reads the terms of a gettext PO file, and the language of its header.
+
+The plural forms are matched with the categories of the X-Plural-Categories
+header, or with "one" and "other" when the file has two plural forms. The empty
+plural forms are ignored. The obsolete entries are ignored.
+*/
+func ReadPO(r io.Reader) (lang string, terms []Term, err error) {
+	reader := &poReader{terms: []Term{}}
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		if err := reader.readLine(lineNumber, strings.TrimSpace(scanner.Text())); err != nil {
+			return "", nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+	if err := reader.flush(); err != nil {
+		return "", nil, err
+	}
+	return reader.headers["Language"], reader.terms, nil
+}
+
+type poReader struct {
+	entry   *poEntry
+	headers map[string]string
+	terms   []Term
+}
+
+func (r *poReader) readLine(number int, line string) error {
+	switch {
+	case line == "":
+		return r.flush()
+	case strings.HasPrefix(line, "#~"), strings.HasPrefix(line, "#|"):
+		return nil
+	case strings.HasPrefix(line, "#"):
+		// A comment after the strings of an entry starts a new entry
+		if r.entry != nil && (r.entry.str != nil || len(r.entry.plurals) > 0) {
+			if err := r.flush(); err != nil {
+				return err
+			}
+		}
+		entry := r.startEntry(number)
+		switch {
+		case strings.HasPrefix(line, "#."):
+			entry.comments = append(entry.comments, strings.TrimPrefix(strings.TrimPrefix(line, "#."), " "))
+		case strings.HasPrefix(line, "#:"):
+			entry.references = append(entry.references, strings.TrimPrefix(strings.TrimPrefix(line, "#:"), " "))
+		case strings.HasPrefix(line, "#,"):
+			for _, flag := range strings.Split(strings.TrimPrefix(line, "#,"), ",") {
+				entry.flags = append(entry.flags, strings.TrimSpace(flag))
+			}
+		}
+		return nil
+	case strings.HasPrefix(line, "\""):
+		if r.entry == nil || r.entry.current == nil {
+			return fmt.Errorf("Line %d: unexpected string", number)
+		}
+		value, err := unquotePO(line)
+		if err != nil {
+			return fmt.Errorf("Line %d: %v", number, err)
+		}
+		*r.entry.current += value
+		return nil
+	}
+
+	space := strings.IndexAny(line, " \t")
+	if space < 0 {
+		return fmt.Errorf("Line %d: unexpected line \"%s\"", number, line)
+	}
+	keyword := line[:space]
+	value, err := unquotePO(strings.TrimSpace(line[space:]))
+	if err != nil {
+		return fmt.Errorf("Line %d: %v", number, err)
+	}
+
+	// A msgctxt or a msgid after the strings of an entry starts a new entry
+	if (keyword == "msgctxt" || keyword == "msgid") && r.entry != nil && (r.entry.str != nil || len(r.entry.plurals) > 0) {
+		if err := r.flush(); err != nil {
+			return err
+		}
+	}
+	entry := r.startEntry(number)
+	field := &value
+	switch {
+	case keyword == "msgctxt":
+		entry.context = field
+	case keyword == "msgid":
+		entry.id = field
+	case keyword == "msgid_plural":
+		entry.idPlural = field
+	case keyword == "msgstr":
+		entry.str = field
+	case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+		index, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+		if err != nil || index < 0 {
+			return fmt.Errorf("Line %d: invalid plural index \"%s\"", number, keyword)
+		}
+		if entry.plurals == nil {
+			entry.plurals = map[int]*string{}
+		}
+		entry.plurals[index] = field
+	default:
+		return fmt.Errorf("Line %d: unknown keyword \"%s\"", number, keyword)
+	}
+	entry.current = field
+	return nil
+}
+
+func (r *poReader) startEntry(number int) *poEntry {
+	if r.entry == nil {
+		r.entry = &poEntry{line: number}
+	}
+	return r.entry
+}
+
+// Converts the entry being read into a term, or reads the header.
+func (r *poReader) flush() error {
+	entry := r.entry
+	r.entry = nil
+	if entry == nil {
+		return nil
+	}
+	if entry.id == nil {
+		if entry.context == nil && entry.str == nil && len(entry.plurals) == 0 {
+			// Only comments
+			return nil
+		}
+		return fmt.Errorf("Line %d: entry without msgid", entry.line)
+	}
+
+	if *entry.id == "" && entry.context == nil {
+		if r.headers != nil {
+			return fmt.Errorf("Line %d: duplicate header", entry.line)
+		}
+		r.headers = parsePOHeaders(entry.str)
+		return nil
+	}
+
+	term := Term{
+		Term:      *entry.id,
+		Comment:   strings.Join(entry.comments, "\n"),
+		Reference: strings.Join(entry.references, "\n"),
+	}
+	if entry.context != nil {
+		term.Context = *entry.context
+	}
+	switch {
+	case entry.idPlural != nil && hasFlag(entry.flags, singularFlag):
+		term.TermPlural = *entry.idPlural
+		if form, found := entry.plurals[0]; found {
+			term.Definition.Value = *form
+		}
+	case entry.idPlural != nil:
+		term.TermPlural = *entry.idPlural
+		categories, err := r.pluralCategories()
+		if err != nil {
+			return fmt.Errorf("Line %d: %v", entry.line, err)
+		}
+		term.Definition.Plurals = map[string]string{}
+		for index, form := range entry.plurals {
+			if index >= len(categories) {
+				return fmt.Errorf("Line %d: no plural category for the form %d", entry.line, index)
+			}
+			if *form != "" {
+				term.Definition.Plurals[categories[index]] = *form
+			}
+		}
+	case entry.str != nil:
+		term.Definition.Value = *entry.str
+	default:
+		return fmt.Errorf("Line %d: entry without msgstr", entry.line)
+	}
+	r.terms = append(r.terms, term)
+	return nil
+}
+
+// Returns the plural categories matching the indexes of the plural forms.
+func (r *poReader) pluralCategories() ([]string, error) {
+	if header := r.headers[pluralCategoriesHeader]; header != "" {
+		categories := []string{}
+		for _, category := range strings.Split(header, ",") {
+			category = strings.TrimSpace(category)
+			if !isPluralCategory(category) {
+				return nil, fmt.Errorf("Unknown plural category: \"%s\"", category)
+			}
+			categories = append(categories, category)
+		}
+		return categories, nil
+	}
+	for _, part := range strings.Split(r.headers["Plural-Forms"], ";") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "nplurals=1":
+			return []string{"other"}, nil
+		case "nplurals=2":
+			return []string{"one", "other"}, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown plural categories, the %s header is required", pluralCategoriesHeader)
+}
+
+func parsePOHeaders(header *string) map[string]string {
+	headers := map[string]string{}
+	if header == nil {
+		return headers
+	}
+	for _, line := range strings.Split(*header, "\n") {
+		if colon := strings.Index(line, ":"); colon > 0 {
+			headers[strings.TrimSpace(line[:colon])] = strings.TrimSpace(line[colon+1:])
+		}
+	}
+	return headers
+}
+
+func hasFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func unquotePO(value string) (string, error) {
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", fmt.Errorf("invalid string %s", value)
+	}
+	var unquoted bytes.Buffer
+	escaped := false
+	for _, c := range value[1 : len(value)-1] {
+		if !escaped {
+			if c == '\\' {
+				escaped = true
+			} else {
+				unquoted.WriteRune(c)
+			}
+			continue
+		}
+		escaped = false
+		switch c {
+		case 'n':
+			unquoted.WriteRune('\n')
+		case 'r':
+			unquoted.WriteRune('\r')
+		case 't':
+			unquoted.WriteRune('\t')
+		case '\\', '"':
+			unquoted.WriteRune(c)
+		default:
+			return "", fmt.Errorf("invalid escape sequence \\%c in %s", c, value)
+		}
+	}
+	if escaped {
+		return "", fmt.Errorf("invalid string %s", value)
+	}
+	return unquoted.String(), nil
+}
@@ -0,0 +1,90 @@
+package translations_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPO_RoundTrip(t *testing.T) {
+	terms := readTransportTerms(t)
+	terms = append(terms,
+		translations.Term{Term: "app.quote", Definition: translations.Definition{Value: "Say \"hello\"\\\tnow"}},
+		translations.Term{Term: "app.singular", TermPlural: "app.singulars", Definition: translations.Definition{Value: "Only one"}},
+		translations.Term{Term: "app.comment", Comment: "First line\nSecond line", Definition: translations.Definition{Value: "x"}},
+	)
+
+	var buffer bytes.Buffer
+	require.Nil(t, translations.WritePO(&buffer, "en", terms))
+	po := buffer.String()
+	require.Contains(t, po, "msgctxt \"form_label\"\nmsgid \"app.login.text\"\nmsgstr \"Username\"\n")
+	require.Contains(t, po, "#. Please don't change the name of the application\n#: /app/modules/views\nmsgid \"app.name\"\n")
+	require.Contains(t, po, "msgid \"app.users.active\"\nmsgid_plural \"app.users.active\"\n"+
+		"msgstr[0] \"Just one user online\"\nmsgstr[1] \"There are %d users online\"\n")
+	require.Contains(t, po, "\"X-Plural-Categories: one, other\\n\"")
+
+	lang, result, err := translations.ReadPO(&buffer)
+	require.Nil(t, err)
+	require.Equal(t, "en", lang)
+	require.Equal(t, terms, result)
+}
+
+func TestReadPO(t *testing.T) {
+	po := `# Translator comment
+msgid ""
+msgstr ""
+"Language: fr\n"
+"Plural-Forms: nplurals=2; plural=(n > 1);\n"
+
+#. Shown on the dashboard
+#: src/dashboard.js:12
+#, c-format
+msgid "app.users.active"
+msgid_plural "app.users.active"
+msgstr[0] "Un utilisateur"
+msgstr[1] ""
+"%d utilisateurs"
+
+msgctxt "form_label"
+msgid "app.login.text"
+msgstr ""
+"Nom "
+"d'utilisateur"
+#~ msgid "app.old"
+#~ msgstr "Obsolète"
+`
+	lang, terms, err := translations.ReadPO(strings.NewReader(po))
+	require.Nil(t, err)
+	require.Equal(t, "fr", lang)
+	require.Equal(t, []translations.Term{
+		{
+			Term:       "app.users.active",
+			TermPlural: "app.users.active",
+			Comment:    "Shown on the dashboard",
+			Reference:  "src/dashboard.js:12",
+			Definition: translations.Definition{Plurals: map[string]string{"one": "Un utilisateur", "other": "%d utilisateurs"}},
+		},
+		{
+			Term:       "app.login.text",
+			Context:    "form_label",
+			Definition: translations.Definition{Value: "Nom d'utilisateur"},
+		},
+	}, terms)
+}
+
+func TestReadPO_Invalid(t *testing.T) {
+	_, _, err := translations.ReadPO(strings.NewReader("msgid \"app.name\"\nmsgstr \"TODO\"\nmsgunknown \"x\"\n"))
+	require.NotNil(t, err)
+	require.Equal(t, "Line 3: unknown keyword \"msgunknown\"", err.Error())
+
+	_, _, err = translations.ReadPO(strings.NewReader("msgid \"app.users\"\nmsgid_plural \"app.users\"\nmsgstr[0] \"x\"\n"))
+	require.NotNil(t, err)
+	require.Equal(t, "Line 1: Unknown plural categories, the X-Plural-Categories header is required", err.Error())
+
+	_, _, err = translations.ReadPO(strings.NewReader("msgid \"app.name\nmsgstr \"TODO\"\n"))
+	require.NotNil(t, err)
+	require.Equal(t, "Line 1: invalid string \"app.name", err.Error())
+}
@@ -0,0 +1,355 @@
+package translations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The keys of the nested translations holding the details of a term. They
+// start with "@" to be told apart from the contexts of the terms.
+const (
+	definitionKey = "@definition"
+	pluralKey     = "@plural"
+	referenceKey  = "@reference"
+	commentKey    = "@comment"
+	contextsKey   = "@contexts"
+)
+
+// The CLDR plural categories, in their canonical order.
+var PluralCategories = []string{"zero", "one", "two", "few", "many", "other"}
+
+// A translation term, as in the term lists of POEditor.
+type Term struct {
+	// The dotted term, such as "app.login.text".
+	Term       string     `json:"term"`
+	Definition Definition `json:"definition"`
+
+	// Tells apart the translations of the same term used in different places.
+	Context string `json:"context"`
+
+	// The plural term, set for the plural translations.
+	TermPlural string `json:"term_plural"`
+	Reference  string `json:"reference"`
+	Comment    string `json:"comment"`
+}
+
+/*
+The translation of a term: a single string, or the plural forms by CLDR
+category ("one", "other"...). In JSON, it is either a string or an object:
+
+	{"one": "Just one user online", "other": "There are %d users online"}
+*/
+type Definition struct {
+	Value   string
+	Plurals map[string]string
+}
+
+// Returns true if the definition has plural forms.
+func (d Definition) IsPlural() bool {
+	return len(d.Plurals) > 0
+}
+
+func (d Definition) MarshalJSON() ([]byte, error) {
+	if d.IsPlural() {
+		return json.Marshal(d.Plurals)
+	}
+	return json.Marshal(d.Value)
+}
+
+func (d *Definition) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	definition, err := parseDefinition(value)
+	if err != nil {
+		return err
+	}
+	*d = definition
+	return nil
+}
+
+func parseDefinition(value interface{}) (Definition, error) {
+	switch value := value.(type) {
+	case string:
+		return Definition{Value: value}, nil
+	case map[string]interface{}:
+		plurals := make(map[string]string, len(value))
+		for category, form := range value {
+			if !isPluralCategory(category) {
+				return Definition{}, fmt.Errorf("Unknown plural category: \"%s\"", category)
+			}
+			text, ok := form.(string)
+			if !ok {
+				return Definition{}, fmt.Errorf("The plural form \"%s\" must be a string", category)
+			}
+			plurals[category] = text
+		}
+		return Definition{Plurals: plurals}, nil
+	case map[string]string:
+		plurals := make(map[string]interface{}, len(value))
+		for category, form := range value {
+			plurals[category] = form
+		}
+		return parseDefinition(plurals)
+	}
+	return Definition{}, errors.New("A definition must be a string or an object of plural forms")
+}
+
+func isPluralCategory(category string) bool {
+	for _, c := range PluralCategories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Returns the terms of the nested translations, sorted by term then context.
+
+In the nested translations, a term is a string, or an object holding its
+details when it has a context, plural forms, a reference or a comment:
+
+	{
+		"app": {
+			"users": {
+				"active": {
+					"@definition": {"one": "Just one user online", "other": "There are %d users online"},
+					"@plural": "app.users.active",
+					"@reference": "/app/modules/views",
+					"@comment": "Shown on the dashboard",
+					"@contexts": {"title": "Online users"}
+				}
+			}
+		}
+	}
+
+The keys not starting with "@" are the contexts of other terms
+("app.users.active.title" for instance).
+*/
+func ToTerms(translations Translations) ([]Term, error) {
+	terms := []Term{}
+	if err := parseNode("", translations, &terms); err != nil {
+		return nil, err
+	}
+	sort.Sort(byTermAndContext(terms))
+	return terms, nil
+}
+
+func parseNode(path string, node map[string]interface{}, terms *[]Term) error {
+	for key, value := range node {
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+		term := joinTerm(path, key)
+		if key == "" || strings.Contains(key, ".") {
+			return fmt.Errorf("Translation key must not be empty nor contain a dot: \"%s\"", term)
+		}
+		if text, ok := value.(string); ok {
+			*terms = append(*terms, Term{Term: term, Definition: Definition{Value: text}})
+			continue
+		}
+		child, ok := asNode(value)
+		if !ok {
+			return fmt.Errorf("Translation must be a string or an object: \"%s\"", term)
+		}
+		if err := parseNode(term, child, terms); err != nil {
+			return err
+		}
+	}
+
+	for key := range node {
+		if !strings.HasPrefix(key, "@") {
+			continue
+		}
+		if path == "" {
+			return errors.New("The root of the translations cannot be a term")
+		}
+		if key != contextsKey && !isTermKey(key) {
+			return fmt.Errorf("Unknown key \"%s\" of the term \"%s\"", key, path)
+		}
+	}
+
+	if _, found := node[definitionKey]; found {
+		term, err := parseTerm(path, "", node)
+		if err != nil {
+			return err
+		}
+		*terms = append(*terms, term)
+	} else {
+		for _, key := range []string{pluralKey, referenceKey, commentKey} {
+			if _, found := node[key]; found {
+				return fmt.Errorf("The term \"%s\" has a %s but no %s", path, key, definitionKey)
+			}
+		}
+	}
+
+	contexts, found := node[contextsKey]
+	if !found {
+		return nil
+	}
+	byContext, ok := asNode(contexts)
+	if !ok {
+		return fmt.Errorf("The contexts of the term \"%s\" must be an object", path)
+	}
+	for context, value := range byContext {
+		if context == "" {
+			return fmt.Errorf("The term \"%s\" has an empty context", path)
+		}
+		if text, ok := value.(string); ok {
+			*terms = append(*terms, Term{Term: path, Context: context, Definition: Definition{Value: text}})
+			continue
+		}
+		details, ok := asNode(value)
+		if !ok {
+			return fmt.Errorf("The term \"%s\" in the context \"%s\" must be a string or an object", path, context)
+		}
+		for key := range details {
+			if !isTermKey(key) {
+				return fmt.Errorf("Unknown key \"%s\" of the term \"%s\" in the context \"%s\"", key, path, context)
+			}
+		}
+		if _, found := details[definitionKey]; !found {
+			return fmt.Errorf("The term \"%s\" in the context \"%s\" has no %s", path, context, definitionKey)
+		}
+		term, err := parseTerm(path, context, details)
+		if err != nil {
+			return err
+		}
+		*terms = append(*terms, term)
+	}
+	return nil
+}
+
+// Reads the details of a term from the "@" keys of its object.
+func parseTerm(path, context string, details map[string]interface{}) (Term, error) {
+	definition, err := parseDefinition(details[definitionKey])
+	if err != nil {
+		return Term{}, fmt.Errorf("Invalid definition of the term \"%s\": %v", path, err)
+	}
+	term := Term{Term: path, Context: context, Definition: definition}
+	for key, field := range map[string]*string{pluralKey: &term.TermPlural, referenceKey: &term.Reference, commentKey: &term.Comment} {
+		value, found := details[key]
+		if !found {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return Term{}, fmt.Errorf("The %s of the term \"%s\" must be a string", key, path)
+		}
+		*field = text
+	}
+	return term, nil
+}
+
+func isTermKey(key string) bool {
+	return key == definitionKey || key == pluralKey || key == referenceKey || key == commentKey
+}
+
+func asNode(value interface{}) (map[string]interface{}, bool) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return value, true
+	case Translations:
+		return value, true
+	}
+	return nil, false
+}
+
+/*
+Returns the nested translations of the terms (see ToTerms for the format).
+The terms without details are plain strings.
+
+Fails when a term is invalid, or when the same term appears twice in a context.
+*/
+func FromTerms(terms []Term) (Translations, error) {
+	translations := Translations{}
+	for _, term := range terms {
+		parts := strings.Split(term.Term, ".")
+		for _, part := range parts {
+			if part == "" || strings.HasPrefix(part, "@") {
+				return nil, fmt.Errorf("Invalid term: \"%s\"", term.Term)
+			}
+		}
+
+		node := map[string]interface{}(translations)
+		for _, part := range parts {
+			child, found := node[part].(map[string]interface{})
+			if !found {
+				child = map[string]interface{}{}
+				node[part] = child
+			}
+			node = child
+		}
+
+		if term.Context != "" {
+			contexts, found := node[contextsKey].(map[string]interface{})
+			if !found {
+				contexts = map[string]interface{}{}
+				node[contextsKey] = contexts
+			}
+			if _, found := contexts[term.Context]; found {
+				return nil, fmt.Errorf("Duplicate term \"%s\" in the context \"%s\"", term.Term, term.Context)
+			}
+			details := map[string]interface{}{}
+			setTermDetails(details, term)
+			contexts[term.Context] = details
+			continue
+		}
+		if _, found := node[definitionKey]; found {
+			return nil, fmt.Errorf("Duplicate term \"%s\"", term.Term)
+		}
+		setTermDetails(node, term)
+	}
+	collapse(translations)
+	return translations, nil
+}
+
+func setTermDetails(details map[string]interface{}, term Term) {
+	if term.Definition.IsPlural() {
+		plurals := make(map[string]interface{}, len(term.Definition.Plurals))
+		for category, form := range term.Definition.Plurals {
+			plurals[category] = form
+		}
+		details[definitionKey] = plurals
+	} else {
+		details[definitionKey] = term.Definition.Value
+	}
+	for key, value := range map[string]string{pluralKey: term.TermPlural, referenceKey: term.Reference, commentKey: term.Comment} {
+		if value != "" {
+			details[key] = value
+		}
+	}
+}
+
+// Replaces the objects only holding a single definition with the definition.
+func collapse(node map[string]interface{}) {
+	for key, value := range node {
+		if key == definitionKey {
+			continue
+		}
+		child, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		collapse(child)
+		if definition, ok := child[definitionKey].(string); ok && len(child) == 1 {
+			node[key] = definition
+		}
+	}
+}
+
+type byTermAndContext []Term
+
+func (t byTermAndContext) Len() int      { return len(t) }
+func (t byTermAndContext) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t byTermAndContext) Less(i, j int) bool {
+	if t[i].Term != t[j].Term {
+		return t[i].Term < t[j].Term
+	}
+	return t[i].Context < t[j].Context
+}
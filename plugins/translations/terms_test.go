@@ -0,0 +1,139 @@
+package translations_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/plugins/translations"
+	"github.com/stretchr/testify/require"
+)
+
+func readTransportTerms(t *testing.T) []translations.Term {
+	data, err := ioutil.ReadFile("../translations_transport.en.json")
+	require.Nil(t, err)
+	terms := []translations.Term{}
+	require.Nil(t, json.Unmarshal(data, &terms))
+	return terms
+}
+
+func sortTerms(terms []translations.Term) []translations.Term {
+	sorted := append([]translations.Term{}, terms...)
+	sort.Sort(byTermAndContext(sorted))
+	return sorted
+}
+
+type byTermAndContext []translations.Term
+
+func (t byTermAndContext) Len() int      { return len(t) }
+func (t byTermAndContext) Swap(i, j int) { t[i], t[j] = t[j], t[i] }
+func (t byTermAndContext) Less(i, j int) bool {
+	if t[i].Term != t[j].Term {
+		return t[i].Term < t[j].Term
+	}
+	return t[i].Context < t[j].Context
+}
+
+func TestTerms_RoundTrip(t *testing.T) {
+	terms := readTransportTerms(t)
+
+	nested, err := translations.FromTerms(terms)
+	require.Nil(t, err)
+	result, err := translations.ToTerms(nested)
+	require.Nil(t, err)
+	require.Equal(t, sortTerms(terms), result)
+
+	// Through JSON, as stored in Consul
+	data, err := json.Marshal(nested)
+	require.Nil(t, err)
+	stored := translations.Translations{}
+	require.Nil(t, json.Unmarshal(data, &stored))
+	require.Equal(t, translations.Translations(nested), stored)
+	result, err = translations.ToTerms(stored)
+	require.Nil(t, err)
+	require.Equal(t, sortTerms(terms), result)
+
+	// The term list renders as the POEditor one
+	data, err = json.Marshal(result)
+	require.Nil(t, err)
+	rendered := []map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(data, &rendered))
+	require.Equal(t, map[string]interface{}{"one": "Just one user online", "other": "There are %d users online"},
+		rendered[len(rendered)-1]["definition"])
+}
+
+func TestFromTerms(t *testing.T) {
+	nested, err := translations.FromTerms([]translations.Term{
+		{Term: "app.login.text", Definition: translations.Definition{Value: "Enter your credentials"}},
+		{Term: "app.login.text", Context: "form_label", Definition: translations.Definition{Value: "Username"}},
+		{Term: "app.name", Definition: translations.Definition{Value: "TODO List"}, Comment: "Do not change"},
+		{Term: "app.pass", Definition: translations.Definition{Value: "Password"}},
+	})
+	require.Nil(t, err)
+	require.Equal(t, translations.Translations{
+		"app": map[string]interface{}{
+			"login": map[string]interface{}{
+				"text": map[string]interface{}{
+					"@definition": "Enter your credentials",
+					"@contexts":   map[string]interface{}{"form_label": "Username"},
+				},
+			},
+			"name": map[string]interface{}{
+				"@definition": "TODO List",
+				"@comment":    "Do not change",
+			},
+			"pass": "Password",
+		},
+	}, nested)
+}
+
+func TestFromTerms_Duplicate(t *testing.T) {
+	_, err := translations.FromTerms([]translations.Term{
+		{Term: "app.name", Context: "title", Definition: translations.Definition{Value: "TODO List"}},
+		{Term: "app.name", Context: "title", Definition: translations.Definition{Value: "TODO"}},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Duplicate term \"app.name\" in the context \"title\"", err.Error())
+
+	_, err = translations.FromTerms([]translations.Term{{Term: "app..name"}})
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid term: \"app..name\"", err.Error())
+}
+
+func TestToTerms_Nested(t *testing.T) {
+	data, err := ioutil.ReadFile("../translations_to_import.en.json")
+	require.Nil(t, err)
+	nested := translations.Translations{}
+	require.Nil(t, json.Unmarshal(data, &nested))
+
+	terms, err := translations.ToTerms(nested)
+	require.Nil(t, err)
+	require.Equal(t, 12, len(terms))
+	require.Equal(t, translations.Term{Term: "app.login.message.error",
+		Definition: translations.Definition{Value: "Make sure you've entered the correct username and password"}}, terms[0])
+
+	result, err := translations.FromTerms(terms)
+	require.Nil(t, err)
+	require.Equal(t, nested, result)
+}
+
+func TestToTerms_Invalid(t *testing.T) {
+	_, err := translations.ToTerms(translations.Translations{
+		"app": map[string]interface{}{"name": map[string]interface{}{"@comment": "No definition"}},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "The term \"app.name\" has a @comment but no @definition", err.Error())
+
+	_, err = translations.ToTerms(translations.Translations{
+		"app": map[string]interface{}{"name": map[string]interface{}{"@definition": map[string]interface{}{"several": "x"}}},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Invalid definition of the term \"app.name\": Unknown plural category: \"several\"", err.Error())
+
+	_, err = translations.ToTerms(translations.Translations{
+		"app": map[string]interface{}{"name": map[string]interface{}{"@definition": "TODO", "@author": "me"}},
+	})
+	require.NotNil(t, err)
+	require.Equal(t, "Unknown key \"@author\" of the term \"app.name\"", err.Error())
+}
@@ -0,0 +1,36 @@
+package translations
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const languagePattern = "^[a-z]{2,3}([_\\-][a-zA-Z0-9]+)*$"
+
+// Checks the contribution of a plugin to the translations of a language.
+func Validate(pluginName, lang string, translations Translations) error {
+	if matched, err := regexp.MatchString("^\\s*$", pluginName); matched || err != nil {
+		return errors.New("Plugin name must not be blank")
+	}
+	if strings.Contains(pluginName, "/") {
+		return fmt.Errorf("Plugin name must not contain a slash: \"%s\"", pluginName)
+	}
+	if err := ValidateLanguage(lang); err != nil {
+		return err
+	}
+	if translations == nil {
+		return errors.New("Translations must not be nil")
+	}
+	_, err := ToTerms(translations)
+	return err
+}
+
+// Checks that the language is a language code such as "en" or "pt-BR".
+func ValidateLanguage(lang string) error {
+	if matched, err := regexp.MatchString(languagePattern, lang); !matched || err != nil {
+		return fmt.Errorf("The language does not match the pattern \"%s\": \"%s\"", languagePattern, lang)
+	}
+	return nil
+}
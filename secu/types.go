@@ -2,14 +2,84 @@ package secu
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/ory-am/hydra/account"
 	"github.com/ory-am/ladon/policy"
 )
 
+// A role is a named bundle of permissions on resources.
+// It is materialized as one Hydra policy per grant.
 type Role struct {
-	Id          string `json:"id"`
-	Description string `json:"description"`
+	Id          string  `json:"id"`
+	Description string  `json:"description"`
+	Grants      []Grant `json:"grants"`
+}
+
+// A grant gives some permissions on a resource (referenced by its key).
+type Grant struct {
+	Resource    string   `json:"resource"`
+	Permissions []string `json:"permissions"`
+}
+
+const rolePolicyPrefix = "role:"
+
+// The pattern of the role ids and of the keys of the granted resources. They
+// are parts of the role policy ids, which are parts of the paths of the Hydra
+// requests: the ":", "/", "?" and "#" characters are not allowed.
+const RolePolicyPartPattern = "^[a-zA-Z0-9\\-_.]+$"
+
+var rolePolicyPartRegexp = regexp.MustCompile(RolePolicyPartPattern)
+
+// Checks that the role id and the resource key make an unambiguous role
+// policy id (see RolePolicyPartPattern).
+func ValidateRolePolicyId(roleId, resourceKey string) error {
+	if !rolePolicyPartRegexp.MatchString(roleId) {
+		return fmt.Errorf("The role id does not match the pattern \"%s\": \"%s\"", RolePolicyPartPattern, roleId)
+	}
+	if !rolePolicyPartRegexp.MatchString(resourceKey) {
+		return fmt.Errorf("The resource key does not match the pattern \"%s\": \"%s\"", RolePolicyPartPattern, resourceKey)
+	}
+	return nil
+}
+
+// Returns the id of the policy materializing the grant of the given role
+// on the given resource.
+func RolePolicyId(roleId, resourceKey string) string {
+	return rolePolicyPrefix + roleId + ":" + resourceKey
+}
+
+// Returns true if the given policy id materializes a grant of the given role,
+// whatever the tenant of the policy.
+func IsRolePolicyId(policyId, roleId string) bool {
+	_, id := SplitTenantPolicyId(policyId)
+	return strings.HasPrefix(id, rolePolicyPrefix+roleId+":")
+}
+
+// A group of users. The id of a group can be used as a policy subject
+// (see GroupSubject), so that all the group members get the policy.
+type Group struct {
+	Id          string   `json:"id"`
+	Description string   `json:"description"`
+	Members     []string `json:"members"` // user ids
+}
+
+const groupSubjectPrefix = "group:"
+
+// Returns the policy subject standing for the members of the given group.
+func GroupSubject(groupId string) string {
+	return groupSubjectPrefix + groupId
+}
+
+// Returns the group id matching the given policy subject, and false if the
+// subject does not stand for a group.
+func SubjectGroupId(subject string) (string, bool) {
+	if !strings.HasPrefix(subject, groupSubjectPrefix) {
+		return "", false
+	}
+	return subject[len(groupSubjectPrefix):], true
 }
 
 type UserData struct {
@@ -17,6 +87,7 @@ type UserData struct {
 	LastName  string `json:"lastName"`
 	Inactive  bool   `json:"inactive"`
 	Blocked   bool   `json:"blocked"`
+	Tenant    string `json:"tenant,omitempty"`
 }
 
 type User struct {
@@ -32,6 +103,30 @@ type Policy struct {
 	Subjects    []string `json:"subjects"`
 	Permissions []string `json:"permissions"`
 	Resource    string   `json:"resource"` // only one resource per policy
+	Tenant      string   `json:"tenant,omitempty"`
+}
+
+const tenantPolicyPrefix = "tenant:"
+
+// Returns the id of a policy belonging to the given tenant.
+// The policies of the default tenant keep their id.
+func TenantPolicyId(tenant, id string) string {
+	if tenant == "" {
+		return id
+	}
+	return tenantPolicyPrefix + tenant + ":" + id
+}
+
+// Splits the id of a policy into its tenant and the id of the policy inside the tenant.
+func SplitTenantPolicyId(policyId string) (tenant, id string) {
+	if !strings.HasPrefix(policyId, tenantPolicyPrefix) {
+		return "", policyId
+	}
+	parts := strings.SplitN(policyId[len(tenantPolicyPrefix):], ":", 2)
+	if len(parts) != 2 {
+		return "", policyId
+	}
+	return parts[0], parts[1]
 }
 
 type UpdateLoginRequest struct {
@@ -91,12 +186,14 @@ func ConvertPolicy(policy *policy.DefaultPolicy) *Policy {
 		return nil
 	}
 
+	tenant, _ := SplitTenantPolicyId(policy.ID)
 	return &Policy{
 		Id:          policy.ID,
 		Description: policy.Description,
 		Subjects:    policy.Subjects,
 		Permissions: policy.Permissions,
 		Resource:    policy.Resources[0],
+		Tenant:      tenant,
 	}
 }
 
@@ -130,3 +227,59 @@ func (p *Policy) ToPolicy() *policy.DefaultPolicy {
 		Permissions: p.Permissions,
 	}
 }
+
+// The permissions a user actually has, resource by resource.
+type EffectivePermissions struct {
+	UserId    string                `json:"userId"`
+	Resources []ResourcePermissions `json:"resources"`
+}
+
+type ResourcePermissions struct {
+	// The resource key, or the policy resource when it does not match any
+	// registered resource.
+	Resource    string `json:"resource"`
+	SecurityKey string `json:"securityKey"`
+
+	Permissions []GrantedPermission `json:"permissions"`
+}
+
+// A permission together with the policy granting (or denying) it.
+type GrantedPermission struct {
+	Permission        string `json:"permission"`
+	Effect            string `json:"effect"`
+	PolicyId          string `json:"policyId"`
+	PolicyDescription string `json:"policyDescription"`
+
+	// Whether the policy only applies under conditions (e.g. SubjectIsOwner).
+	Conditional bool `json:"conditional"`
+}
+
+// Renders the effective permissions as JSON, for the administration UI.
+func (p *EffectivePermissions) JSON() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+/*
+Returns true if the permission on the resource (referenced by its key) is
+allowed and not denied, whatever the request. The conditional policies (such as
+SubjectIsOwner) may not apply: their grants do not allow the permission, while
+their denials deny it.
+*/
+func (p *EffectivePermissions) IsAllowed(resource, permission string) bool {
+	allowed := false
+	for _, resourcePermissions := range p.Resources {
+		if resourcePermissions.Resource != resource {
+			continue
+		}
+		for _, granted := range resourcePermissions.Permissions {
+			if granted.Permission != permission {
+				continue
+			}
+			if granted.Effect == "deny" {
+				return false
+			}
+			allowed = allowed || !granted.Conditional
+		}
+	}
+	return allowed
+}
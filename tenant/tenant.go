@@ -0,0 +1,111 @@
+// Package tenant isolates the data of the customers hosted by a same platform.
+//
+// The tenant of a request travels in its context. The default tenant (empty
+// identifier) matches the data stored before tenants were introduced.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	// The HTTP header holding the tenant of a request.
+	Header = "X-Agilestack-Tenant"
+
+	// The default tenant.
+	Default = ""
+
+	rootPrefix    = "agilestack/"
+	tenantsPrefix = rootPrefix + "tenants/"
+	idPattern     = "^[a-z0-9\\-_]*$"
+)
+
+type contextKey struct{}
+
+var idRegexp = regexp.MustCompile(idPattern)
+
+// Returns a copy of the context holding the given tenant.
+func NewContext(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantId)
+}
+
+// Returns the tenant held by the context, or the default tenant.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return Default
+	}
+	tenantId, _ := ctx.Value(contextKey{}).(string)
+	return tenantId
+}
+
+// Checks that the given tenant identifier is valid.
+func Validate(tenantId string) error {
+	if !idRegexp.MatchString(tenantId) {
+		return fmt.Errorf("The tenant does not match the pattern \"%s\": \"%s\"", idPattern, tenantId)
+	}
+	return nil
+}
+
+/*
+Middleware stores the tenant found in the request header into the request
+context. Requests with an invalid tenant are rejected.
+
+The header is chosen by the client: Middleware must only be used behind a
+trusted proxy which strips or overwrites the X-Agilestack-Tenant header of the
+incoming requests. Otherwise, use NewMiddleware with a resolver deriving the
+tenant from the authenticated user, such as auth.HydraClient.TenantResolver.
+*/
+func Middleware(next http.Handler) http.Handler {
+	return NewMiddleware(HeaderResolver)(next)
+}
+
+// A Resolver returns the tenant of a request.
+type Resolver func(r *http.Request) (string, error)
+
+// HeaderResolver returns the tenant of the X-Agilestack-Tenant header of the
+// request (see Middleware).
+func HeaderResolver(r *http.Request) (string, error) {
+	return r.Header.Get(Header), nil
+}
+
+// NewMiddleware returns a middleware storing the tenant returned by the
+// resolver into the request context. The requests whose tenant cannot be
+// resolved are rejected as unauthorized, the ones with an invalid tenant as
+// bad requests.
+func NewMiddleware(resolve Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenantId, err := resolve(r)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			if err := Validate(tenantId); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), tenantId)))
+		})
+	}
+}
+
+// Returns the Consul key scoped to the tenant of the context.
+//
+// Keys of the default tenant are unchanged, other keys are moved under
+// "agilestack/tenants/<tenant>/":
+// - agilestack/menu/plugin => agilestack/tenants/<tenant>/menu/plugin
+func Key(ctx context.Context, key string) string {
+	return TenantKey(FromContext(ctx), key)
+}
+
+// Returns the Consul key scoped to the given tenant (see Key).
+func TenantKey(tenantId, key string) string {
+	if tenantId == Default {
+		return key
+	}
+	return tenantsPrefix + tenantId + "/" + strings.TrimPrefix(key, rootPrefix)
+}
@@ -0,0 +1,81 @@
+package tenant_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/eogile/agilestack-utils/tenant"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromContext(t *testing.T) {
+	require.Equal(t, tenant.Default, tenant.FromContext(context.Background()))
+	require.Equal(t, "acme", tenant.FromContext(tenant.NewContext(context.Background(), "acme")))
+}
+
+func TestKey(t *testing.T) {
+	ctx := tenant.NewContext(context.Background(), "acme")
+	require.Equal(t, "agilestack/tenants/acme/menu/plugin", tenant.Key(ctx, "agilestack/menu/plugin"))
+	require.Equal(t, "agilestack/tenants/acme/components", tenant.Key(ctx, "agilestack/components"))
+	require.Equal(t, "agilestack/menu/plugin", tenant.Key(context.Background(), "agilestack/menu/plugin"))
+}
+
+func TestValidate(t *testing.T) {
+	require.Nil(t, tenant.Validate(""))
+	require.Nil(t, tenant.Validate("acme-2"))
+	for _, tenantId := range []string{"Acme", "acme/other", "ac me", "../acme"} {
+		err := tenant.Validate(tenantId)
+		require.NotNil(t, err)
+		require.Equal(t, "The tenant does not match the pattern \"^[a-z0-9\\-_]*$\": \""+tenantId+"\"", err.Error())
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var found string
+	handler := tenant.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		found = tenant.FromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest("GET", "/menus", nil)
+	request.Header.Set(tenant.Header, "acme")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "acme", found)
+
+	request = httptest.NewRequest("GET", "/menus", nil)
+	request.Header.Set(tenant.Header, "../other")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestNewMiddleware(t *testing.T) {
+	var found string
+	handler := tenant.NewMiddleware(func(r *http.Request) (string, error) {
+		if r.URL.Query().Get("user") == "" {
+			return "", errors.New("Not Authorized")
+		}
+		return r.URL.Query().Get("user"), nil
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		found = tenant.FromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest("GET", "/menus?user=acme", nil)
+	request.Header.Set(tenant.Header, "other")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "acme", found)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/menus", nil))
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/menus?user=../other", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
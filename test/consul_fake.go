@@ -0,0 +1,342 @@
+package test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	kvPath  = "/v1/kv/"
+	txnPath = "/v1/txn"
+
+	// The maximum duration of a blocking query, as in Consul.
+	maxBlockingWait = 10 * time.Minute
+)
+
+/*
+FakeConsul is an in-process stand-in for the KV HTTP API of Consul.
+
+It supports getting, listing, putting (including check-and-set) and deleting
+keys, the ModifyIndex of the pairs, blocking queries and the transactions made
+of set, delete and delete-tree operations, so the Consul clients can be tested
+without a Consul container.
+*/
+type FakeConsul struct {
+	server *httptest.Server
+
+	mutex sync.Mutex
+	index uint64
+	pairs map[string]*api.KVPair
+	// closed on every write, to wake up the blocking queries
+	changed chan struct{}
+}
+
+/*
+Starts a fake Consul server listening on a local port.
+*/
+func NewFakeConsul() *FakeConsul {
+	c := &FakeConsul{
+		index:   1,
+		pairs:   map[string]*api.KVPair{},
+		changed: make(chan struct{}),
+	}
+	c.server = httptest.NewServer(c)
+	return c
+}
+
+/*
+Returns the connection information of the fake server.
+*/
+func (c *FakeConsul) Info() *ConsulInfo {
+	return &ConsulInfo{Address: strings.TrimPrefix(c.server.URL, "http://")}
+}
+
+/*
+Stops the fake server.
+*/
+func (c *FakeConsul) Close() {
+	c.server.Close()
+}
+
+func (c *FakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == txnPath && r.Method == "PUT" {
+		c.txn(w, r)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, kvPath) {
+		http.NotFound(w, r)
+		return
+	}
+	key := strings.TrimLeft(strings.TrimPrefix(r.URL.Path, kvPath), "/")
+	params := r.URL.Query()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Consul-KnownLeader", "true")
+	w.Header().Set("X-Consul-LastContact", "0")
+
+	switch r.Method {
+	case "GET":
+		c.get(w, key, params)
+	case "PUT":
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.put(w, key, body, params)
+	case "DELETE":
+		c.delete(w, key, params)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (c *FakeConsul) get(w http.ResponseWriter, key string, params map[string][]string) {
+	c.waitForChange(params)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, recurse := params["recurse"]
+	_, keysOnly := params["keys"]
+	_, raw := params["raw"]
+
+	pairs := api.KVPairs{}
+	for _, pair := range c.pairs {
+		if pair.Key == key || ((recurse || keysOnly) && strings.HasPrefix(pair.Key, key)) {
+			copied := *pair
+			pairs = append(pairs, &copied)
+		}
+	}
+	sort.Sort(byKey(pairs))
+
+	index := c.index
+	if len(pairs) == 1 && !recurse && !keysOnly {
+		index = pairs[0].ModifyIndex
+	}
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(index, 10))
+
+	if len(pairs) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case keysOnly:
+		json.NewEncoder(w).Encode(keys(pairs, key, first(params["separator"])))
+	case raw:
+		w.Write(pairs[0].Value)
+	default:
+		json.NewEncoder(w).Encode(pairs)
+	}
+}
+
+// Waits for a write when the request is a blocking query (with an index).
+func (c *FakeConsul) waitForChange(params map[string][]string) {
+	index, err := strconv.ParseUint(first(params["index"]), 10, 64)
+	if err != nil || index == 0 {
+		return
+	}
+	wait, err := time.ParseDuration(first(params["wait"]))
+	if err != nil || wait <= 0 || wait > maxBlockingWait {
+		wait = maxBlockingWait
+	}
+	timeout := time.After(wait)
+	for {
+		c.mutex.Lock()
+		current, changed := c.index, c.changed
+		c.mutex.Unlock()
+		if current > index {
+			return
+		}
+		select {
+		case <-changed:
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func (c *FakeConsul) put(w http.ResponseWriter, key string, value []byte, params map[string][]string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	existing, found := c.pairs[key]
+	if casParam, isCAS := params["cas"]; isCAS {
+		cas, err := strconv.ParseUint(first(casParam), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cas index", http.StatusBadRequest)
+			return
+		}
+		// cas=0 only creates the key, otherwise the key must not have changed
+		if (cas == 0 && found) || (cas != 0 && (!found || existing.ModifyIndex != cas)) {
+			json.NewEncoder(w).Encode(false)
+			return
+		}
+	}
+
+	flags, _ := strconv.ParseUint(first(params["flags"]), 10, 64)
+	index := c.nextIndex()
+	pair := &api.KVPair{
+		Key:         key,
+		Value:       value,
+		Flags:       flags,
+		CreateIndex: index,
+		ModifyIndex: index,
+	}
+	if found {
+		pair.CreateIndex = existing.CreateIndex
+	}
+	c.pairs[key] = pair
+	json.NewEncoder(w).Encode(true)
+}
+
+func (c *FakeConsul) delete(w http.ResponseWriter, key string, params map[string][]string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, recurse := params["recurse"]; recurse {
+		for k := range c.pairs {
+			if strings.HasPrefix(k, key) {
+				delete(c.pairs, k)
+			}
+		}
+		c.nextIndex()
+		json.NewEncoder(w).Encode(true)
+		return
+	}
+
+	existing, found := c.pairs[key]
+	if casParam, isCAS := params["cas"]; isCAS {
+		cas, err := strconv.ParseUint(first(casParam), 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid cas index", http.StatusBadRequest)
+			return
+		}
+		if !found || existing.ModifyIndex != cas {
+			json.NewEncoder(w).Encode(false)
+			return
+		}
+	}
+	delete(c.pairs, key)
+	c.nextIndex()
+	json.NewEncoder(w).Encode(true)
+}
+
+// The operations of a transaction, as sent by the Consul client.
+type txnOps []struct {
+	KV *api.KVTxnOp
+}
+
+/*
+Applies all the operations of the transaction, or none of them when one is not
+supported or when the key of a "cas" operation was modified.
+*/
+func (c *FakeConsul) txn(w http.ResponseWriter, r *http.Request) {
+	ops := txnOps{}
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	txnErrors := api.TxnErrors{}
+	for i, op := range ops {
+		if op.KV == nil {
+			txnErrors = append(txnErrors, &api.TxnError{OpIndex: i, What: "Only KV operations are supported"})
+			continue
+		}
+		switch op.KV.Verb {
+		case api.KVSet, api.KVDelete, api.KVDeleteTree:
+		case api.KVCAS:
+			// An index of 0 only creates the key, otherwise the key must not have changed
+			existing, found := c.pairs[strings.TrimLeft(op.KV.Key, "/")]
+			if (op.KV.Index == 0 && found) || (op.KV.Index != 0 && (!found || existing.ModifyIndex != op.KV.Index)) {
+				txnErrors = append(txnErrors, &api.TxnError{OpIndex: i, What: "Failed to set key \"" + op.KV.Key + "\", index is stale"})
+			}
+		default:
+			txnErrors = append(txnErrors, &api.TxnError{OpIndex: i, What: "Unsupported verb \"" + string(op.KV.Verb) + "\""})
+		}
+	}
+	if len(txnErrors) > 0 {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(api.TxnResponse{Errors: txnErrors})
+		return
+	}
+
+	index := c.nextIndex()
+	results := api.TxnResults{}
+	for _, op := range ops {
+		key := strings.TrimLeft(op.KV.Key, "/")
+		switch op.KV.Verb {
+		case api.KVSet, api.KVCAS:
+			pair := &api.KVPair{Key: key, Value: op.KV.Value, Flags: op.KV.Flags, CreateIndex: index, ModifyIndex: index}
+			if existing, found := c.pairs[key]; found {
+				pair.CreateIndex = existing.CreateIndex
+			}
+			c.pairs[key] = pair
+			results = append(results, &api.TxnResult{KV: &api.KVPair{Key: key, CreateIndex: pair.CreateIndex, ModifyIndex: index}})
+		case api.KVDelete:
+			delete(c.pairs, key)
+		case api.KVDeleteTree:
+			for k := range c.pairs {
+				if strings.HasPrefix(k, key) {
+					delete(c.pairs, k)
+				}
+			}
+		}
+	}
+	json.NewEncoder(w).Encode(api.TxnResponse{Results: results})
+}
+
+// Increments the index after a write and wakes up the blocking queries.
+// The mutex must be held.
+func (c *FakeConsul) nextIndex() uint64 {
+	c.index++
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return c.index
+}
+
+// Returns the keys of the pairs, truncated after the separator following
+// the prefix.
+func keys(pairs api.KVPairs, prefix, separator string) []string {
+	keys := []string{}
+	for _, pair := range pairs {
+		key := pair.Key
+		if separator != "" {
+			if i := strings.Index(key[len(prefix):], separator); i >= 0 {
+				key = key[:len(prefix)+i+len(separator)]
+			}
+		}
+		if len(keys) == 0 || keys[len(keys)-1] != key {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+type byKey api.KVPairs
+
+func (p byKey) Len() int           { return len(p) }
+func (p byKey) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byKey) Less(i, j int) bool { return p[i].Key < p[j].Key }
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+func newFakeConsulKV(t *testing.T) (*FakeConsul, *api.KV) {
+	fake := NewFakeConsul()
+	client, err := fake.Info().NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, client.KV()
+}
+
+func TestFakeConsul_PutGetDelete(t *testing.T) {
+	fake, kv := newFakeConsulKV(t)
+	defer fake.Close()
+
+	if _, err := kv.Put(&api.KVPair{Key: "agilestack/menu/plugin", Value: []byte("v1")}, nil); err != nil {
+		t.Fatal(err)
+	}
+	pair, _, err := kv.Get("/agilestack/menu/plugin", nil)
+	if err != nil || pair == nil || string(pair.Value) != "v1" {
+		t.Fatalf("Unexpected pair: %v, %v", pair, err)
+	}
+	createIndex := pair.CreateIndex
+
+	kv.Put(&api.KVPair{Key: "agilestack/menu/plugin", Value: []byte("v2")}, nil)
+	pair, _, err = kv.Get("agilestack/menu/plugin", nil)
+	if err != nil || string(pair.Value) != "v2" {
+		t.Fatalf("Unexpected pair: %v, %v", pair, err)
+	}
+	if pair.CreateIndex != createIndex || pair.ModifyIndex <= createIndex {
+		t.Errorf("Unexpected indexes: create=%d, modify=%d", pair.CreateIndex, pair.ModifyIndex)
+	}
+
+	if _, err := kv.Delete("agilestack/menu/plugin", nil); err != nil {
+		t.Fatal(err)
+	}
+	pair, _, err = kv.Get("agilestack/menu/plugin", nil)
+	if err != nil || pair != nil {
+		t.Errorf("Expected no pair, got %v, %v", pair, err)
+	}
+}
+
+func TestFakeConsul_ListAndDeleteTree(t *testing.T) {
+	fake, kv := newFakeConsulKV(t)
+	defer fake.Close()
+
+	for _, key := range []string{"agilestack/menu/b", "agilestack/menu/a", "agilestack/registration/a"} {
+		kv.Put(&api.KVPair{Key: key, Value: []byte(key)}, nil)
+	}
+
+	pairs, _, err := kv.List("agilestack/menu/", nil)
+	if err != nil || len(pairs) != 2 || pairs[0].Key != "agilestack/menu/a" || pairs[1].Key != "agilestack/menu/b" {
+		t.Fatalf("Unexpected pairs: %v, %v", pairs, err)
+	}
+	keys, _, err := kv.Keys("agilestack/", "/", nil)
+	if err != nil || len(keys) != 2 || keys[0] != "agilestack/menu/" || keys[1] != "agilestack/registration/" {
+		t.Fatalf("Unexpected keys: %v, %v", keys, err)
+	}
+
+	if _, err := kv.DeleteTree("agilestack/menu/", nil); err != nil {
+		t.Fatal(err)
+	}
+	pairs, _, err = kv.List("agilestack/", nil)
+	if err != nil || len(pairs) != 1 || pairs[0].Key != "agilestack/registration/a" {
+		t.Errorf("Unexpected pairs: %v, %v", pairs, err)
+	}
+}
+
+func TestFakeConsul_CAS(t *testing.T) {
+	fake, kv := newFakeConsulKV(t)
+	defer fake.Close()
+
+	// ModifyIndex 0: creation only
+	stored, _, err := kv.CAS(&api.KVPair{Key: "key", Value: []byte("v1")}, nil)
+	if err != nil || !stored {
+		t.Fatalf("Expected the key to be created: %v, %v", stored, err)
+	}
+	stored, _, err = kv.CAS(&api.KVPair{Key: "key", Value: []byte("v2")}, nil)
+	if err != nil || stored {
+		t.Fatalf("Expected the existing key not to be overwritten: %v, %v", stored, err)
+	}
+
+	pair, _, _ := kv.Get("key", nil)
+	stale := *pair
+	pair.Value = []byte("v3")
+	stored, _, err = kv.CAS(pair, nil)
+	if err != nil || !stored {
+		t.Fatalf("Expected the key to be updated: %v, %v", stored, err)
+	}
+	stale.Value = []byte("v4")
+	stored, _, err = kv.CAS(&stale, nil)
+	if err != nil || stored {
+		t.Fatalf("Expected a stale update to be rejected: %v, %v", stored, err)
+	}
+
+	deleted, _, err := kv.DeleteCAS(&stale, nil)
+	if err != nil || deleted {
+		t.Fatalf("Expected a stale deletion to be rejected: %v, %v", deleted, err)
+	}
+	pair, _, _ = kv.Get("key", nil)
+	if string(pair.Value) != "v3" {
+		t.Errorf("Unexpected value: %s", pair.Value)
+	}
+}
+
+func TestFakeConsul_BlockingQuery(t *testing.T) {
+	fake, kv := newFakeConsulKV(t)
+	defer fake.Close()
+
+	_, meta, err := kv.List("agilestack/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		kv.Put(&api.KVPair{Key: "agilestack/menu/plugin", Value: []byte("v1")}, nil)
+	}()
+
+	pairs, newMeta, err := kv.List("agilestack/", &api.QueryOptions{WaitIndex: meta.LastIndex, WaitTime: 5 * time.Second})
+	if err != nil || len(pairs) != 1 {
+		t.Fatalf("Unexpected pairs: %v, %v", pairs, err)
+	}
+	if newMeta.LastIndex <= meta.LastIndex {
+		t.Errorf("Expected the index to increase: %d => %d", meta.LastIndex, newMeta.LastIndex)
+	}
+
+	start := time.Now()
+	_, _, err = kv.List("agilestack/", &api.QueryOptions{WaitIndex: newMeta.LastIndex, WaitTime: 100 * time.Millisecond})
+	if err != nil || time.Since(start) < 100*time.Millisecond {
+		t.Errorf("Expected the query to block until its timeout: %v", err)
+	}
+}
+
+func TestFakeConsul_Txn(t *testing.T) {
+	fake, kv := newFakeConsulKV(t)
+	defer fake.Close()
+
+	kv.Put(&api.KVPair{Key: "agilestack/registration/plugin", Value: []byte("v0")}, nil)
+	ok, _, _, err := kv.Txn(api.KVTxnOps{
+		{Verb: api.KVSet, Key: "agilestack/menu/plugin", Value: []byte("v1")},
+		{Verb: api.KVSet, Key: "agilestack/components", Value: []byte("v2")},
+		{Verb: api.KVDelete, Key: "agilestack/registration/plugin"},
+	}, nil)
+	if err != nil || !ok {
+		t.Fatalf("Unexpected transaction result: %v, %v", ok, err)
+	}
+	pairs, _, err := kv.List("agilestack/", nil)
+	if err != nil || len(pairs) != 2 || string(pairs[0].Value) != "v2" || string(pairs[1].Value) != "v1" {
+		t.Fatalf("Unexpected pairs: %v, %v", pairs, err)
+	}
+	if pairs[0].ModifyIndex != pairs[1].ModifyIndex {
+		t.Errorf("Expected a single index, got %d and %d", pairs[0].ModifyIndex, pairs[1].ModifyIndex)
+	}
+
+	// An unsupported operation rolls back the transaction
+	ok, response, _, err := kv.Txn(api.KVTxnOps{
+		{Verb: api.KVSet, Key: "agilestack/menu/other", Value: []byte("v3")},
+		{Verb: api.KVLock, Key: "agilestack/menu/plugin"},
+	}, nil)
+	if err != nil || ok || len(response.Errors) != 1 || response.Errors[0].OpIndex != 1 {
+		t.Fatalf("Unexpected transaction result: %v, %v, %v", ok, response, err)
+	}
+	pair, _, err := kv.Get("agilestack/menu/other", nil)
+	if err != nil || pair != nil {
+		t.Errorf("Expected no pair, got %v, %v", pair, err)
+	}
+}
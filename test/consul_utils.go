@@ -1,9 +1,8 @@
 package test
 
 import (
+	"errors"
 	"log"
-	"sync"
-	"time"
 
 	"github.com/eogile/agilestack-utils/dockerclient"
 	"github.com/fsouza/go-dockerclient"
@@ -11,16 +10,32 @@ import (
 )
 
 const (
-	consulContainerName = "consulTest"
+	consulContainerName = "consul-test"
 	consulImageName     = "gliderlabs/consul-server"
-	consulTestPort      = "8501"
-	consulTestHost      = "127.0.0.1:" + consulTestPort
+	consulPort          = docker.Port("8500/tcp")
 )
 
+// The connection information of a Consul container started in tests.
+type ConsulInfo struct {
+	ContainerName string
+
+	// The address of the Consul HTTP API from the host ("127.0.0.1:<port>").
+	Address string
+}
+
+/*
+Returns a Consul client configured to access the Consul container.
+*/
+func (info *ConsulInfo) NewClient() (*api.Client, error) {
+	config := api.DefaultConfig()
+	config.Address = info.Address
+	return api.NewClient(config)
+}
+
 /*
 Starts a consul container for tests purposes.
 */
-func StartConsulContainer(dockerClient *dockerclient.DockerClient, network string) error {
+func (f *Fixture) StartConsul() (*ConsulInfo, error) {
 	/*
 	 * Container configuration
 	 */
@@ -29,116 +44,86 @@ func StartConsulContainer(dockerClient *dockerclient.DockerClient, network strin
 		Tty:   true,
 	}
 	containerConfig.Cmd = append(containerConfig.Cmd, "-bootstrap")
-	//containerConfig.Cmd = append(containerConfig.Cmd, "-ui")
-	containerConfig.ExposedPorts = map[docker.Port]struct{}{
-		"8500/tcp": {},
-	}
-
-	portBindings := map[docker.Port][]docker.PortBinding{
-		"8500/tcp": []docker.PortBinding{
-			{
-				HostIP:   "0.0.0.0",
-				HostPort: consulTestPort,
-			},
-		},
-	}
 
 	/*
 	 * Host configuration
 	 */
 	hostConfig := docker.HostConfig{
 		PublishAllPorts: true,
-		NetworkMode:     network,
-		PortBindings:    portBindings,
 	}
 
 	containerOptions := docker.CreateContainerOptions{
-		Name:       consulContainerName,
+		Name:       f.containerName(consulContainerName),
 		Config:     &containerConfig,
 		HostConfig: &hostConfig,
 	}
 
-	err := dockerClient.LaunchContainer(containerOptions)
+	hostPorts, err := f.launch(containerOptions, consulPort)
 	if err != nil {
-		log.Fatalln("Cannot launch consul : ", err)
+		return nil, err
+	}
+	info := &ConsulInfo{
+		ContainerName: containerOptions.Name,
+		Address:       localhost + ":" + hostPorts[consulPort],
 	}
 
-	consulTestClient, err := NewConsulClient()
-	//test if consul callable
+	// Consul is ready once its HTTP API is up and a leader is elected
+	err = WaitFor(f.dockerClient, info.ContainerName,
+		ForHTTP("http://"+info.Address+"/v1/status/leader"),
+		ForLog("New leader elected", 1),
+	)
 	if err != nil {
-		log.Fatalln("Unable to setup Consul client, ", err)
+		return nil, err
 	}
-	//wait for condition consul is available
-	var mutex sync.Mutex
-	conditionIsAvailable := sync.NewCond(&mutex)
-	go func() {
-		log.Println("In go routine")
-		for i := 0; i < 20; i++ {
-			log.Println("In go routine loop : ", i)
-			health := consulTestClient.Health()
-			healthCheck, _, err := health.State(api.HealthPassing, nil)
-			if err != nil {
-				log.Println("Got error when checking consul health", err)
-				//mutex.Lock()
-				//conditionIsAvailable.Signal()
-				//mutex.Unlock()
-				//t.Fatal("cannot check health", err)
-			}
-			for _, h := range healthCheck {
-				status := h.Status
-				log.Println("got consul health status : ", status)
-				if status == api.HealthPassing {
-					mutex.Lock()
-					conditionIsAvailable.Signal()
-					mutex.Unlock()
-				}
-			}
-			time.Sleep(time.Second)
-		}
-		mutex.Lock()
-		conditionIsAvailable.Signal()
-		mutex.Unlock()
-		log.Fatalln("Health never came good", err)
-
-	}()
-
-	mutex.Lock()
-	conditionIsAvailable.Wait()
-	mutex.Unlock()
-
-	return nil
+	return info, nil
 }
 
+// The Consul container started by StartConsulContainer.
+var legacyConsul *ConsulInfo
+
 /*
-Stops and removes the Consul container used in tests.
+Starts a consul container for tests purposes, NewConsulClient then returning a
+client of this container.
+
+Deprecated: use Fixture.StartConsul, which returns the address of the container.
 */
-func RemoveConsulContainer(dockerClient *dockerclient.DockerClient) {
-	isRunning, err := dockerClient.IsContainerRunning(consulContainerName)
+func StartConsulContainer(dockerClient *dockerclient.DockerClient, network string) error {
+	info, err := legacyFixture(consulContainerName, dockerClient, network).StartConsul()
 	if err != nil {
-		log.Fatalln("in tearDownConsulContainer, unable to do IsContainerRunning ", err)
+		return err
 	}
-	container := dockerClient.GetContainerByName(consulContainerName)
-	if container != nil {
-		if isRunning {
-			err := dockerClient.StopContainer(container.ID, 0)
-			if err != nil {
-				log.Println("Strange behaviour, says that cannot stop consul, but has been killed : ", err)
-			}
-		}
-		isRunning, err = dockerClient.IsContainerRunning(consulContainerName)
-		err = dockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: consulContainerName, RemoveVolumes: false, Force: false})
-		if err != nil {
-			log.Fatalln("Cannot remove consul : ", err)
-		}
+	legacyMutex.Lock()
+	legacyConsul = info
+	legacyMutex.Unlock()
+	return nil
+}
 
+/*
+Stops and removes the Consul container started by StartConsulContainer.
+
+Deprecated: use Fixture.Cleanup.
+*/
+func RemoveConsulContainer(dockerClient *dockerclient.DockerClient) {
+	legacyMutex.Lock()
+	legacyConsul = nil
+	legacyMutex.Unlock()
+	if err := removeLegacyFixture(consulContainerName); err != nil {
+		log.Fatalln("Cannot remove consul : ", err)
 	}
 }
 
 /*
-Returns a Consul client configured to access the Consul container deployed in tests.
+Returns a Consul client configured to access the Consul container started by
+StartConsulContainer.
+
+Deprecated: use ConsulInfo.NewClient.
 */
 func NewConsulClient() (*api.Client, error) {
-	config := api.DefaultConfig()
-	config.Address = consulTestHost
-	return api.NewClient(config)
+	legacyMutex.Lock()
+	info := legacyConsul
+	legacyMutex.Unlock()
+	if info == nil {
+		return nil, errors.New("The Consul container is not started")
+	}
+	return info.NewClient()
 }
@@ -0,0 +1,308 @@
+package test
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eogile/agilestack-utils/dockerclient"
+	"github.com/eogile/agilestack-utils/slices"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// The label identifying the containers started by the test fixtures.
+	Label = "io.agilestack.test"
+
+	// The labels identifying the test run a container belongs to.
+	runLabel      = Label + ".run"
+	hostnameLabel = Label + ".hostname"
+	pidLabel      = Label + ".pid"
+
+	// The containers of other runs older than this age are considered stale,
+	// even if the process which started them is still alive.
+	StaleContainerAge = time.Hour
+
+	localhost = "127.0.0.1"
+)
+
+/*
+A Fixture starts the containers required by tests and removes them once the
+tests are over.
+
+The containers of a fixture have unique names and labels, and their ports are
+bound to free host ports, so several test packages can run in parallel.
+*/
+type Fixture struct {
+	dockerClient *dockerclient.DockerClient
+	network      string
+	runId        string
+
+	// The host ports of the container ports published on fixed ports rather
+	// than on free ones, for the deprecated functions.
+	fixedPorts map[docker.Port]string
+
+	mutex      sync.Mutex
+	containers []string
+}
+
+/*
+Creates a fixture starting its containers in the given Docker network.
+
+The stale containers left by earlier runs (see SweepStaleContainers) are
+removed first.
+*/
+func NewFixture(dockerClient *dockerclient.DockerClient, network string) *Fixture {
+	if err := SweepStaleContainers(dockerClient, StaleContainerAge); err != nil {
+		log.Println("Unable to remove the stale test containers:", err)
+	}
+	return &Fixture{
+		dockerClient: dockerClient,
+		network:      network,
+		runId:        strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		containers:   []string{},
+	}
+}
+
+// Returns the identifier of the test run, shared by all the containers of
+// the fixture.
+func (f *Fixture) RunId() string {
+	return f.runId
+}
+
+// Returns the network the containers are started in.
+func (f *Fixture) Network() string {
+	return f.network
+}
+
+// Returns the unique name of a container of the fixture.
+func (f *Fixture) containerName(name string) string {
+	return name + "-" + f.runId
+}
+
+/*
+Launches a container of the fixture and registers it for cleanup.
+
+The given container ports are published on free host ports, unless they have
+fixed host ports. The returned map
+gives the host port of each container port.
+*/
+func (f *Fixture) launch(options docker.CreateContainerOptions, ports ...docker.Port) (map[docker.Port]string, error) {
+	if options.Config.Labels == nil {
+		options.Config.Labels = map[string]string{}
+	}
+	hostname, _ := os.Hostname()
+	options.Config.Labels[Label] = "true"
+	options.Config.Labels[runLabel] = f.runId
+	options.Config.Labels[hostnameLabel] = hostname
+	options.Config.Labels[pidLabel] = strconv.Itoa(os.Getpid())
+
+	hostPorts := make(map[docker.Port]string, len(ports))
+	options.Config.ExposedPorts = map[docker.Port]struct{}{}
+	options.HostConfig.PortBindings = map[docker.Port][]docker.PortBinding{}
+	for _, port := range ports {
+		hostPort, err := f.hostPort(port)
+		if err != nil {
+			return nil, err
+		}
+		hostPorts[port] = hostPort
+		options.Config.ExposedPorts[port] = struct{}{}
+		options.HostConfig.PortBindings[port] = []docker.PortBinding{
+			{
+				HostIP:   "0.0.0.0",
+				HostPort: hostPort,
+			},
+		}
+	}
+	options.HostConfig.NetworkMode = f.network
+
+	f.mutex.Lock()
+	if !slices.StringInSlice(options.Name, f.containers) {
+		f.containers = append(f.containers, options.Name)
+	}
+	f.mutex.Unlock()
+
+	if err := f.dockerClient.LaunchContainer(options); err != nil {
+		return nil, err
+	}
+	return hostPorts, nil
+}
+
+// Returns the host port publishing the container port: its fixed port, if
+// any, or a free port.
+func (f *Fixture) hostPort(port docker.Port) (string, error) {
+	if hostPort, found := f.fixedPorts[port]; found {
+		return hostPort, nil
+	}
+	return FreePort()
+}
+
+// Returns a free TCP port of the host.
+func FreePort() (string, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return "", err
+	}
+	defer listener.Close()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	return port, err
+}
+
+// Stops and removes a container of the fixture, if it exists.
+func (f *Fixture) remove(containerName string) error {
+	if f.dockerClient.GetContainerByName(containerName) == nil {
+		return nil
+	}
+	return f.dockerClient.RemoveContainer(docker.RemoveContainerOptions{
+		ID:            containerName,
+		RemoveVolumes: true,
+		Force:         true,
+	})
+}
+
+/*
+Stops and removes all the containers of the fixture, in the reverse order of
+their launch. All the containers are processed even if some of them cannot be
+removed.
+*/
+func (f *Fixture) Cleanup() error {
+	f.mutex.Lock()
+	containers := f.containers
+	f.containers = []string{}
+	f.mutex.Unlock()
+
+	failures := []string{}
+	for i := len(containers) - 1; i >= 0; i-- {
+		if err := f.remove(containers[i]); err != nil {
+			failures = append(failures, containers[i]+": "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New("Unable to remove the test containers: " + strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+/*
+Removes the containers of the fixture when the process is interrupted
+(SIGINT or SIGTERM), then exits.
+*/
+func (f *Fixture) CleanupOnInterrupt() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Println("Got signal", sig, "removing the test containers")
+		if err := f.Cleanup(); err != nil {
+			log.Println(err)
+		}
+		os.Exit(1)
+	}()
+}
+
+/*
+Removes the test containers left by earlier runs, for instance when a test
+process crashed before removing them.
+
+A labelled container is stale when it is older than maxAge, or when it was
+started from this host by a process which no longer exists.
+*/
+func SweepStaleContainers(dockerClient *dockerclient.DockerClient, maxAge time.Duration) error {
+	containers, err := dockerClient.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {Label}},
+	})
+	if err != nil {
+		return err
+	}
+
+	hostname, _ := os.Hostname()
+	failures := []string{}
+	for _, container := range containers {
+		if !isStale(container, hostname, maxAge, time.Now()) {
+			continue
+		}
+		log.Println("Removing the stale test container", container.Names)
+		err := dockerClient.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            container.ID,
+			RemoveVolumes: true,
+			Force:         true,
+		})
+		if err != nil {
+			failures = append(failures, container.ID+": "+err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New("Unable to remove the stale test containers: " + strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+func isStale(container docker.APIContainers, hostname string, maxAge time.Duration, now time.Time) bool {
+	if time.Unix(container.Created, 0).Add(maxAge).Before(now) {
+		return true
+	}
+	if container.Labels[hostnameLabel] != hostname {
+		// The process may run on another host
+		return false
+	}
+	pid, err := strconv.Atoi(container.Labels[pidLabel])
+	if err != nil || pid <= 0 {
+		return false
+	}
+	return !isProcessAlive(pid)
+}
+
+func isProcessAlive(pid int) bool {
+	if pid == os.Getpid() {
+		return true
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// The fixtures of the deprecated functions, by kind of container.
+var (
+	legacyMutex    sync.Mutex
+	legacyFixtures = map[string]*Fixture{}
+
+	// The fixed host ports of the deprecated functions, by kind of container.
+	legacyPorts = map[string]map[docker.Port]string{
+		hydraContainerName: {hydraPort: "9090", postgreSQLPort: "54321"},
+	}
+)
+
+// Returns the fixture of the deprecated functions for the kind of container,
+// creating it if needed.
+func legacyFixture(kind string, dockerClient *dockerclient.DockerClient, network string) *Fixture {
+	legacyMutex.Lock()
+	defer legacyMutex.Unlock()
+	if legacyFixtures[kind] == nil {
+		legacyFixtures[kind] = NewFixture(dockerClient, network)
+		legacyFixtures[kind].fixedPorts = legacyPorts[kind]
+	}
+	return legacyFixtures[kind]
+}
+
+// Removes the containers of the fixture of the deprecated functions for the
+// kind of container, if any.
+func removeLegacyFixture(kind string) error {
+	legacyMutex.Lock()
+	f := legacyFixtures[kind]
+	delete(legacyFixtures, kind)
+	legacyMutex.Unlock()
+	if f == nil {
+		return nil
+	}
+	return f.Cleanup()
+}
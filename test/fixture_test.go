@@ -0,0 +1,68 @@
+package test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fsouza/go-dockerclient"
+)
+
+func TestIsStale(t *testing.T) {
+	now := time.Now()
+	container := func(age time.Duration, hostname string, pid int) docker.APIContainers {
+		return docker.APIContainers{
+			Created: now.Add(-age).Unix(),
+			Labels: map[string]string{
+				Label:         "true",
+				hostnameLabel: hostname,
+				pidLabel:      strconv.Itoa(pid),
+			},
+		}
+	}
+
+	cases := []struct {
+		description string
+		container   docker.APIContainers
+		stale       bool
+	}{
+		{"running process", container(time.Minute, "host", os.Getpid()), false},
+		{"old container", container(2*time.Hour, "host", os.Getpid()), true},
+		{"process of another host", container(time.Minute, "other-host", -1), false},
+		{"invalid pid", container(time.Minute, "host", 0), false},
+	}
+	for _, c := range cases {
+		if stale := isStale(c.container, "host", time.Hour, now); stale != c.stale {
+			t.Errorf("%s: expected stale=%v, got %v", c.description, c.stale, stale)
+		}
+	}
+}
+
+func TestFreePort(t *testing.T) {
+	port, err := FreePort()
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 {
+		t.Errorf("Invalid port: %s", port)
+	}
+}
+
+func TestLegacyFixture(t *testing.T) {
+	if _, err := NewConsulClient(); err == nil {
+		t.Error("Expected an error when the Consul container is not started")
+	}
+	if err := StopHydraContainer(nil); err != nil {
+		t.Error("Unexpected error when no Hydra container is started:", err)
+	}
+
+	// The deprecated Hydra functions keep the fixed ports
+	f := &Fixture{fixedPorts: legacyPorts[hydraContainerName]}
+	if port, err := f.hostPort(hydraPort); err != nil || port != "9090" {
+		t.Errorf("Unexpected host port of Hydra: %s, %v", port, err)
+	}
+	if port, err := f.hostPort(consulPort); err != nil || port == "" || port == "9090" {
+		t.Errorf("Unexpected host port of Consul: %s, %v", port, err)
+	}
+}
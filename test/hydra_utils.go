@@ -2,55 +2,143 @@ package test
 
 import (
 	"errors"
+	"fmt"
 	"log"
-	"time"
+	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/eogile/agilestack-utils/dockerclient"
 	"github.com/fsouza/go-dockerclient"
 )
 
 const (
-	postgreSQLPort          = "54321"
 	postgreSQLContainerName = "postgresql-test"
-	hydraPort               = "9090"
+	postgreSQLPort          = docker.Port("5432/tcp")
 	hydraContainerName      = "hydra-test"
+	hydraPort               = docker.Port("9090/tcp")
 )
 
-func StartHydraContainer(dockerClient *dockerclient.DockerClient, network string) error {
-	if err := startPostgreSQLContainer(dockerClient, network); err != nil {
+// The connection information of a PostgreSQL container started in tests.
+type PostgreSQLInfo struct {
+	ContainerName string
+
+	// The address of the database from the host ("127.0.0.1:<port>").
+	Address string
+
+	// The address of the database from the containers of the fixture network.
+	NetworkAddress string
+
+	User     string
+	Password string
+	Database string
+}
+
+// Returns the URL of the database from the containers of the fixture network.
+func (info *PostgreSQLInfo) NetworkURL() string {
+	return "postgres://" + info.User + ":" + info.Password + "@" + info.NetworkAddress + "/" + info.Database + "?sslmode=disable"
+}
+
+// The connection information of a Hydra container started in tests.
+type HydraInfo struct {
+	ContainerName string
+
+	// The URL of Hydra from the host ("http://127.0.0.1:<port>").
+	URL string
+
+	// The credentials of the client application.
+	ClientID     string
+	ClientSecret string
+
+	// The credentials of the super account.
+	Username string
+	Password string
+
+	// The database of Hydra.
+	PostgreSQL *PostgreSQLInfo
+}
+
+// Requests a token for the client application.
+func (info *HydraInfo) checkToken() error {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequest("POST", info.URL+"/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
 		return err
 	}
-	return startHydra(dockerClient, network, 0)
-}
+	req.SetBasicAuth(info.ClientID, info.ClientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
-func StopHydraContainer(dockerClient *dockerclient.DockerClient) error {
-	if err := stopContainer(dockerClient, postgreSQLContainerName); err != nil {
+	client := http.Client{Timeout: checkTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
 		return err
 	}
-	return stopContainer(dockerClient, hydraContainerName)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Got status '%s' when requesting a token", resp.Status)
+	}
+	return nil
+}
+
+// Starts a PostgreSQL container, then a Hydra container using it.
+func (f *Fixture) StartHydra() (*HydraInfo, error) {
+	postgreSQL, err := f.StartPostgreSQL()
+	if err != nil {
+		return nil, err
+	}
+	info := &HydraInfo{
+		ContainerName: f.containerName(hydraContainerName),
+		ClientID:      "superapp2",
+		ClientSecret:  "supersecret2",
+		Username:      "superadmin@eogile.com",
+		Password:      "supersecret",
+		PostgreSQL:    postgreSQL,
+	}
+	if err := f.startHydra(info, 0); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+/*
+Starts a PostgreSQL container, then a Hydra container using it. Like before
+the fixtures, Hydra is published on the port 9090 of the host, and PostgreSQL
+on the port 54321.
+
+Deprecated: use Fixture.StartHydra, which publishes the containers on free
+ports and returns the URL of Hydra.
+*/
+func StartHydraContainer(dockerClient *dockerclient.DockerClient, network string) error {
+	_, err := legacyFixture(hydraContainerName, dockerClient, network).StartHydra()
+	return err
+}
+
+/*
+Stops and removes the containers started by StartHydraContainer.
+
+Deprecated: use Fixture.Cleanup.
+*/
+func StopHydraContainer(dockerClient *dockerclient.DockerClient) error {
+	return removeLegacyFixture(hydraContainerName)
 }
 
 // Starts a PostgreSQL container.
-func startPostgreSQLContainer(dockerClient *dockerclient.DockerClient, network string) error {
+func (f *Fixture) StartPostgreSQL() (*PostgreSQLInfo, error) {
+	info := &PostgreSQLInfo{
+		ContainerName: f.containerName(postgreSQLContainerName),
+		User:          "hydra",
+		Password:      "hydra_agilestack_test",
+		Database:      "hydra",
+	}
+	info.NetworkAddress = info.ContainerName + "." + f.network + ":5432"
+
 	containerConfig := docker.Config{
 		Image: "postgres:9.5",
 		Tty:   true,
 		Env: []string{
-			"POSTGRES_USER=hydra",
-			"POSTGRES_PASSWORD=hydra_agilestack_test",
-			"POSTGRES_DB=hydra",
-		},
-		ExposedPorts: map[docker.Port]struct{}{
-			"5432/tcp": {},
-		},
-	}
-
-	portBindings := map[docker.Port][]docker.PortBinding{
-		"5432/tcp": []docker.PortBinding{
-			{
-				HostIP:   "0.0.0.0",
-				HostPort: postgreSQLPort,
-			},
+			"POSTGRES_USER=" + info.User,
+			"POSTGRES_PASSWORD=" + info.Password,
+			"POSTGRES_DB=" + info.Database,
 		},
 	}
 
@@ -59,24 +147,36 @@ func startPostgreSQLContainer(dockerClient *dockerclient.DockerClient, network s
 	 */
 	hostConfig := docker.HostConfig{
 		PublishAllPorts: true,
-		NetworkMode:     network,
-		PortBindings:    portBindings,
 	}
 
 	containerOptions := docker.CreateContainerOptions{
-		Name:       postgreSQLContainerName,
+		Name:       info.ContainerName,
 		Config:     &containerConfig,
 		HostConfig: &hostConfig,
 	}
 
-	return dockerClient.LaunchContainer(containerOptions)
+	hostPorts, err := f.launch(containerOptions, postgreSQLPort)
+	if err != nil {
+		return nil, err
+	}
+	info.Address = localhost + ":" + hostPorts[postgreSQLPort]
+
+	// The database is started twice: once to be initialized, then for good.
+	err = WaitFor(f.dockerClient, info.ContainerName,
+		ForLog("database system is ready to accept connections", 2),
+		ForTCP(info.Address),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
 }
 
 // Starts an "eogile/agilestack-hydra-host" container.
-// As the PostgreSQL container may take some time to initialize itself, several
-// attempts to start the Hydra container may be required (the container fails to
-// start if it cannot connect the PostgreSQL database).
-func startHydra(dockerClient *dockerclient.DockerClient, network string, counter int) error {
+// As the PostgreSQL container may take some time to accept the connections of
+// the other containers, several attempts to start the Hydra container may be
+// required (the container stops if it cannot connect the PostgreSQL database).
+func (f *Fixture) startHydra(info *HydraInfo, counter int) error {
 	log.Println("Hydra container launch attempt:", counter)
 	if counter >= 15 {
 		return errors.New("Max attempts threshold achieved")
@@ -86,27 +186,11 @@ func startHydra(dockerClient *dockerclient.DockerClient, network string, counter
 		Image: "eogile/agilestack-hydra-host",
 		Tty:   true,
 		Env: []string{
-			"CLIENT_ID=superapp2",
-			"CLIENT_SECRET=supersecret2",
-			"SUPERACCOUNT_USERNAME=superadmin@eogile.com",
-			"SUPERACCOUNT_SECRET=supersecret",
-			"DATABASE_URL=postgres://hydra:hydra_agilestack_test@" +
-				postgreSQLContainerName +
-				"." +
-				network +
-				":5432/hydra?sslmode=disable",
-		},
-		ExposedPorts: map[docker.Port]struct{}{
-			"9090/tcp": {},
-		},
-	}
-
-	portBindings := map[docker.Port][]docker.PortBinding{
-		"9090/tcp": []docker.PortBinding{
-			{
-				HostIP:   "0.0.0.0",
-				HostPort: hydraPort,
-			},
+			"CLIENT_ID=" + info.ClientID,
+			"CLIENT_SECRET=" + info.ClientSecret,
+			"SUPERACCOUNT_USERNAME=" + info.Username,
+			"SUPERACCOUNT_SECRET=" + info.Password,
+			"DATABASE_URL=" + info.PostgreSQL.NetworkURL(),
 		},
 	}
 
@@ -115,56 +199,29 @@ func startHydra(dockerClient *dockerclient.DockerClient, network string, counter
 	 */
 	hostConfig := docker.HostConfig{
 		PublishAllPorts: true,
-		NetworkMode:     network,
-		PortBindings:    portBindings,
 	}
 
 	containerOptions := docker.CreateContainerOptions{
-		Name:       hydraContainerName,
+		Name:       info.ContainerName,
 		Config:     &containerConfig,
 		HostConfig: &hostConfig,
 	}
 
-	time.Sleep(500000000)
-	if err := dockerClient.LaunchContainer(containerOptions); err != nil {
-		return err
-	}
-	time.Sleep(500000000)
-	if isRunning, err := dockerClient.IsContainerRunning(hydraContainerName); err != nil || isRunning {
+	hostPorts, err := f.launch(containerOptions, hydraPort)
+	if err != nil {
 		return err
 	}
+	info.URL = "http://" + localhost + ":" + hostPorts[hydraPort]
 
-	if err := stopContainer(dockerClient, hydraContainerName); err != nil {
+	// Hydra is ready once it issues tokens to the client application
+	err = WaitFor(f.dockerClient, info.ContainerName, ForFunc(info.checkToken))
+	if _, stopped := err.(*ContainerStoppedError); !stopped {
 		return err
 	}
 
-	return startHydra(dockerClient, network, counter+1)
-}
-
-// Stops the container matching the given name.
-// If there is no such container, nothing is done.
-func stopContainer(dockerClient *dockerclient.DockerClient, containerName string) error {
-
-	isRunning, err := dockerClient.IsContainerRunning(containerName)
-	if err != nil {
+	if err := f.remove(info.ContainerName); err != nil {
 		return err
 	}
 
-	container := dockerClient.GetContainerByName(containerName)
-	if container != nil {
-		if isRunning {
-			err := dockerClient.StopContainer(container.ID, 0)
-			if err != nil {
-				log.Println("Strange behaviour, says that cannot stop container, but has been killed : ", err)
-				return err
-			}
-		}
-
-		return dockerClient.RemoveContainer(docker.RemoveContainerOptions{
-			ID:            containerName,
-			RemoveVolumes: true,
-			Force:         false,
-		})
-	}
-	return nil
+	return f.startHydra(info, counter+1)
 }
@@ -0,0 +1,239 @@
+package test
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/eogile/agilestack-utils/dockerclient"
+	"github.com/fsouza/go-dockerclient"
+)
+
+const (
+	// The default time given to a strategy to succeed.
+	DefaultWaitTimeout = time.Minute
+
+	// The delay between two checks of a strategy.
+	waitInterval = 250 * time.Millisecond
+
+	// The timeout of a single network check.
+	checkTimeout = time.Second
+)
+
+// The container a strategy checks the readiness of.
+type WaitTarget struct {
+	DockerClient  *dockerclient.DockerClient
+	ContainerName string
+}
+
+/*
+A WaitStrategy checks a readiness condition of a container.
+
+Check is called repeatedly until it returns nil or the timeout of the strategy
+elapses. The error returned by the last check describes why the container is
+not ready.
+*/
+type WaitStrategy interface {
+	Check(target WaitTarget) error
+	Timeout() time.Duration
+}
+
+// The error returned when a container stops while waiting for it.
+type ContainerStoppedError struct {
+	ContainerName string
+}
+
+func (err *ContainerStoppedError) Error() string {
+	return fmt.Sprintf("The container %s stopped before being ready", err.ContainerName)
+}
+
+/*
+Waits for the given strategies to succeed, one after another.
+
+Returns a *ContainerStoppedError if the container stops meanwhile.
+*/
+func WaitFor(dockerClient *dockerclient.DockerClient, containerName string, strategies ...WaitStrategy) error {
+	target := WaitTarget{DockerClient: dockerClient, ContainerName: containerName}
+	stopped := func() bool {
+		container, err := dockerClient.InspectContainer(containerName)
+		return err == nil && !container.State.Running
+	}
+	for _, strategy := range strategies {
+		if err := waitUntil(target, strategy, stopped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func waitUntil(target WaitTarget, strategy WaitStrategy, stopped func() bool) error {
+	deadline := time.Now().Add(strategy.Timeout())
+	for {
+		err := strategy.Check(target)
+		if err == nil {
+			return nil
+		}
+		if stopped() {
+			return &ContainerStoppedError{ContainerName: target.ContainerName}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("The container %s is not ready after %v: %v", target.ContainerName, strategy.Timeout(), err)
+		}
+		time.Sleep(waitInterval)
+	}
+}
+
+/*
+Returns a copy of the strategy with the given timeout.
+*/
+func WithTimeout(timeout time.Duration, strategy WaitStrategy) WaitStrategy {
+	return timeoutStrategy{WaitStrategy: strategy, timeout: timeout}
+}
+
+type timeoutStrategy struct {
+	WaitStrategy
+	timeout time.Duration
+}
+
+func (s timeoutStrategy) Timeout() time.Duration {
+	return s.timeout
+}
+
+/*
+Waits for a TCP port to accept connections.
+
+Please notice that the ports published by Docker may accept connections
+before the container listens to them.
+*/
+func ForTCP(address string) WaitStrategy {
+	return tcpStrategy{address: address}
+}
+
+type tcpStrategy struct {
+	address string
+}
+
+func (s tcpStrategy) Check(target WaitTarget) error {
+	conn, err := net.DialTimeout("tcp", s.address, checkTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func (s tcpStrategy) Timeout() time.Duration {
+	return DefaultWaitTimeout
+}
+
+/*
+Waits for a GET request on the URL to return a 200 status.
+*/
+func ForHTTP(url string) WaitStrategy {
+	return httpStrategy{url: url}
+}
+
+type httpStrategy struct {
+	url string
+}
+
+func (s httpStrategy) Check(target WaitTarget) error {
+	client := http.Client{Timeout: checkTimeout}
+	resp, err := client.Get(s.url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Got status '%s' on %s", resp.Status, s.url)
+	}
+	return nil
+}
+
+func (s httpStrategy) Timeout() time.Duration {
+	return DefaultWaitTimeout
+}
+
+/*
+Waits for the logs of the container to match the given regular expression the
+given number of times.
+*/
+func ForLog(pattern string, occurrences int) WaitStrategy {
+	return logStrategy{pattern: regexp.MustCompile(pattern), occurrences: occurrences}
+}
+
+type logStrategy struct {
+	pattern     *regexp.Regexp
+	occurrences int
+}
+
+func (s logStrategy) Check(target WaitTarget) error {
+	var logs bytes.Buffer
+	err := target.DockerClient.Logs(docker.LogsOptions{
+		Container:    target.ContainerName,
+		OutputStream: &logs,
+		ErrorStream:  &logs,
+		Stdout:       true,
+		Stderr:       true,
+		RawTerminal:  true,
+	})
+	if err != nil {
+		return err
+	}
+	return s.match(logs.Bytes())
+}
+
+func (s logStrategy) match(logs []byte) error {
+	if found := len(s.pattern.FindAllIndex(logs, -1)); found < s.occurrences {
+		return fmt.Errorf("Found \"%s\" %d time(s) in the logs instead of %d", s.pattern, found, s.occurrences)
+	}
+	return nil
+}
+
+func (s logStrategy) Timeout() time.Duration {
+	return DefaultWaitTimeout
+}
+
+/*
+Waits for the Docker healthcheck of the container to report it healthy.
+*/
+func ForHealthcheck() WaitStrategy {
+	return healthcheckStrategy{}
+}
+
+type healthcheckStrategy struct{}
+
+func (s healthcheckStrategy) Check(target WaitTarget) error {
+	container, err := target.DockerClient.InspectContainer(target.ContainerName)
+	if err != nil {
+		return err
+	}
+	if status := container.State.Health.Status; status != "healthy" {
+		return fmt.Errorf("Got healthcheck status '%s'", status)
+	}
+	return nil
+}
+
+func (s healthcheckStrategy) Timeout() time.Duration {
+	return DefaultWaitTimeout
+}
+
+/*
+Waits for the given function to return nil, for the readiness conditions that
+are specific to an application.
+*/
+func ForFunc(check func() error) WaitStrategy {
+	return funcStrategy(check)
+}
+
+type funcStrategy func() error
+
+func (s funcStrategy) Check(target WaitTarget) error {
+	return s()
+}
+
+func (s funcStrategy) Timeout() time.Duration {
+	return DefaultWaitTimeout
+}
@@ -0,0 +1,101 @@
+package test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var target = WaitTarget{ContainerName: "container-test"}
+
+func neverStopped() bool {
+	return false
+}
+
+func TestWaitUntil_Func(t *testing.T) {
+	attempts := 0
+	strategy := ForFunc(func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("Not ready")
+		}
+		return nil
+	})
+	if err := waitUntil(target, strategy, neverStopped); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestWaitUntil_Timeout(t *testing.T) {
+	strategy := WithTimeout(100*time.Millisecond, ForFunc(func() error {
+		return errors.New("Not ready")
+	}))
+	err := waitUntil(target, strategy, neverStopped)
+	if err == nil || !strings.Contains(err.Error(), "Not ready") {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+}
+
+func TestWaitUntil_Stopped(t *testing.T) {
+	strategy := ForFunc(func() error {
+		return errors.New("Not ready")
+	})
+	err := waitUntil(target, strategy, func() bool { return true })
+	if _, ok := err.(*ContainerStoppedError); !ok {
+		t.Errorf("Expected a *ContainerStoppedError, got %v", err)
+	}
+}
+
+func TestTCPStrategy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	if err := ForTCP(address).Check(target); err != nil {
+		t.Error("Unexpected error on an open port:", err)
+	}
+	listener.Close()
+	if err := ForTCP(address).Check(target); err == nil {
+		t.Error("Expected an error on a closed port")
+	}
+}
+
+func TestHTTPStrategy(t *testing.T) {
+	ready := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	if err := ForHTTP(server.URL + "/health").Check(target); err == nil {
+		t.Error("Expected an error on a 503 status")
+	}
+	ready = true
+	if err := ForHTTP(server.URL + "/health").Check(target); err != nil {
+		t.Error("Unexpected error on a 200 status:", err)
+	}
+}
+
+func TestLogStrategy_Match(t *testing.T) {
+	strategy := ForLog("ready to accept connections", 2).(logStrategy)
+	logs := "LOG:  database system is ready to accept connections\nLOG:  shutting down\n"
+
+	if err := strategy.match([]byte(logs)); err == nil {
+		t.Error("Expected an error when the line is found once")
+	}
+	logs += "LOG:  database system is ready to accept connections\n"
+	if err := strategy.match([]byte(logs)); err != nil {
+		t.Error("Unexpected error when the line is found twice:", err)
+	}
+}